@@ -4,8 +4,11 @@ import (
 	"api-mail/main/src/cache"
 	"api-mail/main/src/configs"
 	"api-mail/main/src/database"
+	"api-mail/main/src/jobs"
 	"api-mail/main/src/middleware"
 	"api-mail/main/src/routes"
+	"api-mail/main/src/services"
+	"context"
 	"fmt"
 	routeutil "github.com/ArnoldPMolenaar/api-utils/routes"
 	"github.com/ArnoldPMolenaar/api-utils/utils"
@@ -34,6 +37,29 @@ func main() {
 	}
 	defer cache.Valkey.Close()
 
+	// Set up the blob store of the attachments and move the attachments that are still stored in the database.
+	if err := services.SetupBlobStore(); err != nil {
+		panic(fmt.Sprintf("Could not set up the blob store: %v", err))
+	}
+	if err := services.MigrateAttachmentBlobs(context.Background()); err != nil {
+		panic(fmt.Sprintf("Could not move the attachments to the blob store: %v", err))
+	}
+
+	// Set up the malware scanner of the attachments.
+	if err := services.SetupAttachmentScanner(); err != nil {
+		panic(fmt.Sprintf("Could not set up the attachment scanner: %v", err))
+	}
+
+	// Start the periodic domain verification.
+	if err := jobs.StartDomainVerificationJob(context.Background()); err != nil {
+		panic(fmt.Sprintf("Could not start the domain verification: %v", err))
+	}
+
+	// Start the periodic removal of the expired library attachments and download links.
+	if err := jobs.StartAttachmentExpiryJob(context.Background()); err != nil {
+		panic(fmt.Sprintf("Could not start the attachment expiry: %v", err))
+	}
+
 	// Register a private routes_util for app.
 	routes.PrivateRoutes(app)
 	// Register a public routes_util for app.
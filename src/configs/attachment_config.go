@@ -0,0 +1,67 @@
+package configs
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxAttachmentSize  = 35 * 1024 * 1024
+	defaultMaxAttachmentsSize = 150 * 1024 * 1024
+	defaultSmtpMessageSize    = 25 * 1024 * 1024
+	defaultLinkThreshold      = 5 * 1024 * 1024
+	defaultLinkTtl            = 7 * 24 * time.Hour
+)
+
+// AttachmentLimits are the size limits in bytes of the attachments of a streamed multipart mail.
+type AttachmentLimits struct {
+	MaxFileSize  int64
+	MaxTotalSize int64
+}
+
+// AttachmentConfig returns the limits of MAIL_MAX_ATTACHMENT_SIZE and MAIL_MAX_ATTACHMENTS_SIZE, default 35 MB per file and 150 MB in total.
+func AttachmentConfig() AttachmentLimits {
+	return AttachmentLimits{
+		MaxFileSize:  envSize("MAIL_MAX_ATTACHMENT_SIZE", defaultMaxAttachmentSize),
+		MaxTotalSize: envSize("MAIL_MAX_ATTACHMENTS_SIZE", defaultMaxAttachmentsSize),
+	}
+}
+
+// AttachmentLinkSettings are the settings of the download links that replace the large attachments of a mail.
+type AttachmentLinkSettings struct {
+	Threshold int64
+	Ttl       time.Duration
+	BaseURL   string
+	Secret    string
+}
+
+// AttachmentLinkConfig returns the settings of ATTACHMENT_LINK_THRESHOLD (default 5 MB), ATTACHMENT_LINK_TTL (default 7 days),
+// ATTACHMENT_LINK_BASE_URL and ATTACHMENT_LINK_SECRET.
+func AttachmentLinkConfig() AttachmentLinkSettings {
+	ttl, err := time.ParseDuration(os.Getenv("ATTACHMENT_LINK_TTL"))
+	if err != nil || ttl <= 0 {
+		ttl = defaultLinkTtl
+	}
+
+	return AttachmentLinkSettings{
+		Threshold: envSize("ATTACHMENT_LINK_THRESHOLD", defaultLinkThreshold),
+		Ttl:       ttl,
+		BaseURL:   os.Getenv("ATTACHMENT_LINK_BASE_URL"),
+		Secret:    os.Getenv("ATTACHMENT_LINK_SECRET"),
+	}
+}
+
+// SmtpMessageSize returns the largest message in bytes that is sent with SMTP, SMTP_MAX_MESSAGE_SIZE or 25 MB.
+func SmtpMessageSize() int64 {
+	return envSize("SMTP_MAX_MESSAGE_SIZE", defaultSmtpMessageSize)
+}
+
+// envSize returns the size in bytes of the environment variable, or the fallback when it is empty or invalid.
+func envSize(key string, fallback int64) int64 {
+	if size, err := strconv.ParseInt(os.Getenv(key), 10, 64); err == nil && size > 0 {
+		return size
+	}
+
+	return fallback
+}
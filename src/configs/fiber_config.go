@@ -15,8 +15,22 @@ func FiberConfig() fiber.Config {
 	readTimeoutSecondsCount, _ := strconv.Atoi(os.Getenv("SERVER_READ_TIMEOUT"))
 
 	// Return Fiber configuration.
+	// The request body is streamed, so multipart mails are read part by part instead of in memory.
+	// The body limit of the other requests is checked by middleware.BodyLimit.
 	return fiber.Config{
-		ReadTimeout:  time.Second * time.Duration(readTimeoutSecondsCount),
-		ErrorHandler: utils.ErrorHandler,
+		ReadTimeout:                  time.Second * time.Duration(readTimeoutSecondsCount),
+		BodyLimit:                    BodyLimit(),
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+		ErrorHandler:                 utils.ErrorHandler,
 	}
 }
+
+// BodyLimit returns the maximum request body size in bytes of SERVER_BODY_LIMIT, default 4 MB.
+func BodyLimit() int {
+	if limit, err := strconv.Atoi(os.Getenv("SERVER_BODY_LIMIT")); err == nil && limit > 0 {
+		return limit
+	}
+
+	return fiber.DefaultBodyLimit
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/dto/responses"
+	"api-mail/main/src/errors"
+	"api-mail/main/src/services"
+	errorutil "github.com/ArnoldPMolenaar/api-utils/errors"
+	"github.com/ArnoldPMolenaar/api-utils/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAppMail func for getting the settings of an app mail.
+func GetAppMail(c *fiber.Ctx) error {
+	// Find the app mail.
+	appMail, err := services.GetAppMail(c.Params("name"), c.Params("mail"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if appMail.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.AppMailExists, "AppMail does not exist.")
+	}
+
+	response := responses.AppMail{}
+	response.SetAppMail(&appMail)
+
+	return c.JSON(response)
+}
+
+// UpdateAppMail func for updating the settings of an app mail.
+func UpdateAppMail(c *fiber.Ctx) error {
+	// Create a new app mail struct for the request.
+	req := &requests.UpdateAppMail{}
+
+	// Check, if received JSON data is parsed.
+	if err := c.BodyParser(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate app mail fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Find the app mail.
+	appMail, err := services.GetAppMail(c.Params("name"), c.Params("mail"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if appMail.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.AppMailExists, "AppMail does not exist.")
+	}
+
+	// Update the app mail.
+	updatedAppMail, err := services.UpdateAppMail(&appMail, req)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	response := responses.AppMail{}
+	response.SetAppMail(updatedAppMail)
+
+	return c.JSON(response)
+}
+
+// GetAppMailCapabilities func for getting the capabilities of the configured providers of an app mail.
+func GetAppMailCapabilities(c *fiber.Ctx) error {
+	// Find the app mail with its providers.
+	appMail, err := services.GetAppMail(c.Params("name"), c.Params("mail"), true)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if appMail.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.AppMailExists, "AppMail does not exist.")
+	}
+
+	response := responses.AppMailCapabilities{}
+	response.SetAppMailCapabilities(&appMail, services.GetAppMailCapabilities(&appMail))
+
+	return c.JSON(response)
+}
@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/dto/responses"
+	"api-mail/main/src/enums"
+	"api-mail/main/src/errors"
+	"api-mail/main/src/models"
+	"api-mail/main/src/services"
+	"bytes"
+	stderrors "errors"
+	"fmt"
+	errorutil "github.com/ArnoldPMolenaar/api-utils/errors"
+	"github.com/ArnoldPMolenaar/api-utils/pagination"
+	"github.com/ArnoldPMolenaar/api-utils/utils"
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"strings"
+	"time"
+)
+
+// GetAttachments func for getting the attachments of the library of an app that are not expired.
+func GetAttachments(c *fiber.Ctx) error {
+	attachments := make([]models.Attachment, 0)
+	values := c.Request().URI().QueryArgs()
+	allowedColumns := map[string]bool{
+		"id":         true,
+		"file_name":  true,
+		"file_type":  true,
+		"file_size":  true,
+		"file_hash":  true,
+		"expires_at": true,
+		"created_at": true,
+		"updated_at": true,
+	}
+
+	queryFunc := pagination.Query(values, allowedColumns)
+	sortFunc := pagination.Sort(values, allowedColumns)
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 {
+		limit = 10
+	}
+	offset := pagination.Offset(page, limit)
+	now := time.Now()
+
+	db := database.Pg.Scopes(queryFunc, sortFunc).
+		Where("app_name = ? AND (expires_at IS NULL OR expires_at > ?)", c.Params("name"), now).
+		Limit(limit).
+		Offset(offset).
+		Find(&attachments)
+	if db.Error != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, db.Error.Error())
+	}
+
+	total := int64(0)
+	database.Pg.Scopes(queryFunc).
+		Model(&models.Attachment{}).
+		Where("app_name = ? AND (expires_at IS NULL OR expires_at > ?)", c.Params("name"), now).
+		Count(&total)
+	pageCount := pagination.Count(int(total), limit)
+
+	paginationModel := pagination.CreatePaginationModel(limit, page, pageCount, int(total), toAttachmentPagination(attachments))
+
+	return c.Status(fiber.StatusOK).JSON(paginationModel)
+}
+
+// CreateAttachment func for uploading a file to the attachment library of an app.
+// The file is streamed from a multipart form and checked like the attachments of a mail.
+func CreateAttachment(c *fiber.Ctx) error {
+	// Check that the file is uploaded as multipart form.
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, "The attachment is uploaded as multipart/form-data.")
+	}
+
+	// Check if app exists.
+	if available, err := services.IsAppAvailable(c.Params("name")); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if !available {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.AppExists, "AppName does not exist.")
+	}
+
+	// Get the attachment policy of the app.
+	policy, err := services.GetAppAttachmentPolicy(c.Params("name"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Read the file up to the maximum file size of the policy.
+	var body io.Reader
+	if c.Request().IsBodyStream() {
+		body = c.Request().BodyStream()
+	} else {
+		body = bytes.NewReader(c.Body())
+	}
+
+	boundary := string(c.Request().Header.MultipartFormBoundary())
+	req, err := services.ParseMultipartAttachment(body, boundary, *policy.MaxFileSize)
+	if err != nil {
+		if stderrors.Is(err, services.ErrAttachmentSize) {
+			return errorutil.Response(c, fiber.StatusRequestEntityTooLarge, errors.AttachmentSize, err.Error())
+		}
+
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate attachment fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Validate the expiry.
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, "ExpiresAt is not in the future.")
+	}
+
+	// Validate the extension of the file name against the type detected from the data.
+	if !services.IsExtensionCompatible(req.FileName, req.FileType) {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentExtension, fmt.Sprintf("The extension of %s does not match the data, which is %s.", req.FileName, req.FileType))
+	}
+
+	// Validate the type and the extension against the policy, text is checked by the type of its extension.
+	if policyType := services.PolicyFileType(req.FileName, req.FileType); !policy.AllowsType(policyType) {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentType, fmt.Sprintf("The type %s of %s is not allowed.", policyType, req.FileName))
+	} else if !policy.AllowsExtension(req.FileName) {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentExtensionDenied, fmt.Sprintf("The extension of %s is not allowed.", req.FileName))
+	}
+
+	// Scan the file, an infected file is not stored.
+	scans, err := services.ScanAttachments(c.Context(), []requests.SendMailAttachment{{FileName: req.FileName, FileData: req.FileData}})
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errors.AttachmentScan, err.Error())
+	} else if len(scans) > 0 && scans[0].Verdict == enums.Infected {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentInfected, fmt.Sprintf("%s is infected with %s.", req.FileName, scans[0].Signature))
+	}
+
+	// Create the attachment.
+	attachment, err := services.CreateAttachment(c.Context(), c.Params("name"), req)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	response := responses.Attachment{}
+	response.SetAttachment(attachment)
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// GetAttachment func for getting an attachment of the library of an app.
+func GetAttachment(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the attachment.
+	attachment, err := services.GetAttachment(c.Params("name"), id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if attachment.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.AttachmentExists, "Attachment does not exist.")
+	}
+
+	response := responses.Attachment{}
+	response.SetAttachment(attachment)
+
+	return c.JSON(response)
+}
+
+// DeleteAttachment func for deleting an attachment from the library of an app.
+func DeleteAttachment(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the attachment.
+	attachment, err := services.GetAttachment(c.Params("name"), id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if attachment.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.AttachmentExists, "Attachment does not exist.")
+	}
+
+	// Delete the attachment.
+	if err := services.DeleteAttachment(c.Context(), attachment); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// toAttachmentPagination func for converting attachments to attachment responses.
+func toAttachmentPagination(attachments []models.Attachment) []responses.Attachment {
+	attachmentResponses := make([]responses.Attachment, len(attachments))
+
+	for i := range attachments {
+		response := responses.Attachment{}
+		response.SetAttachment(&attachments[i])
+		attachmentResponses[i] = response
+	}
+
+	return attachmentResponses
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"api-mail/main/src/errors"
+	"api-mail/main/src/services"
+	stderrors "errors"
+	errorutil "github.com/ArnoldPMolenaar/api-utils/errors"
+	"github.com/ArnoldPMolenaar/api-utils/utils"
+	"github.com/gofiber/fiber/v2"
+	"mime"
+)
+
+// DownloadAttachmentLink func for downloading an attachment that is sent as download link.
+// The link is public, the signature of the URL proves it was sent by a mail.
+func DownloadAttachmentLink(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the link, a link with an invalid signature does not exist.
+	link, err := services.GetAttachmentLink(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if link.ID == 0 || !services.IsAttachmentLinkSigned(link, c.Query("expires"), c.Query("signature")) {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.AttachmentLinkExists, "AttachmentLink does not exist.")
+	}
+
+	// Count the download and open the file.
+	content, err := services.DownloadAttachmentLink(c.Context(), link)
+	if err != nil {
+		if stderrors.Is(err, services.ErrAttachmentLinkExpired) {
+			return errorutil.Response(c, fiber.StatusGone, errors.AttachmentLinkExpired, "AttachmentLink is expired.")
+		}
+
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, link.FileType)
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": link.FileName}))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+
+	// Stream the file, fiber closes it after the response is written.
+	return c.SendStream(content, int(link.FileSize))
+}
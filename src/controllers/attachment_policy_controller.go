@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/dto/responses"
+	"api-mail/main/src/errors"
+	"api-mail/main/src/services"
+	errorutil "github.com/ArnoldPMolenaar/api-utils/errors"
+	"github.com/ArnoldPMolenaar/api-utils/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAttachmentPolicy func for getting the attachment policy of an app.
+func GetAttachmentPolicy(c *fiber.Ctx) error {
+	// Find the attachment policy.
+	policy, err := services.GetAttachmentPolicy(c.Params("name"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if policy.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.AttachmentPolicyExists, "AttachmentPolicy does not exist.")
+	}
+
+	response := responses.AttachmentPolicy{}
+	response.SetAttachmentPolicy(policy)
+
+	return c.JSON(response)
+}
+
+// UpdateAttachmentPolicy func for creating or replacing the attachment policy of an app.
+func UpdateAttachmentPolicy(c *fiber.Ctx) error {
+	// Create a new attachment policy struct for the request.
+	req := &requests.UpdateAttachmentPolicy{}
+
+	// Check, if received JSON data is parsed.
+	if err := c.BodyParser(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate attachment policy fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Check if app exists.
+	if available, err := services.IsAppAvailable(c.Params("name")); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if !available {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.AppExists, "AppName does not exist.")
+	}
+
+	// Find the current attachment policy.
+	policy, err := services.GetAttachmentPolicy(c.Params("name"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Save the attachment policy.
+	savedPolicy, err := services.SaveAttachmentPolicy(c.Params("name"), policy, req)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	response := responses.AttachmentPolicy{}
+	response.SetAttachmentPolicy(savedPolicy)
+
+	return c.JSON(response)
+}
+
+// DeleteAttachmentPolicy func for deleting the attachment policy of an app.
+func DeleteAttachmentPolicy(c *fiber.Ctx) error {
+	// Find the attachment policy.
+	policy, err := services.GetAttachmentPolicy(c.Params("name"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if policy.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.AttachmentPolicyExists, "AttachmentPolicy does not exist.")
+	}
+
+	// Delete the attachment policy.
+	if err := services.DeleteAttachmentPolicy(policy); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
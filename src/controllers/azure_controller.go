@@ -8,12 +8,14 @@ import (
 	"api-mail/main/src/models"
 	"api-mail/main/src/services"
 	"context"
+	"fmt"
 	errorutil "github.com/ArnoldPMolenaar/api-utils/errors"
 	"github.com/ArnoldPMolenaar/api-utils/pagination"
 	"github.com/ArnoldPMolenaar/api-utils/utils"
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/oauth2"
 	"strconv"
+	"strings"
 )
 
 // Oauth2AzureCallback func for handling the Azure OAuth2 callback.
@@ -27,13 +29,13 @@ func Oauth2AzureCallback(c *fiber.Ctx) error {
 	}
 
 	// Get azure.
-	azure, err := services.GetAzureByID(azureID)
+	azure, err := services.GetAzure(azureID)
 	if err != nil {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
 	}
 
 	// Create OAuth2 config
-	oauthConfig := services.CreateAzureOauthConfig(azure.ClientID, azure.TenantID, azure.Secret)
+	oauthConfig := services.CreateAzureOauthConfig(azure)
 
 	// Exchange code for token
 	token, err := oauthConfig.Exchange(context.Background(), code)
@@ -41,7 +43,15 @@ func Oauth2AzureCallback(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errors.OauthExchange, err.Error())
 	}
 
-	// Save the token into the database
+	// Verify that the authorized account is the configured mailbox
+	identities, ok, err := services.VerifyAzureIdentity(context.Background(), azure, token)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errors.OauthIdentity, err.Error())
+	} else if !ok {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.OauthAccountMismatch, fmt.Sprintf("The authorized account %s is not the mailbox %s.", strings.Join(identities, ", "), azure.AppMail.MailName))
+	}
+
+	// Save the token and the verified mail into the database
 	azure, err = services.UpdateAzureToken(azure, token)
 	if err != nil {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
@@ -160,7 +170,7 @@ func CreateAzure(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
 	}
 
-	oauthConfig := services.CreateAzureOauthConfig(req.ClientID, req.TenantID, req.Secret)
+	oauthConfig := services.CreateAzureOauthConfig(azure)
 	authCodeURL := oauthConfig.AuthCodeURL(strconv.Itoa(int(azure.ID)), oauth2.AccessTypeOffline)
 
 	// Return the url to request the token.
@@ -211,7 +221,7 @@ func UpdateAzure(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
 	}
 
-	oauthConfig := services.CreateAzureOauthConfig(req.ClientID, req.TenantID, req.Secret)
+	oauthConfig := services.CreateAzureOauthConfig(azure)
 	authCodeURL := oauthConfig.AuthCodeURL(strconv.Itoa(int(azure.ID)), oauth2.AccessTypeOffline)
 
 	// Return the url to request the token.
@@ -237,6 +247,11 @@ func DeleteAzure(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusNotFound, errors.AzureExists, "Azure does not exist.")
 	}
 
+	// Revoke the token at Microsoft.
+	if err := services.RevokeAzureToken(context.Background(), azure); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errors.OauthRevoke, err.Error())
+	}
+
 	// Delete the Azure.
 	if err := services.DeleteAzure(azure); err != nil {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
@@ -266,7 +281,7 @@ func RestoreAzure(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
 	}
 
-	oauthConfig := services.CreateAzureOauthConfig(azure.ClientID, azure.TenantID, azure.Secret)
+	oauthConfig := services.CreateAzureOauthConfig(azure)
 	authCodeURL := oauthConfig.AuthCodeURL(strconv.Itoa(int(azure.ID)), oauth2.AccessTypeOffline)
 
 	// Return the url to request the token.
@@ -276,6 +291,34 @@ func RestoreAzure(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
+// ReauthorizeAzure func for getting a new consent url of an Azure record.
+// The stored token is used until the callback of the new consent replaces it.
+func ReauthorizeAzure(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the Azure.
+	azure, err := services.GetAzure(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if azure.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.AzureExists, "Azure does not exist.")
+	}
+
+	// Force the consent screen, so changed permissions are granted again.
+	oauthConfig := services.CreateAzureOauthConfig(azure)
+	authCodeURL := oauthConfig.AuthCodeURL(strconv.Itoa(int(azure.ID)), oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+
+	// Return the url to request the token.
+	response := responses.Azure{}
+	response.SetAzure(azure, authCodeURL)
+
+	return c.JSON(response)
+}
+
 // toAzurePagination func for converting Azures to Azure responses.
 func toAzurePagination(azures []models.Azure) []responses.AzurePagination {
 	azureResponses := make([]responses.AzurePagination, len(azures))
@@ -288,3 +331,28 @@ func toAzurePagination(azures []models.Azure) []responses.AzurePagination {
 
 	return azureResponses
 }
+
+// TestAzure func for testing the token and mailbox of an Azure record.
+func TestAzure(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the Azure.
+	azure, err := services.GetAzure(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if azure.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.AzureExists, "Azure does not exist.")
+	}
+
+	// Test the connection.
+	check := services.CheckAzureConnection(c.Context(), azure)
+
+	response := responses.ConnectionCheck{}
+	response.SetConnectionCheck(check)
+
+	return c.JSON(response)
+}
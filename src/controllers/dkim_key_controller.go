@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/dto/responses"
+	"api-mail/main/src/errors"
+	"api-mail/main/src/models"
+	"api-mail/main/src/services"
+	errorutil "github.com/ArnoldPMolenaar/api-utils/errors"
+	"github.com/ArnoldPMolenaar/api-utils/pagination"
+	"github.com/ArnoldPMolenaar/api-utils/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetDkimKeys func for getting all DKIM keys.
+func GetDkimKeys(c *fiber.Ctx) error {
+	dkimKeys := make([]models.DkimKey, 0)
+	values := c.Request().URI().QueryArgs()
+	allowedColumns := map[string]bool{
+		"id":                  true,
+		"app_name":            true,
+		"domain":              true,
+		"selector":            true,
+		"dkim_algorithm_name": true,
+		"active":              true,
+		"created_at":          true,
+		"updated_at":          true,
+	}
+
+	queryFunc := pagination.Query(values, allowedColumns)
+	sortFunc := pagination.Sort(values, allowedColumns)
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 {
+		limit = 10
+	}
+	offset := pagination.Offset(page, limit)
+
+	db := database.Pg.Scopes(queryFunc, sortFunc).
+		Limit(limit).
+		Offset(offset).
+		Find(&dkimKeys)
+	if db.Error != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, db.Error.Error())
+	}
+
+	total := int64(0)
+	database.Pg.Scopes(queryFunc).
+		Model(&models.DkimKey{}).
+		Count(&total)
+	pageCount := pagination.Count(int(total), limit)
+
+	paginationModel := pagination.CreatePaginationModel(limit, page, pageCount, int(total), toDkimKeyPagination(dkimKeys))
+
+	return c.Status(fiber.StatusOK).JSON(paginationModel)
+}
+
+// GetDkimKey func for getting a DKIM key.
+func GetDkimKey(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the DKIM key.
+	dkimKey, err := services.GetDkimKey(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if dkimKey.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.DkimKeyExists, "DkimKey does not exist.")
+	}
+
+	response := responses.DkimKey{}
+	response.SetDkimKey(dkimKey)
+
+	return c.JSON(response)
+}
+
+// CreateDkimKey func for generating a new DKIM key, the response contains the DNS record to publish.
+func CreateDkimKey(c *fiber.Ctx) error {
+	// Create a new dkim key struct for the request.
+	req := &requests.CreateDkimKey{}
+
+	// Check, if received JSON data is parsed.
+	if err := c.BodyParser(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate dkim key fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Check if app exists.
+	if available, err := services.IsAppAvailable(req.App); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if !available {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.AppExists, "AppName does not exist.")
+	}
+
+	// Check if the selector is already used for the domain.
+	if available, err := services.IsDkimSelectorAvailable(req.App, req.Domain, req.Selector); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if available {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.DkimKeyAvailable, "DkimKey selector already exist for the domain.")
+	}
+
+	// Generate dkim key.
+	dkimKey, err := services.GenerateDkimKey(req)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Return the dkim key.
+	response := responses.DkimKey{}
+	response.SetDkimKey(dkimKey)
+
+	return c.JSON(response)
+}
+
+// ActivateDkimKey func for making a DKIM key the signing key of its domain.
+func ActivateDkimKey(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the DKIM key.
+	dkimKey, err := services.GetDkimKey(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if dkimKey.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.DkimKeyExists, "DkimKey does not exist.")
+	}
+
+	// Activate the DKIM key.
+	if err := services.ActivateDkimKey(dkimKey); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	response := responses.DkimKey{}
+	response.SetDkimKey(dkimKey)
+
+	return c.JSON(response)
+}
+
+// DeleteDkimKey func for deleting a DKIM key.
+func DeleteDkimKey(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the DKIM key.
+	dkimKey, err := services.GetDkimKey(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if dkimKey.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.DkimKeyExists, "DkimKey does not exist.")
+	}
+
+	// Delete the DKIM key.
+	if err := services.DeleteDkimKey(dkimKey); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RestoreDkimKey func for restoring a deleted DKIM key.
+func RestoreDkimKey(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the DKIM key.
+	dkimKey, err := services.GetDkimKey(id, true)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if dkimKey.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.DkimKeyExists, "DkimKey does not exist.")
+	}
+
+	// Restore the DKIM key.
+	if err := services.RestoreDkimKey(dkimKey); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// toDkimKeyPagination func for converting DKIM keys to DKIM key responses.
+func toDkimKeyPagination(dkimKeys []models.DkimKey) []responses.DkimKey {
+	dkimKeyResponses := make([]responses.DkimKey, len(dkimKeys))
+
+	for i := range dkimKeys {
+		response := responses.DkimKey{}
+		response.SetDkimKey(&dkimKeys[i])
+		dkimKeyResponses[i] = response
+	}
+
+	return dkimKeyResponses
+}
@@ -8,6 +8,8 @@ import (
 	"api-mail/main/src/models"
 	"api-mail/main/src/services"
 	"context"
+	stderrors "errors"
+	"fmt"
 	errorutil "github.com/ArnoldPMolenaar/api-utils/errors"
 	"github.com/ArnoldPMolenaar/api-utils/pagination"
 	"github.com/ArnoldPMolenaar/api-utils/utils"
@@ -16,6 +18,9 @@ import (
 	"strconv"
 )
 
+// gmailSettingsScopeMessage asks to authorize a Gmail again whose consent lacks the gmail.settings.basic scope.
+const gmailSettingsScopeMessage = "The Gmail consent doesn't grant the gmail.settings.basic scope, authorize it again with POST /v1/gmails/{id}/reauthorize."
+
 // Oauth2GmailCallback func for handling the Gmail OAuth2 callback.
 func Oauth2GmailCallback(c *fiber.Ctx) error {
 	code := c.Query("code")
@@ -27,13 +32,13 @@ func Oauth2GmailCallback(c *fiber.Ctx) error {
 	}
 
 	// Get gmail.
-	gmail, err := services.GetGmailByID(gmailID)
+	gmail, err := services.GetGmail(gmailID)
 	if err != nil {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
 	}
 
 	// Create OAuth2 config
-	oauthConfig := services.CreateGmailOauthConfig(gmail.ClientID, gmail.Secret)
+	oauthConfig := services.CreateGmailOauthConfig(gmail.ClientID, gmail.Secret, gmail.SmtpDelivery)
 
 	// Exchange code for token
 	token, err := oauthConfig.Exchange(context.Background(), code)
@@ -41,7 +46,15 @@ func Oauth2GmailCallback(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errors.OauthExchange, err.Error())
 	}
 
-	// Save the token into the database
+	// Verify that the authorized account is the configured mailbox
+	identity, ok, err := services.VerifyGmailIdentity(gmail, token)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errors.OauthIdentity, err.Error())
+	} else if !ok {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.OauthAccountMismatch, fmt.Sprintf("The authorized account %s is not the mailbox %s.", identity, gmail.AppMail.MailName))
+	}
+
+	// Save the token and the verified mail into the database
 	gmail, err = services.UpdateGmailToken(gmail, token)
 	if err != nil {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
@@ -159,7 +172,7 @@ func CreateGmail(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
 	}
 
-	oauthConfig := services.CreateGmailOauthConfig(req.ClientID, req.Secret)
+	oauthConfig := services.CreateGmailOauthConfig(req.ClientID, req.Secret, req.SmtpDelivery)
 	authCodeURL := oauthConfig.AuthCodeURL(strconv.Itoa(int(gmail.ID)), oauth2.AccessTypeOffline)
 
 	// Return the url to request the token.
@@ -210,7 +223,7 @@ func UpdateGmail(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
 	}
 
-	oauthConfig := services.CreateGmailOauthConfig(req.ClientID, req.Secret)
+	oauthConfig := services.CreateGmailOauthConfig(req.ClientID, req.Secret, req.SmtpDelivery)
 	authCodeURL := oauthConfig.AuthCodeURL(strconv.Itoa(int(gmail.ID)), oauth2.AccessTypeOffline)
 
 	// Return the url to request the token.
@@ -236,6 +249,11 @@ func DeleteGmail(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusNotFound, errors.GmailExists, "Gmail does not exist.")
 	}
 
+	// Revoke the token at Google.
+	if err := services.RevokeGmailToken(context.Background(), gmail); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errors.OauthRevoke, err.Error())
+	}
+
 	// Delete the Gmail.
 	if err := services.DeleteGmail(gmail); err != nil {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
@@ -265,7 +283,7 @@ func RestoreGmail(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
 	}
 
-	oauthConfig := services.CreateGmailOauthConfig(gmail.ClientID, gmail.Secret)
+	oauthConfig := services.CreateGmailOauthConfig(gmail.ClientID, gmail.Secret, gmail.SmtpDelivery)
 	authCodeURL := oauthConfig.AuthCodeURL(strconv.Itoa(int(gmail.ID)), oauth2.AccessTypeOffline)
 
 	// Return the url to request the token.
@@ -275,6 +293,92 @@ func RestoreGmail(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
+// ReauthorizeGmail func for getting a new consent url of a Gmail record.
+// The stored token is used until the callback of the new consent replaces it.
+func ReauthorizeGmail(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the Gmail.
+	gmail, err := services.GetGmail(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if gmail.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.GmailExists, "Gmail does not exist.")
+	}
+
+	// Force the consent screen, Google only returns a new refresh token on consent.
+	oauthConfig := services.CreateGmailOauthConfig(gmail.ClientID, gmail.Secret, gmail.SmtpDelivery)
+	authCodeURL := oauthConfig.AuthCodeURL(strconv.Itoa(int(gmail.ID)), oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+
+	// Return the url to request the token.
+	response := responses.Gmail{}
+	response.SetGmail(gmail, authCodeURL)
+
+	return c.JSON(response)
+}
+
+// GetGmailSendAs func for getting the stored send-as aliases of a Gmail record.
+func GetGmailSendAs(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the Gmail.
+	gmail, err := services.GetGmail(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if gmail.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.GmailExists, "Gmail does not exist.")
+	}
+
+	// Find the send-as aliases.
+	sendAs, err := services.GetGmailSendAs(gmail.ID)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	response := responses.GmailSendAs{}
+	response.SetGmailSendAs(gmail, sendAs)
+
+	return c.JSON(response)
+}
+
+// SyncGmailSendAs func for fetching the send-as aliases of a Gmail record from Google.
+func SyncGmailSendAs(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the Gmail.
+	gmail, err := services.GetGmail(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if gmail.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.GmailExists, "Gmail does not exist.")
+	}
+
+	// Fetch the send-as aliases.
+	sendAs, err := services.SyncGmailSendAs(context.Background(), gmail)
+	if stderrors.Is(err, services.ErrGmailSettingsScope) {
+		return errorutil.Response(c, fiber.StatusForbidden, errors.GmailSendAs, gmailSettingsScopeMessage)
+	} else if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errors.GmailSendAs, err.Error())
+	}
+
+	response := responses.GmailSendAs{}
+	response.SetGmailSendAs(gmail, sendAs)
+
+	return c.JSON(response)
+}
+
 // toGmailPagination func for converting Gmails to Gmail responses.
 func toGmailPagination(gmails []models.Gmail) []responses.GmailPagination {
 	gmailResponses := make([]responses.GmailPagination, len(gmails))
@@ -287,3 +391,28 @@ func toGmailPagination(gmails []models.Gmail) []responses.GmailPagination {
 
 	return gmailResponses
 }
+
+// TestGmail func for testing the token and mailbox of a Gmail record.
+func TestGmail(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the Gmail.
+	gmail, err := services.GetGmail(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if gmail.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.GmailExists, "Gmail does not exist.")
+	}
+
+	// Test the connection.
+	check := services.CheckGmailConnection(c.Context(), gmail)
+
+	response := responses.ConnectionCheck{}
+	response.SetConnectionCheck(check)
+
+	return c.JSON(response)
+}
@@ -1,23 +1,51 @@
 package controllers
 
 import (
+	"api-mail/main/src/configs"
 	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/dto/responses"
 	"api-mail/main/src/enums"
 	"api-mail/main/src/errors"
+	"api-mail/main/src/models"
 	"api-mail/main/src/services"
-	"database/sql"
+	"bytes"
+	"context"
+	stderrors "errors"
+	"fmt"
 	errorutil "github.com/ArnoldPMolenaar/api-utils/errors"
 	"github.com/ArnoldPMolenaar/api-utils/utils"
 	"github.com/gofiber/fiber/v2"
+	"io"
+	"log"
+	"slices"
+	"strings"
 )
 
 // SendMail func for sending mail.
+// The mail is read from the JSON body, or streamed from a multipart form with the mail as JSON part and the attachments as file parts.
 func SendMail(c *fiber.Ctx) error {
 	// Create a new mail struct for the request.
 	sendMail := &requests.SendMail{}
 
-	// Check, if received JSON data is parsed.
-	if err := c.BodyParser(sendMail); err != nil {
+	// Check, if received JSON or multipart data is parsed.
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		var body io.Reader
+		if c.Request().IsBodyStream() {
+			body = c.Request().BodyStream()
+		} else {
+			body = bytes.NewReader(c.Body())
+		}
+
+		var err error
+		boundary := string(c.Request().Header.MultipartFormBoundary())
+		if sendMail, err = services.ParseMultipartMail(body, boundary, configs.AttachmentConfig()); err != nil {
+			if stderrors.Is(err, services.ErrAttachmentSize) {
+				return errorutil.Response(c, fiber.StatusRequestEntityTooLarge, errors.AttachmentSize, err.Error())
+			}
+
+			return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+		}
+	} else if err := c.BodyParser(sendMail); err != nil {
 		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
 	}
 
@@ -27,16 +55,12 @@ func SendMail(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
 	}
 
-	// Validate each attachment.
-	for _, attachment := range sendMail.Attachments {
-		// Validate FileType.
-		if !isValidMimeType(attachment.FileType) {
-			return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, "Invalid file type")
-		}
-
-		// Validate FileData.
-		if len(attachment.FileData) == 0 {
-			return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, "File data is empty")
+	// Check that the draft can be sent later.
+	if isDraftMode(sendMail.Mode) {
+		if sendMail.DisableSave {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.Draft, "A draft is always saved, disableSave can't be used.")
+		} else if sendMail.Event != nil {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.Draft, "A calendar invitation can't be created as draft.")
 		}
 	}
 
@@ -47,6 +71,103 @@ func SendMail(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusBadRequest, errors.AppExists, "AppName does not exist.")
 	}
 
+	// Render the subject and body from the active version of the template.
+	var templateVersion *models.TemplateVersion
+	if sendMail.TemplateID != nil {
+		template, err := services.GetTemplate(sendMail.App, *sendMail.TemplateID)
+		if err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+		} else if template.ID == 0 {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.TemplateExists, "Template does not exist.")
+		}
+
+		if templateVersion, err = services.GetActiveTemplateVersion(template); err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+		} else if templateVersion.ID == 0 {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.TemplateVersionExists, "Template has no active version.")
+		}
+
+		if err := services.RenderSendMailTemplate(templateVersion, sendMail); err != nil {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.TemplateRender, err.Error())
+		}
+	}
+
+	// Add the attachments of the library of the app.
+	if len(sendMail.AttachmentIDs) > 0 {
+		attachments, err := services.GetAttachmentsByIDs(sendMail.App, sendMail.AttachmentIDs)
+		if err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+		}
+
+		// Keep the order of the IDs.
+		ordered := make([]models.Attachment, len(sendMail.AttachmentIDs))
+		for i, id := range sendMail.AttachmentIDs {
+			index := slices.IndexFunc(attachments, func(attachment models.Attachment) bool { return attachment.ID == id })
+			if index == -1 {
+				return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentExists, fmt.Sprintf("Attachment %d does not exist.", id))
+			} else if attachments[index].IsExpired() {
+				return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentExpired, fmt.Sprintf("Attachment %d is expired.", id))
+			}
+			ordered[i] = attachments[index]
+		}
+
+		libraryAttachments, err := services.ReadAttachments(c.Context(), ordered)
+		if err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+		}
+		sendMail.Attachments = append(sendMail.Attachments, libraryAttachments...)
+	}
+
+	// Get the attachment policy of the app.
+	policy, err := services.GetAppAttachmentPolicy(sendMail.App)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Validate the attachments against the policy.
+	if policy.MaxCount != nil && len(sendMail.Attachments) > *policy.MaxCount {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentCount, fmt.Sprintf("The mail has %d attachments, the maximum is %d.", len(sendMail.Attachments), *policy.MaxCount))
+	}
+
+	// Validate each attachment.
+	totalSize := int64(0)
+	for _, attachment := range sendMail.Attachments {
+		// Validate FileData.
+		if len(attachment.FileData) == 0 {
+			return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, "File data is empty")
+		}
+
+		// Validate FileSize against the data.
+		if attachment.FileSize != int64(len(attachment.FileData)) {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentSizeMismatch, fmt.Sprintf("FileSize of %s is %d, but the data is %d bytes.", attachment.FileName, attachment.FileSize, len(attachment.FileData)))
+		}
+
+		// Validate FileType against the type detected from the data.
+		fileType := services.DetectFileType(attachment.FileName, attachment.FileData)
+		if !services.IsFileTypeCompatible(fileType, attachment.FileType) {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentTypeMismatch, fmt.Sprintf("FileType of %s is %s, but the data is %s.", attachment.FileName, attachment.FileType, fileType))
+		}
+
+		// Validate the extension of FileName against the type detected from the data.
+		if !services.IsExtensionCompatible(attachment.FileName, fileType) {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentExtension, fmt.Sprintf("The extension of %s does not match the data, which is %s.", attachment.FileName, fileType))
+		}
+
+		// Validate the type and the extension against the policy, text is checked by the type of its extension.
+		if policyType := services.PolicyFileType(attachment.FileName, attachment.FileType); !policy.AllowsType(policyType) {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentType, fmt.Sprintf("The type %s of %s is not allowed.", policyType, attachment.FileName))
+		} else if !policy.AllowsExtension(attachment.FileName) {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentExtensionDenied, fmt.Sprintf("The extension of %s is not allowed.", attachment.FileName))
+		}
+
+		// Validate the size against the policy.
+		if totalSize += attachment.FileSize; attachment.FileSize > *policy.MaxFileSize {
+			return errorutil.Response(c, fiber.StatusRequestEntityTooLarge, errors.AttachmentSize, fmt.Sprintf("%s is larger than %d bytes.", attachment.FileName, *policy.MaxFileSize))
+		} else if totalSize > *policy.MaxTotalSize {
+			return errorutil.Response(c, fiber.StatusRequestEntityTooLarge, errors.AttachmentSize, fmt.Sprintf("The attachments are larger than %d bytes.", *policy.MaxTotalSize))
+		}
+	}
+
 	// Check if mail exists.
 	if available, err := services.IsMailAvailable(sendMail.Mail); err != nil {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
@@ -69,97 +190,386 @@ func SendMail(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
 	}
 
-	// Check if primary type is set.
-	sendSmtpMail := false
-	sendGmailMail := false
-	sendAzureMail := false
+	// Determine the provider.
+	primaryType, err := services.GetSendPrimaryType(&appMail, sendMail.Type)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
 
-	primaryType := enums.ToAppMailPrimaryType(&appMail.PrimaryType.String)
-	if sendMail.Type != nil {
-		primaryType = enums.ToAppMailPrimaryType(sendMail.Type)
+	// Check that Gmail sends from the mailbox or a verified send-as alias, it rewrites any other From.
+	if primaryType == enums.Gmail {
+		if ok, err := services.IsGmailSendAs(c.Context(), &appMail, sendMail.FromMail); stderrors.Is(err, services.ErrGmailSettingsScope) {
+			return errorutil.Response(c, fiber.StatusForbidden, errors.GmailSendAs, gmailSettingsScopeMessage)
+		} else if err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errors.GmailSendAs, err.Error())
+		} else if !ok {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.GmailSendAs, "FromMail is not a verified send-as alias of the Gmail.")
+		}
 	}
-	switch *primaryType {
+
+	// Complete the calendar event with the last sent state of the same UID.
+	if sendMail.Event != nil {
+		calendarEvent, err := services.GetCalendarEvent(appMail.ID, sendMail.Event.UID)
+		if err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+		}
+
+		if err := services.MergeCalendarEvent(sendMail.Event, calendarEvent, sendMail.Subject, sendMail.FromName, sendMail.FromMail); err != nil {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.CalendarEvent, err.Error())
+		}
+	}
+
+	// Check that only one mail protection is requested.
+	if sendMail.Smime != nil && (sendMail.Smime.Sign || sendMail.Smime.Encrypt) && sendMail.Pgp != nil && (sendMail.Pgp.Sign || sendMail.Pgp.Encrypt) {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.MailProtection, "S/MIME and OpenPGP can't be combined.")
+	}
+
+	// Check that an encrypted mail keeps its attachments, a download link would be readable without the key.
+	if sendMail.LinkAttachments && ((sendMail.Smime != nil && sendMail.Smime.Encrypt) || (sendMail.Pgp != nil && sendMail.Pgp.Encrypt)) {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.MailProtection, "Attachment links can't be combined with an encrypted mail.")
+	}
+
+	// Outlook only sends a protected mail in the MIME format over SMTP, a draft is created with Graph.
+	protectable := primaryType != enums.Azure
+	if primaryType == enums.Azure && !isDraftMode(sendMail.Mode) &&
+		((sendMail.Smime != nil && (sendMail.Smime.Sign || sendMail.Smime.Encrypt)) || (sendMail.Pgp != nil && (sendMail.Pgp.Sign || sendMail.Pgp.Encrypt))) {
+		if protectable, err = services.IsAzureSmtpDelivery(&appMail); err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+		}
+	}
+
+	// Check the S/MIME certificates.
+	if sendMail.Smime != nil && (sendMail.Smime.Sign || sendMail.Smime.Encrypt) {
+		if !protectable {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.SmimeUnsupported, "S/MIME is only supported for SMTP, Gmail and Outlook with SMTP delivery.")
+		}
+
+		if sendMail.Smime.Sign {
+			if available, err := services.IsSmimeAvailable(appMail.AppName, appMail.MailName); err != nil {
+				return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+			} else if !available {
+				return errorutil.Response(c, fiber.StatusBadRequest, errors.SmimeExists, "Smime does not exist.")
+			}
+		}
+
+		if sendMail.Smime.Encrypt {
+			recipients := append(append([]string{sendMail.To}, sendMail.Ccs...), sendMail.Bccs...)
+			if missing, err := services.GetMissingSmimeCertificates(appMail.AppName, recipients); err != nil {
+				return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+			} else if len(missing) > 0 {
+				return errorutil.Response(c, fiber.StatusBadRequest, errors.SmimeCertificateExists, "SmimeCertificate does not exist for: "+strings.Join(missing, ", ")+".")
+			}
+		}
+	}
+
+	// Check the OpenPGP keys.
+	if sendMail.Pgp != nil && (sendMail.Pgp.Sign || sendMail.Pgp.Encrypt) {
+		if !protectable {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.PgpUnsupported, "OpenPGP is only supported for SMTP, Gmail and Outlook with SMTP delivery.")
+		}
+
+		if sendMail.Pgp.Sign {
+			if available, err := services.IsPgpKeyAvailable(appMail.AppName, appMail.MailName); err != nil {
+				return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+			} else if !available {
+				return errorutil.Response(c, fiber.StatusBadRequest, errors.PgpKeyExists, "PgpKey does not exist.")
+			}
+		}
+
+		if sendMail.Pgp.Encrypt {
+			recipients := append(append([]string{sendMail.To}, sendMail.Ccs...), sendMail.Bccs...)
+			if missing, err := services.GetMissingPgpPublicKeys(appMail.AppName, recipients); err != nil {
+				return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+			} else if len(missing) > 0 {
+				return errorutil.Response(c, fiber.StatusBadRequest, errors.PgpPublicKeyExists, "PgpPublicKey does not exist for: "+strings.Join(missing, ", ")+".")
+			}
+		}
+	}
+
+	// Scan the attachments, an infected attachment blocks the mail.
+	scans, err := services.ScanAttachments(c.Context(), sendMail.Attachments)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errors.AttachmentScan, err.Error())
+	}
+	for i := range scans {
+		if scans[i].Verdict == enums.Infected {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentInfected, fmt.Sprintf("%s is infected with %s.", sendMail.Attachments[i].FileName, scans[i].Signature))
+		}
+	}
+
+	// Replace the large attachments by download links when the mail is too large for the provider.
+	var links []models.AttachmentLink
+	if sendMail.LinkAttachments {
+		if scans, links, err = services.LinkLargeAttachments(c.Context(), &appMail, primaryType, sendMail, scans); err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errors.AttachmentLink, err.Error())
+		}
+	}
+
+	// Delete the download links again when the mail is not sent or created as draft, the expiry removes what is left.
+	linksUsed := false
+	defer func() {
+		if !linksUsed {
+			if err := services.DeleteAttachmentLinks(context.Background(), links); err != nil {
+				log.Printf("attachment links of an unsent mail: %s", err.Error())
+			}
+		}
+	}()
+
+	// Check that Outlook accepts the mail on its delivery path.
+	if primaryType == enums.Azure {
+		if err := services.CheckAzureMessage(&appMail, sendMail, isDraftMode(sendMail.Mode)); err != nil {
+			return azureMessageResponse(c, err)
+		}
+	}
+
+	// Create mail.
+	var record *models.SendMail
+	if !sendMail.DisableSave {
+		if record, err = services.CreateSendMail(&appMail, sendMail, scans, links, templateVersion); err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+		}
+	}
+
+	// Create a draft instead of sending the mail.
+	if isDraftMode(sendMail.Mode) {
+		var draftID string
+		switch primaryType {
+		case enums.SMTP:
+			draftID, err = services.CreateSmtpDraft(&appMail, sendMail)
+		case enums.Gmail:
+			draftID, err = services.CreateGmailDraft(&appMail, sendMail)
+		case enums.Azure:
+			draftID, err = services.CreateAzureDraft(&appMail, sendMail)
+		default:
+			return errorutil.Response(c, fiber.StatusInternalServerError, errors.Draft, "PrimaryType not found.")
+		}
+		if err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errors.Draft, err.Error())
+		}
+
+		linksUsed = true
+
+		return createdDraft(c, record, primaryType, draftID)
+	}
+
+	// Send mail.
+	switch primaryType {
 	case enums.SMTP:
-		sendSmtpMail = true
+		if err := services.SendSmtpMail(&appMail, sendMail); err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errors.SendMail, err.Error())
+		}
 	case enums.Gmail:
-		sendGmailMail = true
+		if err := services.SendGmailMail(&appMail, sendMail); err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errors.SendMail, err.Error())
+		}
 	case enums.Azure:
-		sendAzureMail = true
+		if err := services.SendAzureMail(&appMail, sendMail); err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errors.SendMail, err.Error())
+		}
 	default:
-		appMail, err := services.GetAppMail(sendMail.App, sendMail.Mail, true)
+		return errorutil.Response(c, fiber.StatusInternalServerError, errors.SendMail, "PrimaryType not found.")
+	}
+	linksUsed = true
+
+	// Save the sent state of the calendar event.
+	if sendMail.Event != nil {
+		if err := services.SaveCalendarEvent(appMail.ID, sendMail.Event); err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+		}
+	}
+
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+// SendRawMail func for sending a raw RFC 822 message unchanged.
+// The message is read from the JSON body or from the uploaded .eml file of a multipart form.
+func SendRawMail(c *fiber.Ctx) error {
+	// Create a new raw mail struct for the request.
+	sendRawMail := &requests.SendRawMail{}
+
+	// Check, if received JSON or form data is parsed.
+	if err := c.BodyParser(sendRawMail); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Read the uploaded .eml file.
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+		}
+
+		file, err := fileHeader.Open()
 		if err != nil {
+			return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+		}
+		defer file.Close()
+
+		if sendRawMail.Raw, err = io.ReadAll(file); err != nil {
+			return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+		}
+	}
+
+	// Validate sendRawMail fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(sendRawMail); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Validate the raw message.
+	if len(sendRawMail.Raw) == 0 {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, "Raw message is empty")
+	}
+
+	// Check that the draft can be sent later.
+	if isDraftMode(sendRawMail.Mode) && sendRawMail.DisableSave {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.Draft, "A draft is always saved, disableSave can't be used.")
+	}
+
+	sendMail, recipients, err := services.ParseRawMail(sendRawMail)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.RawMailParse, err.Error())
+	}
+
+	// Check if app exists.
+	if available, err := services.IsAppAvailable(sendMail.App); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if !available {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.AppExists, "AppName does not exist.")
+	}
+
+	// Check if mail exists.
+	if available, err := services.IsMailAvailable(sendMail.Mail); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if !available {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.MailExists, "MailName does not exist.")
+	}
+
+	// Check if type exists.
+	if sendMail.Type != nil {
+		if available, err := services.IsPrimaryTypeAvailable(*sendMail.Type); err != nil {
 			return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+		} else if !available {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.MailTypeExists, "MailType does not exist.")
 		}
+	}
 
-		switch {
-		case appMail.Azure != nil:
-			sendAzureMail = true
-		case appMail.Gmail != nil:
-			sendGmailMail = true
-		default:
-			sendSmtpMail = true
+	// Get app mail.
+	appMail, err := services.GetAppMail(sendMail.App, sendMail.Mail)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Determine the provider.
+	primaryType, err := services.GetSendPrimaryType(&appMail, sendMail.Type)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Check that Gmail sends from the mailbox or a verified send-as alias, it rewrites any other From.
+	if primaryType == enums.Gmail {
+		if ok, err := services.IsGmailSendAs(c.Context(), &appMail, sendMail.FromMail); stderrors.Is(err, services.ErrGmailSettingsScope) {
+			return errorutil.Response(c, fiber.StatusForbidden, errors.GmailSendAs, gmailSettingsScopeMessage)
+		} else if err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errors.GmailSendAs, err.Error())
+		} else if !ok {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.GmailSendAs, "FromMail is not a verified send-as alias of the Gmail.")
 		}
 	}
 
-	switch {
-	case sendAzureMail:
-		azureType := enums.Azure
-		appMail.PrimaryType = sql.NullString{String: *azureType.ToString(), Valid: true}
-	case sendGmailMail:
-		gmailType := enums.Gmail
-		appMail.PrimaryType = sql.NullString{String: *gmailType.ToString(), Valid: true}
-	default:
-		smtpType := enums.SMTP
-		appMail.PrimaryType = sql.NullString{String: *smtpType.ToString(), Valid: true}
+	// Validate the attachments of the message against the policy of the app.
+	policy, err := services.GetAppAttachmentPolicy(sendMail.App)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	if policy.MaxCount != nil && len(sendMail.Attachments) > *policy.MaxCount {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentCount, fmt.Sprintf("The mail has %d attachments, the maximum is %d.", len(sendMail.Attachments), *policy.MaxCount))
+	}
+
+	totalSize := int64(0)
+	for i := range sendMail.Attachments {
+		attachment := &sendMail.Attachments[i]
+
+		// Mail clients declare an unknown type as application/octet-stream, the detected type is used instead.
+		fileType := services.DetectFileType(attachment.FileName, attachment.FileData)
+		if strings.EqualFold(attachment.FileType, "application/octet-stream") {
+			attachment.FileType = fileType
+		}
+
+		if !services.IsFileTypeCompatible(fileType, attachment.FileType) {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentTypeMismatch, fmt.Sprintf("The type of %s is %s, but the data is %s.", attachment.FileName, attachment.FileType, fileType))
+		} else if !services.IsExtensionCompatible(attachment.FileName, fileType) {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentExtension, fmt.Sprintf("The extension of %s does not match the data, which is %s.", attachment.FileName, fileType))
+		}
+
+		if policyType := services.PolicyFileType(attachment.FileName, attachment.FileType); !policy.AllowsType(policyType) {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentType, fmt.Sprintf("The type %s of %s is not allowed.", policyType, attachment.FileName))
+		} else if !policy.AllowsExtension(attachment.FileName) {
+			return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentExtensionDenied, fmt.Sprintf("The extension of %s is not allowed.", attachment.FileName))
+		}
+
+		if totalSize += attachment.FileSize; attachment.FileSize > *policy.MaxFileSize {
+			return errorutil.Response(c, fiber.StatusRequestEntityTooLarge, errors.AttachmentSize, fmt.Sprintf("%s is larger than %d bytes.", attachment.FileName, *policy.MaxFileSize))
+		} else if totalSize > *policy.MaxTotalSize {
+			return errorutil.Response(c, fiber.StatusRequestEntityTooLarge, errors.AttachmentSize, fmt.Sprintf("The attachments are larger than %d bytes.", *policy.MaxTotalSize))
+		}
+	}
+
+	// Scan the message, an infected attachment blocks the mail.
+	if scan, err := services.ScanRawMail(c.Context(), sendRawMail.Raw); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errors.AttachmentScan, err.Error())
+	} else if scan != nil && scan.Verdict == enums.Infected {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.AttachmentInfected, fmt.Sprintf("The message is infected with %s.", scan.Signature))
+	}
+
+	// Check that Outlook accepts the message on its delivery path.
+	if primaryType == enums.Azure {
+		if err := services.CheckAzureRawMessage(&appMail, sendRawMail.Raw, isDraftMode(sendMail.Mode)); err != nil {
+			return azureMessageResponse(c, err)
+		}
 	}
 
 	// Create mail.
+	var record *models.SendMail
 	if !sendMail.DisableSave {
-		if err := services.CreateSendMail(&appMail, sendMail); err != nil {
+		if record, err = services.CreateSendMail(&appMail, sendMail, nil, nil, nil); err != nil {
 			return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
 		}
 	}
 
+	// Create a draft instead of sending the mail.
+	if isDraftMode(sendMail.Mode) {
+		var draftID string
+		switch primaryType {
+		case enums.SMTP:
+			draftID, err = services.CreateSmtpRawDraft(&appMail, sendRawMail.Raw)
+		case enums.Gmail:
+			draftID, err = services.CreateGmailRawDraft(&appMail, sendRawMail.Raw)
+		case enums.Azure:
+			draftID, err = services.CreateAzureRawDraft(&appMail, sendRawMail.Raw)
+		default:
+			return errorutil.Response(c, fiber.StatusInternalServerError, errors.Draft, "PrimaryType not found.")
+		}
+		if err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errors.Draft, err.Error())
+		}
+
+		return createdDraft(c, record, primaryType, draftID)
+	}
+
 	// Send mail.
-	switch {
-	case sendSmtpMail:
-		if err := services.SendSmtpMail(
-			&appMail,
-			sendMail.FromName,
-			sendMail.FromMail,
-			sendMail.To,
-			sendMail.Subject,
-			sendMail.Body,
-			sendMail.MimeType,
-			sendMail.Ccs,
-			sendMail.Bccs,
-			sendMail.Attachments); err != nil {
+	switch primaryType {
+	case enums.SMTP:
+		if err := services.SendSmtpRawMail(&appMail, sendMail.FromMail, recipients, sendRawMail.Raw); err != nil {
 			return errorutil.Response(c, fiber.StatusInternalServerError, errors.SendMail, err.Error())
 		}
-	case sendGmailMail:
-		if err := services.SendGmailMail(
-			&appMail,
-			sendMail.FromName,
-			sendMail.FromMail,
-			sendMail.To,
-			sendMail.Subject,
-			sendMail.Body,
-			sendMail.MimeType,
-			sendMail.Ccs,
-			sendMail.Bccs,
-			sendMail.Attachments); err != nil {
+	case enums.Gmail:
+		if err := services.SendGmailRawMail(&appMail, recipients, sendRawMail.Raw); err != nil {
 			return errorutil.Response(c, fiber.StatusInternalServerError, errors.SendMail, err.Error())
 		}
-	case sendAzureMail:
-		if err := services.SendAzureMail(
-			&appMail,
-			sendMail.To,
-			sendMail.Subject,
-			sendMail.Body,
-			sendMail.MimeType,
-			sendMail.Ccs,
-			sendMail.Bccs,
-			sendMail.Attachments); err != nil {
+	case enums.Azure:
+		if err := services.SendAzureRawMail(&appMail, recipients, sendRawMail.Raw); err != nil {
 			return errorutil.Response(c, fiber.StatusInternalServerError, errors.SendMail, err.Error())
 		}
 	default:
@@ -169,26 +579,76 @@ func SendMail(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusCreated)
 }
 
-// isValidMimeType checks if the provided MIME type is valid.
-func isValidMimeType(mimeType string) bool {
-	// Add your valid MIME types here.
-	validMimeTypes := map[string]bool{
-		"application/pdf":    true,
-		"image/jpeg":         true,
-		"image/png":          true,
-		"text/plain":         true,
-		"text/html":          true,
-		"application/msword": true, // .doc
-		"application/vnd.openxmlformats-officedocument.wordprocessingml.document": true, // .docx
-		"application/vnd.ms-excel": true, // .xls
-		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         true, // .xlsx
-		"application/vnd.ms-powerpoint":                                             true, // .ppt
-		"application/vnd.openxmlformats-officedocument.presentationml.presentation": true, // .pptx
-		"application/zip":              true,
-		"application/x-rar-compressed": true,
-		"application/x-7z-compressed":  true,
-		"application/x-tar":            true,
-	}
-
-	return validMimeTypes[mimeType]
+// SendDraft func for sending a draft that was created with the draft mode.
+func SendDraft(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the mail.
+	sendMail, err := services.GetSendMail(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if sendMail.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.SendMailExists, "SendMail does not exist.")
+	} else if !sendMail.DraftID.Valid {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.Draft, "SendMail is not a draft.")
+	}
+
+	// Mark the draft as sent, so it is sent only once.
+	if claimed, err := services.ClaimSendMailDraft(sendMail); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if !claimed {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.Draft, "The draft is already sent.")
+	}
+
+	// Send the draft.
+	switch enums.AppMailPrimaryType(sendMail.DraftType.String) {
+	case enums.SMTP:
+		err = services.SendSmtpDraft(&sendMail.AppMail, sendMail.DraftID.String)
+	case enums.Gmail:
+		err = services.SendGmailDraft(&sendMail.AppMail, sendMail.DraftID.String)
+	case enums.Azure:
+		err = services.SendAzureDraft(&sendMail.AppMail, sendMail.DraftID.String)
+	default:
+		err = fmt.Errorf("primary type %s not found", sendMail.DraftType.String)
+	}
+	if err != nil {
+		if err := services.ReleaseSendMailDraft(sendMail); err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+		}
+
+		return errorutil.Response(c, fiber.StatusInternalServerError, errors.SendMail, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+// isDraftMode checks if the mode of the send request is draft.
+func isDraftMode(mode *string) bool {
+	return mode != nil && enums.SendMode(*mode) == enums.ModeDraft
+}
+
+// azureMessageResponse responds with the error of a mail that Outlook doesn't accept on its delivery path.
+func azureMessageResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case stderrors.Is(err, services.ErrMessageSize):
+		return errorutil.Response(c, fiber.StatusRequestEntityTooLarge, errors.AttachmentSize, err.Error())
+	default:
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+}
+
+// createdDraft stores the draft of the send-mail and returns the IDs of the send-mail and the draft.
+func createdDraft(c *fiber.Ctx, sendMail *models.SendMail, primaryType enums.AppMailPrimaryType, draftID string) error {
+	if err := services.SetSendMailDraft(sendMail, primaryType, draftID); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	response := responses.SendMailDraft{}
+	response.SetSendMailDraft(sendMail)
+
+	return c.Status(fiber.StatusCreated).JSON(response)
 }
@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/dto/responses"
+	"api-mail/main/src/errors"
+	"api-mail/main/src/models"
+	"api-mail/main/src/services"
+	errorutil "github.com/ArnoldPMolenaar/api-utils/errors"
+	"github.com/ArnoldPMolenaar/api-utils/pagination"
+	"github.com/ArnoldPMolenaar/api-utils/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetPgpKeys func for getting all OpenPGP keys.
+func GetPgpKeys(c *fiber.Ctx) error {
+	pgpKeys := make([]models.PgpKey, 0)
+	values := c.Request().URI().QueryArgs()
+	allowedColumns := map[string]bool{
+		"id":          true,
+		"fingerprint": true,
+		"user_id":     true,
+		"created_at":  true,
+		"updated_at":  true,
+		"app_name":    true,
+		"mail_name":   true,
+	}
+
+	queryFunc := pagination.Query(values, allowedColumns)
+	sortFunc := pagination.Sort(values, allowedColumns)
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 {
+		limit = 10
+	}
+	offset := pagination.Offset(page, limit)
+
+	db := database.Pg.Scopes(queryFunc, sortFunc).
+		Limit(limit).
+		Offset(offset).
+		Preload("AppMail").
+		Joins("JOIN \"app_mails\" ON \"app_mails\".\"id\" = \"app_mail_id\"").
+		Find(&pgpKeys)
+	if db.Error != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, db.Error.Error())
+	}
+
+	total := int64(0)
+	database.Pg.Scopes(queryFunc).
+		Model(&models.PgpKey{}).
+		Joins("JOIN \"app_mails\" ON \"app_mails\".\"id\" = \"app_mail_id\"").
+		Count(&total)
+	pageCount := pagination.Count(int(total), limit)
+
+	paginationModel := pagination.CreatePaginationModel(limit, page, pageCount, int(total), toPgpKeyPagination(pgpKeys))
+
+	return c.Status(fiber.StatusOK).JSON(paginationModel)
+}
+
+// GetPgpKey func for getting an OpenPGP key.
+func GetPgpKey(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the OpenPGP key.
+	pgpKey, err := services.GetPgpKey(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if pgpKey.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.PgpKeyExists, "PgpKey does not exist.")
+	}
+
+	response := responses.PgpKey{}
+	response.SetPgpKey(pgpKey)
+
+	return c.JSON(response)
+}
+
+// CreatePgpKey func for creating a new OpenPGP key.
+func CreatePgpKey(c *fiber.Ctx) error {
+	// Create a new pgpKey struct for the request.
+	req := &requests.CreatePgpKey{}
+
+	// Check, if received JSON data is parsed.
+	if err := c.BodyParser(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate pgpKey fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Check if app exists.
+	if available, err := services.IsAppAvailable(req.App); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if !available {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.AppExists, "AppName does not exist.")
+	}
+
+	// Check if pgpKey exists.
+	if available, err := services.IsPgpKeyAvailable(req.App, req.Mail); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if available {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.PgpKeyAvailable, "PgpKey of the mail already exist.")
+	}
+
+	// Check if app mail exists.
+	if appMail, err := services.GetAppMail(req.App, req.Mail); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if appMail.ID == 0 {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.MailExists, "Mail does not exist.")
+	}
+
+	// Check if the private key is valid.
+	if err := services.ValidatePgpKey(req.PrivateKey, req.Passphrase); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.PgpKeyInvalid, err.Error())
+	}
+
+	// Create pgpKey.
+	pgpKey, err := services.CreatePgpKey(req)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Return the pgpKey.
+	if pgpKey != nil {
+		response := responses.PgpKey{}
+		response.SetPgpKey(pgpKey)
+
+		return c.JSON(response)
+	} else {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, "Failed to create pgp key.")
+	}
+}
+
+// UpdatePgpKey func for updating an OpenPGP key.
+func UpdatePgpKey(c *fiber.Ctx) error {
+	// Create a new pgpKey struct for the request.
+	req := &requests.UpdatePgpKey{}
+
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Check, if received JSON data is parsed.
+	if err := c.BodyParser(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate pgpKey fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Find the OpenPGP key.
+	pgpKey, err := services.GetPgpKey(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if pgpKey.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.PgpKeyExists, "PgpKey does not exist.")
+	}
+
+	// Check if the pgpKey data has been modified since it was last fetched.
+	if req.UpdatedAt.Unix() < pgpKey.UpdatedAt.Unix() {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.OutOfSync, "Data is out of sync.")
+	}
+
+	// Check if the private key is valid.
+	if err := services.ValidatePgpKey(req.PrivateKey, req.Passphrase); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.PgpKeyInvalid, err.Error())
+	}
+
+	// Update pgpKey.
+	pgpKey, err = services.UpdatePgpKey(pgpKey, req)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Return the pgpKey.
+	if pgpKey != nil {
+		response := responses.PgpKey{}
+		response.SetPgpKey(pgpKey)
+
+		return c.JSON(response)
+	} else {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, "Failed to update pgp key.")
+	}
+}
+
+// DeletePgpKey func for deleting an OpenPGP key.
+func DeletePgpKey(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the OpenPGP key.
+	pgpKey, err := services.GetPgpKey(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if pgpKey.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.PgpKeyExists, "PgpKey does not exist.")
+	}
+
+	// Delete the OpenPGP key.
+	if err := services.DeletePgpKey(pgpKey); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RestorePgpKey func for restoring a deleted OpenPGP key.
+func RestorePgpKey(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the OpenPGP key.
+	pgpKey, err := services.GetPgpKey(id, true)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if pgpKey.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.PgpKeyExists, "PgpKey does not exist.")
+	}
+
+	// Restore the OpenPGP key.
+	if err := services.RestorePgpKey(pgpKey); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// toPgpKeyPagination func for converting OpenPGP keys to OpenPGP key responses.
+func toPgpKeyPagination(pgpKeys []models.PgpKey) []responses.PgpKey {
+	pgpKeyResponses := make([]responses.PgpKey, len(pgpKeys))
+
+	for i := range pgpKeys {
+		response := responses.PgpKey{}
+		response.SetPgpKey(&pgpKeys[i])
+		pgpKeyResponses[i] = response
+	}
+
+	return pgpKeyResponses
+}
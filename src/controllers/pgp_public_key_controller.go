@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/dto/responses"
+	"api-mail/main/src/errors"
+	"api-mail/main/src/models"
+	"api-mail/main/src/services"
+	errorutil "github.com/ArnoldPMolenaar/api-utils/errors"
+	"github.com/ArnoldPMolenaar/api-utils/pagination"
+	"github.com/ArnoldPMolenaar/api-utils/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetPgpPublicKeys func for getting all OpenPGP public keys.
+func GetPgpPublicKeys(c *fiber.Ctx) error {
+	publicKeys := make([]models.PgpPublicKey, 0)
+	values := c.Request().URI().QueryArgs()
+	allowedColumns := map[string]bool{
+		"id":          true,
+		"mail":        true,
+		"fingerprint": true,
+		"user_id":     true,
+		"created_at":  true,
+		"updated_at":  true,
+		"app_name":    true,
+	}
+
+	queryFunc := pagination.Query(values, allowedColumns)
+	sortFunc := pagination.Sort(values, allowedColumns)
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 {
+		limit = 10
+	}
+	offset := pagination.Offset(page, limit)
+
+	db := database.Pg.Scopes(queryFunc, sortFunc).
+		Limit(limit).
+		Offset(offset).
+		Find(&publicKeys)
+	if db.Error != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, db.Error.Error())
+	}
+
+	total := int64(0)
+	database.Pg.Scopes(queryFunc).
+		Model(&models.PgpPublicKey{}).
+		Count(&total)
+	pageCount := pagination.Count(int(total), limit)
+
+	paginationModel := pagination.CreatePaginationModel(limit, page, pageCount, int(total), toPgpPublicKeyPagination(publicKeys))
+
+	return c.Status(fiber.StatusOK).JSON(paginationModel)
+}
+
+// GetPgpPublicKey func for getting an OpenPGP public key.
+func GetPgpPublicKey(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the OpenPGP public key.
+	publicKey, err := services.GetPgpPublicKey(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if publicKey.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.PgpPublicKeyExists, "PgpPublicKey does not exist.")
+	}
+
+	response := responses.PgpPublicKey{}
+	response.SetPgpPublicKey(publicKey)
+
+	return c.JSON(response)
+}
+
+// CreatePgpPublicKey func for creating a new OpenPGP public key.
+func CreatePgpPublicKey(c *fiber.Ctx) error {
+	// Create a new publicKey struct for the request.
+	req := &requests.CreatePgpPublicKey{}
+
+	// Check, if received JSON data is parsed.
+	if err := c.BodyParser(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate publicKey fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Check if app exists.
+	if available, err := services.IsAppAvailable(req.App); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if !available {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.AppExists, "AppName does not exist.")
+	}
+
+	// Check if publicKey exists.
+	if available, err := services.IsPgpPublicKeyAvailable(req.App, req.Mail); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if available {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.PgpPublicKeyAvailable, "PgpPublicKey of the mail already exist.")
+	}
+
+	// Check if the public key is valid.
+	if err := services.ValidatePgpPublicKey(req.PublicKey); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.PgpKeyInvalid, err.Error())
+	}
+
+	// Create publicKey.
+	publicKey, err := services.CreatePgpPublicKey(req)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Return the publicKey.
+	if publicKey != nil {
+		response := responses.PgpPublicKey{}
+		response.SetPgpPublicKey(publicKey)
+
+		return c.JSON(response)
+	} else {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, "Failed to create pgp public key.")
+	}
+}
+
+// UpdatePgpPublicKey func for updating an OpenPGP public key.
+func UpdatePgpPublicKey(c *fiber.Ctx) error {
+	// Create a new publicKey struct for the request.
+	req := &requests.UpdatePgpPublicKey{}
+
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Check, if received JSON data is parsed.
+	if err := c.BodyParser(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate publicKey fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Find the OpenPGP public key.
+	publicKey, err := services.GetPgpPublicKey(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if publicKey.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.PgpPublicKeyExists, "PgpPublicKey does not exist.")
+	}
+
+	// Check if the publicKey data has been modified since it was last fetched.
+	if req.UpdatedAt.Unix() < publicKey.UpdatedAt.Unix() {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.OutOfSync, "Data is out of sync.")
+	}
+
+	// Check if the public key is valid.
+	if err := services.ValidatePgpPublicKey(req.PublicKey); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.PgpKeyInvalid, err.Error())
+	}
+
+	// Update publicKey.
+	publicKey, err = services.UpdatePgpPublicKey(publicKey, req)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Return the publicKey.
+	if publicKey != nil {
+		response := responses.PgpPublicKey{}
+		response.SetPgpPublicKey(publicKey)
+
+		return c.JSON(response)
+	} else {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, "Failed to update pgp public key.")
+	}
+}
+
+// DeletePgpPublicKey func for deleting an OpenPGP public key.
+func DeletePgpPublicKey(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the OpenPGP public key.
+	publicKey, err := services.GetPgpPublicKey(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if publicKey.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.PgpPublicKeyExists, "PgpPublicKey does not exist.")
+	}
+
+	// Delete the OpenPGP public key.
+	if err := services.DeletePgpPublicKey(publicKey); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RestorePgpPublicKey func for restoring a deleted OpenPGP public key.
+func RestorePgpPublicKey(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the OpenPGP public key.
+	publicKey, err := services.GetPgpPublicKey(id, true)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if publicKey.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.PgpPublicKeyExists, "PgpPublicKey does not exist.")
+	}
+
+	// Restore the OpenPGP public key.
+	if err := services.RestorePgpPublicKey(publicKey); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// toPgpPublicKeyPagination func for converting OpenPGP public keys to OpenPGP public key responses.
+func toPgpPublicKeyPagination(publicKeys []models.PgpPublicKey) []responses.PgpPublicKey {
+	publicKeyResponses := make([]responses.PgpPublicKey, len(publicKeys))
+
+	for i := range publicKeys {
+		response := responses.PgpPublicKey{}
+		response.SetPgpPublicKey(&publicKeys[i])
+		publicKeyResponses[i] = response
+	}
+
+	return publicKeyResponses
+}
@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/dto/responses"
+	"api-mail/main/src/errors"
+	"api-mail/main/src/models"
+	"api-mail/main/src/services"
+	errorutil "github.com/ArnoldPMolenaar/api-utils/errors"
+	"github.com/ArnoldPMolenaar/api-utils/pagination"
+	"github.com/ArnoldPMolenaar/api-utils/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetSmimeCertificates func for getting all S/MIME recipient certificates.
+func GetSmimeCertificates(c *fiber.Ctx) error {
+	certificateCertificates := make([]models.SmimeCertificate, 0)
+	values := c.Request().URI().QueryArgs()
+	allowedColumns := map[string]bool{
+		"id":         true,
+		"mail":       true,
+		"subject":    true,
+		"expires_at": true,
+		"created_at": true,
+		"updated_at": true,
+		"app_name":   true,
+	}
+
+	queryFunc := pagination.Query(values, allowedColumns)
+	sortFunc := pagination.Sort(values, allowedColumns)
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 {
+		limit = 10
+	}
+	offset := pagination.Offset(page, limit)
+
+	db := database.Pg.Scopes(queryFunc, sortFunc).
+		Limit(limit).
+		Offset(offset).
+		Find(&certificateCertificates)
+	if db.Error != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, db.Error.Error())
+	}
+
+	total := int64(0)
+	database.Pg.Scopes(queryFunc).
+		Model(&models.SmimeCertificate{}).
+		Count(&total)
+	pageCount := pagination.Count(int(total), limit)
+
+	paginationModel := pagination.CreatePaginationModel(limit, page, pageCount, int(total), toSmimeCertificatePagination(certificateCertificates))
+
+	return c.Status(fiber.StatusOK).JSON(paginationModel)
+}
+
+// GetSmimeCertificate func for getting a S/MIME recipient certificate.
+func GetSmimeCertificate(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the S/MIME recipient certificate.
+	certificate, err := services.GetSmimeCertificate(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if certificate.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.SmimeCertificateExists, "SmimeCertificate does not exist.")
+	}
+
+	response := responses.SmimeCertificate{}
+	response.SetSmimeCertificate(certificate)
+
+	return c.JSON(response)
+}
+
+// CreateSmimeCertificate func for creating a new S/MIME recipient certificate.
+func CreateSmimeCertificate(c *fiber.Ctx) error {
+	// Create a new certificate struct for the request.
+	req := &requests.CreateSmimeCertificate{}
+
+	// Check, if received JSON data is parsed.
+	if err := c.BodyParser(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate certificate fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Check if app exists.
+	if available, err := services.IsAppAvailable(req.App); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if !available {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.AppExists, "AppName does not exist.")
+	}
+
+	// Check if certificate exists.
+	if available, err := services.IsSmimeCertificateAvailable(req.App, req.Mail); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if available {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.SmimeCertificateAvailable, "SmimeCertificate of the mail already exist.")
+	}
+
+	// Check if the certificate is valid.
+	if _, err := services.ParseSmimeCertificates(req.Certificate); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.SmimeInvalid, err.Error())
+	}
+
+	// Create certificate.
+	certificate, err := services.CreateSmimeCertificate(req)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Return the certificate.
+	if certificate != nil {
+		response := responses.SmimeCertificate{}
+		response.SetSmimeCertificate(certificate)
+
+		return c.JSON(response)
+	} else {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, "Failed to create smime certificate.")
+	}
+}
+
+// UpdateSmimeCertificate func for updating a S/MIME recipient certificate.
+func UpdateSmimeCertificate(c *fiber.Ctx) error {
+	// Create a new certificate struct for the request.
+	req := &requests.UpdateSmimeCertificate{}
+
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Check, if received JSON data is parsed.
+	if err := c.BodyParser(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate certificate fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Find the S/MIME recipient certificate.
+	certificate, err := services.GetSmimeCertificate(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if certificate.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.SmimeCertificateExists, "SmimeCertificate does not exist.")
+	}
+
+	// Check if the certificate data has been modified since it was last fetched.
+	if req.UpdatedAt.Unix() < certificate.UpdatedAt.Unix() {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.OutOfSync, "Data is out of sync.")
+	}
+
+	// Check if the certificate is valid.
+	if _, err := services.ParseSmimeCertificates(req.Certificate); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.SmimeInvalid, err.Error())
+	}
+
+	// Update certificate.
+	certificate, err = services.UpdateSmimeCertificate(certificate, req)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Return the certificate.
+	if certificate != nil {
+		response := responses.SmimeCertificate{}
+		response.SetSmimeCertificate(certificate)
+
+		return c.JSON(response)
+	} else {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, "Failed to update smime certificate.")
+	}
+}
+
+// DeleteSmimeCertificate func for deleting a S/MIME recipient certificate.
+func DeleteSmimeCertificate(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the S/MIME recipient certificate.
+	certificate, err := services.GetSmimeCertificate(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if certificate.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.SmimeCertificateExists, "SmimeCertificate does not exist.")
+	}
+
+	// Delete the S/MIME recipient certificate.
+	if err := services.DeleteSmimeCertificate(certificate); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RestoreSmimeCertificate func for restoring a deleted S/MIME recipient certificate.
+func RestoreSmimeCertificate(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the S/MIME recipient certificate.
+	certificate, err := services.GetSmimeCertificate(id, true)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if certificate.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.SmimeCertificateExists, "SmimeCertificate does not exist.")
+	}
+
+	// Restore the S/MIME recipient certificate.
+	if err := services.RestoreSmimeCertificate(certificate); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// toSmimeCertificatePagination func for converting S/MIME recipient certificates to S/MIME recipient certificate responses.
+func toSmimeCertificatePagination(certificateCertificates []models.SmimeCertificate) []responses.SmimeCertificate {
+	certificateResponses := make([]responses.SmimeCertificate, len(certificateCertificates))
+
+	for i := range certificateCertificates {
+		response := responses.SmimeCertificate{}
+		response.SetSmimeCertificate(&certificateCertificates[i])
+		certificateResponses[i] = response
+	}
+
+	return certificateResponses
+}
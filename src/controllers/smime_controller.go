@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/dto/responses"
+	"api-mail/main/src/errors"
+	"api-mail/main/src/models"
+	"api-mail/main/src/services"
+	errorutil "github.com/ArnoldPMolenaar/api-utils/errors"
+	"github.com/ArnoldPMolenaar/api-utils/pagination"
+	"github.com/ArnoldPMolenaar/api-utils/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetSmimes func for getting all S/MIME records.
+func GetSmimes(c *fiber.Ctx) error {
+	smimes := make([]models.Smime, 0)
+	values := c.Request().URI().QueryArgs()
+	allowedColumns := map[string]bool{
+		"id":         true,
+		"subject":    true,
+		"expires_at": true,
+		"created_at": true,
+		"updated_at": true,
+		"app_name":   true,
+		"mail_name":  true,
+	}
+
+	queryFunc := pagination.Query(values, allowedColumns)
+	sortFunc := pagination.Sort(values, allowedColumns)
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 {
+		limit = 10
+	}
+	offset := pagination.Offset(page, limit)
+
+	db := database.Pg.Scopes(queryFunc, sortFunc).
+		Limit(limit).
+		Offset(offset).
+		Preload("AppMail").
+		Joins("JOIN \"app_mails\" ON \"app_mails\".\"id\" = \"app_mail_id\"").
+		Find(&smimes)
+	if db.Error != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, db.Error.Error())
+	}
+
+	total := int64(0)
+	database.Pg.Scopes(queryFunc).
+		Model(&models.Smime{}).
+		Joins("JOIN \"app_mails\" ON \"app_mails\".\"id\" = \"app_mail_id\"").
+		Count(&total)
+	pageCount := pagination.Count(int(total), limit)
+
+	paginationModel := pagination.CreatePaginationModel(limit, page, pageCount, int(total), toSmimePagination(smimes))
+
+	return c.Status(fiber.StatusOK).JSON(paginationModel)
+}
+
+// GetSmime func for getting a S/MIME record.
+func GetSmime(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the S/MIME record.
+	smime, err := services.GetSmime(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if smime.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.SmimeExists, "Smime does not exist.")
+	}
+
+	response := responses.Smime{}
+	response.SetSmime(smime)
+
+	return c.JSON(response)
+}
+
+// CreateSmime func for creating a new S/MIME record.
+func CreateSmime(c *fiber.Ctx) error {
+	// Create a new smime struct for the request.
+	req := &requests.CreateSmime{}
+
+	// Check, if received JSON data is parsed.
+	if err := c.BodyParser(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate smime fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Check if app exists.
+	if available, err := services.IsAppAvailable(req.App); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if !available {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.AppExists, "AppName does not exist.")
+	}
+
+	// Check if smime exists.
+	if available, err := services.IsSmimeAvailable(req.App, req.Mail); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if available {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.SmimeAvailable, "Smime of the mail already exist.")
+	}
+
+	// Check if app mail exists.
+	if appMail, err := services.GetAppMail(req.App, req.Mail); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if appMail.ID == 0 {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.MailExists, "Mail does not exist.")
+	}
+
+	// Check if the certificate and the private key are valid.
+	if err := services.ValidateSmime(req.Certificate, req.PrivateKey); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.SmimeInvalid, err.Error())
+	}
+
+	// Create smime.
+	smime, err := services.CreateSmime(req)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Return the smime.
+	if smime != nil {
+		response := responses.Smime{}
+		response.SetSmime(smime)
+
+		return c.JSON(response)
+	} else {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, "Failed to create smime.")
+	}
+}
+
+// UpdateSmime func for updating a S/MIME record.
+func UpdateSmime(c *fiber.Ctx) error {
+	// Create a new smime struct for the request.
+	req := &requests.UpdateSmime{}
+
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Check, if received JSON data is parsed.
+	if err := c.BodyParser(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate smime fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Find the S/MIME record.
+	smime, err := services.GetSmime(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if smime.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.SmimeExists, "Smime does not exist.")
+	}
+
+	// Check if the smime data has been modified since it was last fetched.
+	if req.UpdatedAt.Unix() < smime.UpdatedAt.Unix() {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.OutOfSync, "Data is out of sync.")
+	}
+
+	// Check if the certificate and the (stored) private key are valid.
+	privateKey := req.PrivateKey
+	if privateKey == "" {
+		if privateKey, err = smime.DecryptPrivateKey(); err != nil {
+			return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+		}
+	}
+	if err := services.ValidateSmime(req.Certificate, privateKey); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.SmimeInvalid, err.Error())
+	}
+
+	// Update smime.
+	smime, err = services.UpdateSmime(smime, req)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Return the smime.
+	if smime != nil {
+		response := responses.Smime{}
+		response.SetSmime(smime)
+
+		return c.JSON(response)
+	} else {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, "Failed to update smime.")
+	}
+}
+
+// DeleteSmime func for deleting a S/MIME record.
+func DeleteSmime(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the S/MIME record.
+	smime, err := services.GetSmime(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if smime.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.SmimeExists, "Smime does not exist.")
+	}
+
+	// Delete the S/MIME record.
+	if err := services.DeleteSmime(smime); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RestoreSmime func for restoring a deleted S/MIME record.
+func RestoreSmime(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the S/MIME record.
+	smime, err := services.GetSmime(id, true)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if smime.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.SmimeExists, "Smime does not exist.")
+	}
+
+	// Restore the S/MIME record.
+	if err := services.RestoreSmime(smime); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// toSmimePagination func for converting S/MIME records to S/MIME responses.
+func toSmimePagination(smimes []models.Smime) []responses.Smime {
+	smimeResponses := make([]responses.Smime, len(smimes))
+
+	for i := range smimes {
+		response := responses.Smime{}
+		response.SetSmime(&smimes[i])
+		smimeResponses[i] = response
+	}
+
+	return smimeResponses
+}
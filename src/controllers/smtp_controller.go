@@ -100,6 +100,16 @@ func CreateSmtp(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
 	}
 
+	// Check if the DKIM headers contain the From header.
+	if !services.IsValidDkimHeaders(req.DkimHeaders) {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.DkimHeaders, "DkimHeaders must contain the From header.")
+	}
+
+	// Check if the CA bundle contains certificates.
+	if !services.IsValidCaBundle(req.CaBundle) {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.SmtpCaBundle, "CaBundle must contain PEM certificates.")
+	}
+
 	// Check if app exists.
 	if available, err := services.IsAppAvailable(req.App); err != nil {
 		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
@@ -153,6 +163,16 @@ func UpdateSmtp(c *fiber.Ctx) error {
 		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
 	}
 
+	// Check if the DKIM headers contain the From header.
+	if !services.IsValidDkimHeaders(req.DkimHeaders) {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.DkimHeaders, "DkimHeaders must contain the From header.")
+	}
+
+	// Check if the CA bundle contains certificates.
+	if !services.IsValidCaBundle(req.CaBundle) {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.SmtpCaBundle, "CaBundle must contain PEM certificates.")
+	}
+
 	// Find the SMTP.
 	smtp, err := services.GetSmtp(id)
 	if err != nil {
@@ -231,6 +251,61 @@ func RestoreSmtp(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// VerifySmtpDomain func for checking the DKIM, SPF and DMARC records of a SMTP record.
+func VerifySmtpDomain(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the SMTP.
+	smtp, err := services.GetSmtp(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if smtp.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.SmtpExists, "Smtp does not exist.")
+	}
+
+	// Check if the SMTP has a DKIM domain.
+	if smtp.DkimDomain == nil || *smtp.DkimDomain == "" {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.DkimDomain, "Smtp has no DKIM domain.")
+	}
+
+	// Verify the domain.
+	verification, err := services.VerifySmtpDomain(c.Context(), smtp)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errors.DomainVerification, err.Error())
+	}
+
+	response := responses.DomainVerification{}
+	response.SetDomainVerification(verification)
+
+	return c.JSON(response)
+}
+
+// GetSmtpDomainVerification func for getting the last domain verification of a SMTP record.
+func GetSmtpDomainVerification(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the domain verification.
+	verification, err := services.GetDomainVerification(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if verification.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.DomainVerification, "DomainVerification does not exist.")
+	}
+
+	response := responses.DomainVerification{}
+	response.SetDomainVerification(verification)
+
+	return c.JSON(response)
+}
+
 // toSmtpPagination func for converting SMTPs to SMTP responses.
 func toSmtpPagination(smtps []models.Smtp) []responses.Smtp {
 	smtpResponses := make([]responses.Smtp, len(smtps))
@@ -243,3 +318,44 @@ func toSmtpPagination(smtps []models.Smtp) []responses.Smtp {
 
 	return smtpResponses
 }
+
+// TestSmtp func for testing the connection and credentials of a SMTP record.
+func TestSmtp(c *fiber.Ctx) error {
+	// Create a new test struct for the request.
+	req := &requests.TestSmtp{}
+
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Check, if received JSON data is parsed, the body is optional.
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(req); err != nil {
+			return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+		}
+	}
+
+	// Validate test fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Find the SMTP.
+	smtp, err := services.GetSmtp(id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if smtp.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.SmtpExists, "Smtp does not exist.")
+	}
+
+	// Test the connection.
+	check := services.CheckSmtpConnection(smtp, req.To)
+
+	response := responses.ConnectionCheck{}
+	response.SetConnectionCheck(check)
+
+	return c.JSON(response)
+}
@@ -0,0 +1,387 @@
+package controllers
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/dto/responses"
+	"api-mail/main/src/errors"
+	"api-mail/main/src/models"
+	"api-mail/main/src/services"
+	errorutil "github.com/ArnoldPMolenaar/api-utils/errors"
+	"github.com/ArnoldPMolenaar/api-utils/pagination"
+	"github.com/ArnoldPMolenaar/api-utils/utils"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+)
+
+// GetTemplates func for getting the templates of an app.
+func GetTemplates(c *fiber.Ctx) error {
+	// Check if app exists.
+	if available, err := services.IsAppAvailable(c.Params("name")); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if !available {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.AppExists, "AppName does not exist.")
+	}
+
+	templates := make([]models.Template, 0)
+	values := c.Request().URI().QueryArgs()
+	allowedColumns := map[string]bool{
+		"id":                true,
+		"name":              true,
+		"active_version_id": true,
+		"created_at":        true,
+		"updated_at":        true,
+	}
+
+	queryFunc := pagination.Query(values, allowedColumns)
+	sortFunc := pagination.Sort(values, allowedColumns)
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 {
+		limit = 10
+	}
+	offset := pagination.Offset(page, limit)
+
+	db := database.Pg.Scopes(queryFunc, sortFunc).
+		Where("app_name = ?", c.Params("name")).
+		Limit(limit).
+		Offset(offset).
+		Find(&templates)
+	if db.Error != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, db.Error.Error())
+	}
+
+	total := int64(0)
+	database.Pg.Scopes(queryFunc).
+		Model(&models.Template{}).
+		Where("app_name = ?", c.Params("name")).
+		Count(&total)
+	pageCount := pagination.Count(int(total), limit)
+
+	paginationModel := pagination.CreatePaginationModel(limit, page, pageCount, int(total), toTemplatePagination(templates))
+
+	return c.Status(fiber.StatusOK).JSON(paginationModel)
+}
+
+// CreateTemplate func for creating a template of an app with its first version.
+func CreateTemplate(c *fiber.Ctx) error {
+	// Create a new template struct for the request.
+	req := &requests.CreateTemplate{}
+
+	// Check, if received JSON data is parsed.
+	if err := c.BodyParser(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate template fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Check that the content parses.
+	if err := services.ParseTemplateVersion(req.Subject, req.Html, req.Text); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.TemplateParse, err.Error())
+	}
+
+	// Check if app exists.
+	if available, err := services.IsAppAvailable(c.Params("name")); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if !available {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.AppExists, "AppName does not exist.")
+	}
+
+	// Check if the name is already used.
+	if available, err := services.IsTemplateAvailable(c.Params("name"), req.Name, 0); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if available {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.TemplateAvailable, "Template name already exist.")
+	}
+
+	// Create the template.
+	template, version, err := services.CreateTemplate(c.Params("name"), req)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	response := responses.Template{}
+	response.SetTemplate(template, version)
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// GetTemplate func for getting a template of an app with its active version.
+func GetTemplate(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the template.
+	template, err := services.GetTemplate(c.Params("name"), id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if template.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.TemplateExists, "Template does not exist.")
+	}
+
+	// Find the active version.
+	version, err := services.GetActiveTemplateVersion(template)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	response := responses.Template{}
+	response.SetTemplate(template, version)
+
+	return c.JSON(response)
+}
+
+// UpdateTemplate func for updating the name and description of a template.
+func UpdateTemplate(c *fiber.Ctx) error {
+	// Create a new template struct for the request.
+	req := &requests.UpdateTemplate{}
+
+	// Check, if received JSON data is parsed.
+	if err := c.BodyParser(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate template fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the template.
+	template, err := services.GetTemplate(c.Params("name"), id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if template.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.TemplateExists, "Template does not exist.")
+	}
+
+	// Check if the name is already used by another template.
+	if available, err := services.IsTemplateAvailable(template.AppName, req.Name, template.ID); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if available {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.TemplateAvailable, "Template name already exist.")
+	}
+
+	// Update the template.
+	if template, err = services.UpdateTemplate(template, req); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	// Find the active version.
+	version, err := services.GetActiveTemplateVersion(template)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	response := responses.Template{}
+	response.SetTemplate(template, version)
+
+	return c.JSON(response)
+}
+
+// DeleteTemplate func for deleting a template with its versions.
+func DeleteTemplate(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the template.
+	template, err := services.GetTemplate(c.Params("name"), id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if template.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.TemplateExists, "Template does not exist.")
+	}
+
+	// Delete the template.
+	if err := services.DeleteTemplate(template); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetTemplateVersions func for getting the versions of a template, the newest first.
+func GetTemplateVersions(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the template.
+	template, err := services.GetTemplate(c.Params("name"), id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if template.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.TemplateExists, "Template does not exist.")
+	}
+
+	// Find the versions.
+	versions, err := services.GetTemplateVersions(template.ID)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	response := make([]responses.TemplateVersion, len(versions))
+	for i := range versions {
+		response[i].SetTemplateVersion(&versions[i])
+	}
+
+	return c.JSON(response)
+}
+
+// CreateTemplateVersion func for adding a version to a template.
+func CreateTemplateVersion(c *fiber.Ctx) error {
+	// Create a new template version struct for the request.
+	req := &requests.CreateTemplateVersion{}
+
+	// Check, if received JSON data is parsed.
+	if err := c.BodyParser(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+	}
+
+	// Validate template version fields.
+	validate := utils.NewValidator()
+	if err := validate.Struct(req); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.Validator, utils.ValidatorErrors(err))
+	}
+
+	// Check that the content parses.
+	if err := services.ParseTemplateVersion(req.Subject, req.Html, req.Text); err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errors.TemplateParse, err.Error())
+	}
+
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the template.
+	template, err := services.GetTemplate(c.Params("name"), id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if template.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.TemplateExists, "Template does not exist.")
+	}
+
+	// Create the version.
+	version, err := services.CreateTemplateVersion(template, req)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	response := responses.TemplateVersion{}
+	response.SetTemplateVersion(version)
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// GetTemplateVersion func for getting a version of a template.
+func GetTemplateVersion(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the template.
+	template, err := services.GetTemplate(c.Params("name"), id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if template.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.TemplateExists, "Template does not exist.")
+	}
+
+	// Get the version number from the URL.
+	number, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the template version.
+	version, err := services.GetTemplateVersion(template.ID, number)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if version.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.TemplateVersionExists, "TemplateVersion does not exist.")
+	}
+
+	response := responses.TemplateVersion{}
+	response.SetTemplateVersion(version)
+
+	return c.JSON(response)
+}
+
+// ActivateTemplateVersion func for making a version the version of its template that is sent.
+func ActivateTemplateVersion(c *fiber.Ctx) error {
+	// Get the ID from the URL.
+	id, err := utils.StringToUint(c.Params("id"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the template.
+	template, err := services.GetTemplate(c.Params("name"), id)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if template.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.TemplateExists, "Template does not exist.")
+	}
+
+	// Get the version number from the URL.
+	number, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusBadRequest, errorutil.InvalidParam, err.Error())
+	}
+
+	// Find the template version.
+	version, err := services.GetTemplateVersion(template.ID, number)
+	if err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	} else if version.ID == 0 {
+		return errorutil.Response(c, fiber.StatusNotFound, errors.TemplateVersionExists, "TemplateVersion does not exist.")
+	}
+
+	// Activate the version.
+	if err := services.ActivateTemplateVersion(template, version); err != nil {
+		return errorutil.Response(c, fiber.StatusInternalServerError, errorutil.QueryError, err.Error())
+	}
+
+	response := responses.Template{}
+	response.SetTemplate(template, version)
+
+	return c.JSON(response)
+}
+
+// toTemplatePagination func for converting templates to template responses.
+func toTemplatePagination(templates []models.Template) []responses.Template {
+	templateResponses := make([]responses.Template, len(templates))
+
+	for i := range templates {
+		response := responses.Template{}
+		response.SetTemplate(&templates[i], nil)
+		templateResponses[i] = response
+	}
+
+	return templateResponses
+}
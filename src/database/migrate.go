@@ -12,15 +12,32 @@ func Migrate(db *gorm.DB) error {
 		models.App{},
 		models.Mail{},
 		models.AppMailPrimaryType{},
+		models.SmtpEncryption{},
+		models.SmtpAuth{},
 		models.Smtp{},
+		models.AzureCloud{},
 		models.Azure{},
 		models.Gmail{},
+		models.GmailSendAs{},
 		models.AppMail{},
+		models.AttachmentPolicy{},
+		models.Attachment{},
 		models.DkimCanonicalization{},
+		models.DkimAlgorithm{},
+		models.DkimKey{},
+		models.DomainVerification{},
+		models.Template{},
+		models.TemplateVersion{},
 		models.SendMail{},
 		models.SendMailCc{},
 		models.SendMailBcc{},
-		models.SendMailAttachment{})
+		models.SendMailAttachment{},
+		models.AttachmentLink{},
+		models.CalendarEvent{},
+		models.Smime{},
+		models.SmimeCertificate{},
+		models.PgpKey{},
+		models.PgpPublicKey{})
 	if err != nil {
 		return err
 	}
@@ -33,6 +50,30 @@ func Migrate(db *gorm.DB) error {
 		}
 	}
 
+	// Seed SmtpEncryption.
+	smtpEncryptions := []string{"None", "SSL/TLS", "STARTTLS"}
+	for _, smtpEncryption := range smtpEncryptions {
+		if err := db.FirstOrCreate(&models.SmtpEncryption{}, models.SmtpEncryption{Name: smtpEncryption}).Error; err != nil {
+			return err
+		}
+	}
+
+	// Seed SmtpAuth.
+	smtpAuths := []string{"None", "PLAIN", "LOGIN", "CRAM-MD5", "XOAUTH2"}
+	for _, smtpAuth := range smtpAuths {
+		if err := db.FirstOrCreate(&models.SmtpAuth{}, models.SmtpAuth{Name: smtpAuth}).Error; err != nil {
+			return err
+		}
+	}
+
+	// Seed AzureCloud.
+	azureClouds := []string{"Global", "USGovernment", "China"}
+	for _, azureCloud := range azureClouds {
+		if err := db.FirstOrCreate(&models.AzureCloud{}, models.AzureCloud{Name: azureCloud}).Error; err != nil {
+			return err
+		}
+	}
+
 	// Seed DkimCanonicalization.
 	dkimCanonicalization := []string{"Simple", "Relaxed"}
 	for _, dkimCanonicalization := range dkimCanonicalization {
@@ -41,5 +82,13 @@ func Migrate(db *gorm.DB) error {
 		}
 	}
 
+	// Seed DkimAlgorithm.
+	dkimAlgorithms := []string{"RSA", "Ed25519"}
+	for _, dkimAlgorithm := range dkimAlgorithms {
+		if err := db.FirstOrCreate(&models.DkimAlgorithm{}, models.DkimAlgorithm{Name: dkimAlgorithm}).Error; err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,10 @@
+package requests
+
+import "time"
+
+type CreateAttachment struct {
+	FileName  string     `json:"fileName" validate:"required"`
+	FileType  string     `json:"fileType" validate:"required"`
+	FileData  []byte     `json:"fileData" validate:"required"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
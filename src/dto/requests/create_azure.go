@@ -2,11 +2,15 @@ package requests
 
 // CreateAzure struct for creating a new Azure.
 type CreateAzure struct {
-	App      string `json:"app" validate:"required"`
-	Mail     string `json:"mail" validate:"required,email"`
-	ClientID string `json:"clientId" validate:"required"`
-	TenantID string `json:"tenantId" validate:"required"`
-	Secret   string `json:"secret" validate:"required"`
-	User     string `json:"user" validate:"required"`
-	Primary  bool   `json:"primary"`
+	App          string  `json:"app" validate:"required"`
+	Mail         string  `json:"mail" validate:"required,email"`
+	ClientID     string  `json:"clientId" validate:"required"`
+	TenantID     string  `json:"tenantId" validate:"required"`
+	Secret       string  `json:"secret" validate:"required"`
+	User         string  `json:"user" validate:"required"`
+	SmtpDelivery bool    `json:"smtpDelivery"`
+	Cloud        *string `json:"cloud" validate:"omitempty,oneof=Global USGovernment China"`
+	AuthorityURL *string `json:"authorityUrl" validate:"omitempty,url"`
+	GraphURL     *string `json:"graphUrl" validate:"omitempty,url"`
+	Primary      bool    `json:"primary"`
 }
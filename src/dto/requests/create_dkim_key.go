@@ -0,0 +1,11 @@
+package requests
+
+// CreateDkimKey struct for generating a new DKIM key.
+type CreateDkimKey struct {
+	App       string `json:"app" validate:"required"`
+	Domain    string `json:"domain" validate:"required,fqdn"`
+	Selector  string `json:"selector" validate:"required,hostname_rfc1123"`
+	Algorithm string `json:"algorithm" validate:"required,oneof=RSA Ed25519"`
+	Bits      int    `json:"bits" validate:"omitempty,oneof=1024 2048 4096"`
+	Active    bool   `json:"active"`
+}
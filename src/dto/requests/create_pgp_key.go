@@ -0,0 +1,9 @@
+package requests
+
+// CreatePgpKey struct for creating a new OpenPGP private key of an app mail.
+type CreatePgpKey struct {
+	App        string `json:"app" validate:"required"`
+	Mail       string `json:"mail" validate:"required,email"`
+	PrivateKey string `json:"privateKey" validate:"required"`
+	Passphrase string `json:"passphrase"`
+}
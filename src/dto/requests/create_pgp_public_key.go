@@ -0,0 +1,8 @@
+package requests
+
+// CreatePgpPublicKey struct for creating a new OpenPGP public key of a recipient.
+type CreatePgpPublicKey struct {
+	App       string `json:"app" validate:"required"`
+	Mail      string `json:"mail" validate:"required,email"`
+	PublicKey string `json:"publicKey" validate:"required"`
+}
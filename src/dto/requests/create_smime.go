@@ -0,0 +1,9 @@
+package requests
+
+// CreateSmime struct for creating a new S/MIME certificate of an app mail.
+type CreateSmime struct {
+	App         string `json:"app" validate:"required"`
+	Mail        string `json:"mail" validate:"required,email"`
+	Certificate string `json:"certificate" validate:"required"`
+	PrivateKey  string `json:"privateKey" validate:"required"`
+}
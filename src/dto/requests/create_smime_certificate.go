@@ -0,0 +1,8 @@
+package requests
+
+// CreateSmimeCertificate struct for creating a new S/MIME certificate of a recipient.
+type CreateSmimeCertificate struct {
+	App         string `json:"app" validate:"required"`
+	Mail        string `json:"mail" validate:"required,email"`
+	Certificate string `json:"certificate" validate:"required"`
+}
@@ -2,14 +2,30 @@ package requests
 
 // CreateSmtp struct for creating a new SMTP.
 type CreateSmtp struct {
-	App                  string  `json:"app" validate:"required"`
-	Mail                 string  `json:"mail" validate:"required,email"`
-	Username             string  `json:"username" validate:"required"`
-	Password             string  `json:"password" validate:"required"`
-	Host                 string  `json:"host" validate:"required"`
-	Port                 int     `json:"port" validate:"required"`
-	DkimPrivateKey       *string `json:"dkimPrivateKey"`
-	DkimDomain           *string `json:"dkimDomain"`
-	DkimCanonicalization *string `json:"dkimCanonicalization"`
-	Primary              bool    `json:"primary"`
+	App                  string   `json:"app" validate:"required"`
+	Mail                 string   `json:"mail" validate:"required,email"`
+	Username             string   `json:"username" validate:"required_unless=Auth None"`
+	Password             string   `json:"password" validate:"required_unless=Auth None"`
+	Host                 string   `json:"host" validate:"required"`
+	Port                 int      `json:"port" validate:"required"`
+	Encryption           *string  `json:"encryption" validate:"omitempty,oneof=None SSL/TLS STARTTLS"`
+	Auth                 *string  `json:"auth" validate:"omitempty,oneof=None PLAIN LOGIN CRAM-MD5 XOAUTH2"`
+	TlsServerName        *string  `json:"tlsServerName" validate:"omitempty,hostname_rfc1123"`
+	CaBundle             *string  `json:"caBundle"`
+	ConnectTimeout       *int     `json:"connectTimeout" validate:"omitempty,min=1,max=300"`
+	SendTimeout          *int     `json:"sendTimeout" validate:"omitempty,min=1,max=3600"`
+	HeloName             *string  `json:"heloName" validate:"omitempty,hostname_rfc1123"`
+	KeepAlive            *bool    `json:"keepAlive"`
+	MaxConnections       *int     `json:"maxConnections" validate:"omitempty,min=1,max=100"`
+	IdleTimeout          *int     `json:"idleTimeout" validate:"omitempty,min=1,max=3600"`
+	ImapHost             *string  `json:"imapHost" validate:"omitempty,hostname_rfc1123"`
+	ImapPort             *int     `json:"imapPort" validate:"omitempty,min=1,max=65535"`
+	SentFolder           *string  `json:"sentFolder" validate:"omitempty,max=255"`
+	DraftsFolder         *string  `json:"draftsFolder" validate:"omitempty,max=255"`
+	DkimPrivateKey       *string  `json:"dkimPrivateKey"`
+	DkimDomain           *string  `json:"dkimDomain"`
+	DkimCanonicalization *string  `json:"dkimCanonicalization"`
+	DkimSelector         *string  `json:"dkimSelector"`
+	DkimHeaders          []string `json:"dkimHeaders"`
+	Primary              bool     `json:"primary"`
 }
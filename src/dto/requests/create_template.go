@@ -0,0 +1,10 @@
+package requests
+
+// CreateTemplate struct for creating a template with its first version.
+type CreateTemplate struct {
+	Name        string  `json:"name" validate:"required"`
+	Description *string `json:"description"`
+	Subject     string  `json:"subject" validate:"required"`
+	Html        *string `json:"html"`
+	Text        *string `json:"text"`
+}
@@ -0,0 +1,9 @@
+package requests
+
+// CreateTemplateVersion struct for adding a version to a template.
+type CreateTemplateVersion struct {
+	Subject  string  `json:"subject" validate:"required"`
+	Html     *string `json:"html"`
+	Text     *string `json:"text"`
+	Activate bool    `json:"activate"`
+}
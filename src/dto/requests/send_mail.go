@@ -1,17 +1,26 @@
 package requests
 
 type SendMail struct {
-	App         string               `json:"app" validate:"required"`
-	Mail        string               `json:"mail"`
-	Type        *string              `json:"type"`
-	FromName    string               `json:"fromName"`
-	FromMail    string               `json:"fromMail" validate:"email"`
-	To          string               `json:"to" validate:"required,email"`
-	Subject     string               `json:"subject" validate:"required"`
-	Body        string               `json:"body" validate:"required"`
-	MimeType    string               `json:"mimeType"`
-	Ccs         []string             `json:"ccs"`
-	Bccs        []string             `json:"bccs"`
-	Attachments []SendMailAttachment `json:"attachments" validate:"dive"`
-	DisableSave bool                 `json:"disableSave,omitempty"`
+	App             string               `json:"app" validate:"required"`
+	Mail            string               `json:"mail"`
+	Type            *string              `json:"type"`
+	FromName        string               `json:"fromName"`
+	FromMail        string               `json:"fromMail" validate:"email"`
+	To              string               `json:"to" validate:"required,email"`
+	Subject         string               `json:"subject" validate:"required_without=TemplateID,excluded_with=TemplateID"`
+	Body            string               `json:"body" validate:"required_without=TemplateID,excluded_with=TemplateID"`
+	MimeType        string               `json:"mimeType"`
+	TextBody        string               `json:"textBody" validate:"excluded_with=TemplateID"`
+	TemplateID      *uint                `json:"templateId"`
+	Data            map[string]any       `json:"data"`
+	Ccs             []string             `json:"ccs"`
+	Bccs            []string             `json:"bccs"`
+	Attachments     []SendMailAttachment `json:"attachments" validate:"dive"`
+	AttachmentIDs   []uint               `json:"attachmentIds"`
+	LinkAttachments bool                 `json:"linkAttachments,omitempty"`
+	Event           *SendMailEvent       `json:"event"`
+	Smime           *SendMailSmime       `json:"smime"`
+	Pgp             *SendMailPgp         `json:"pgp"`
+	DisableSave     bool                 `json:"disableSave,omitempty"`
+	Mode            *string              `json:"mode" validate:"omitempty,oneof=send draft"`
 }
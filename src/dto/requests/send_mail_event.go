@@ -0,0 +1,26 @@
+package requests
+
+import "time"
+
+// SendMailEvent struct for sending a calendar invitation with the mail.
+type SendMailEvent struct {
+	Method      string                  `json:"method" validate:"required,oneof=REQUEST CANCEL"`
+	UID         string                  `json:"uid" validate:"required"`
+	Sequence    *int                    `json:"sequence" validate:"omitempty,min=0"`
+	Organizer   *SendMailEventAttendee  `json:"organizer"`
+	Attendees   []SendMailEventAttendee `json:"attendees" validate:"dive"`
+	Start       *time.Time              `json:"start"`
+	End         *time.Time              `json:"end"`
+	TimeZone    string                  `json:"timeZone"`
+	Location    string                  `json:"location"`
+	Summary     string                  `json:"summary"`
+	Description string                  `json:"description"`
+}
+
+// SendMailEventAttendee struct for the organizer or an attendee of a calendar invitation.
+type SendMailEventAttendee struct {
+	Name string `json:"name"`
+	Mail string `json:"mail" validate:"required,email"`
+	Role string `json:"role" validate:"omitempty,oneof=REQ-PARTICIPANT OPT-PARTICIPANT NON-PARTICIPANT CHAIR"`
+	Rsvp bool   `json:"rsvp"`
+}
@@ -0,0 +1,7 @@
+package requests
+
+// SendMailPgp struct for signing and encrypting the mail with OpenPGP.
+type SendMailPgp struct {
+	Sign    bool `json:"sign"`
+	Encrypt bool `json:"encrypt"`
+}
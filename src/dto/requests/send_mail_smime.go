@@ -0,0 +1,7 @@
+package requests
+
+// SendMailSmime struct for signing and encrypting the mail with S/MIME.
+type SendMailSmime struct {
+	Sign    bool `json:"sign"`
+	Encrypt bool `json:"encrypt"`
+}
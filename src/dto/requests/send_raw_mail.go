@@ -0,0 +1,11 @@
+package requests
+
+// SendRawMail struct for sending a complete RFC 822 message.
+type SendRawMail struct {
+	App         string  `json:"app" form:"app" validate:"required"`
+	Mail        string  `json:"mail" form:"mail"`
+	Type        *string `json:"type" form:"type"`
+	Raw         []byte  `json:"raw" form:"-"`
+	DisableSave bool    `json:"disableSave,omitempty" form:"disableSave"`
+	Mode        *string `json:"mode" form:"mode" validate:"omitempty,oneof=send draft"`
+}
@@ -0,0 +1,6 @@
+package requests
+
+// TestSmtp struct for testing the connection of a SMTP, a test mail is sent when To is set.
+type TestSmtp struct {
+	To *string `json:"to" validate:"omitempty,email"`
+}
@@ -0,0 +1,6 @@
+package requests
+
+// UpdateAppMail struct for updating the settings of an app mail.
+type UpdateAppMail struct {
+	SaveSentCopy bool `json:"saveSentCopy"`
+}
@@ -0,0 +1,12 @@
+package requests
+
+// UpdateAttachmentPolicy struct for creating or replacing the attachment policy of an app.
+type UpdateAttachmentPolicy struct {
+	AllowedTypes      []string `json:"allowedTypes" validate:"dive,required"`
+	BlockedTypes      []string `json:"blockedTypes" validate:"dive,required"`
+	AllowedExtensions []string `json:"allowedExtensions" validate:"dive,required"`
+	BlockedExtensions []string `json:"blockedExtensions" validate:"dive,required"`
+	MaxFileSize       *int64   `json:"maxFileSize" validate:"omitempty,min=1"`
+	MaxTotalSize      *int64   `json:"maxTotalSize" validate:"omitempty,min=1"`
+	MaxCount          *int     `json:"maxCount" validate:"omitempty,min=0"`
+}
@@ -4,10 +4,14 @@ import "time"
 
 // UpdateAzure struct for updating a Azure record.
 type UpdateAzure struct {
-	ClientID  string    `json:"clientId" validate:"required"`
-	TenantID  string    `json:"tenantId" validate:"required"`
-	Secret    string    `json:"secret" validate:"required"`
-	User      string    `json:"user" validate:"required"`
-	Primary   bool      `json:"primary"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ClientID     string    `json:"clientId" validate:"required"`
+	TenantID     string    `json:"tenantId" validate:"required"`
+	Secret       string    `json:"secret" validate:"required"`
+	User         string    `json:"user" validate:"required"`
+	SmtpDelivery bool      `json:"smtpDelivery"`
+	Cloud        *string   `json:"cloud" validate:"omitempty,oneof=Global USGovernment China"`
+	AuthorityURL *string   `json:"authorityUrl" validate:"omitempty,url"`
+	GraphURL     *string   `json:"graphUrl" validate:"omitempty,url"`
+	Primary      bool      `json:"primary"`
+	UpdatedAt    time.Time `json:"updatedAt"`
 }
@@ -0,0 +1,10 @@
+package requests
+
+import "time"
+
+// UpdatePgpKey struct for updating an OpenPGP private key.
+type UpdatePgpKey struct {
+	PrivateKey string    `json:"privateKey" validate:"required"`
+	Passphrase string    `json:"passphrase"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,9 @@
+package requests
+
+import "time"
+
+// UpdatePgpPublicKey struct for updating an OpenPGP public key of a recipient.
+type UpdatePgpPublicKey struct {
+	PublicKey string    `json:"publicKey" validate:"required"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,10 @@
+package requests
+
+import "time"
+
+// UpdateSmime struct for updating a S/MIME record.
+type UpdateSmime struct {
+	Certificate string    `json:"certificate" validate:"required"`
+	PrivateKey  string    `json:"privateKey"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,9 @@
+package requests
+
+import "time"
+
+// UpdateSmimeCertificate struct for updating a S/MIME certificate of a recipient.
+type UpdateSmimeCertificate struct {
+	Certificate string    `json:"certificate" validate:"required"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
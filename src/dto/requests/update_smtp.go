@@ -4,13 +4,29 @@ import "time"
 
 // UpdateSmtp struct for updating a SMTP record.
 type UpdateSmtp struct {
-	Username             string    `json:"username" validate:"required"`
+	Username             string    `json:"username" validate:"required_unless=Auth None"`
 	Password             string    `json:"password"`
 	Host                 string    `json:"host" validate:"required"`
 	Port                 int       `json:"port" validate:"required"`
+	Encryption           *string   `json:"encryption" validate:"omitempty,oneof=None SSL/TLS STARTTLS"`
+	Auth                 *string   `json:"auth" validate:"omitempty,oneof=None PLAIN LOGIN CRAM-MD5 XOAUTH2"`
+	TlsServerName        *string   `json:"tlsServerName" validate:"omitempty,hostname_rfc1123"`
+	CaBundle             *string   `json:"caBundle"`
+	ConnectTimeout       *int      `json:"connectTimeout" validate:"omitempty,min=1,max=300"`
+	SendTimeout          *int      `json:"sendTimeout" validate:"omitempty,min=1,max=3600"`
+	HeloName             *string   `json:"heloName" validate:"omitempty,hostname_rfc1123"`
+	KeepAlive            *bool     `json:"keepAlive"`
+	MaxConnections       *int      `json:"maxConnections" validate:"omitempty,min=1,max=100"`
+	IdleTimeout          *int      `json:"idleTimeout" validate:"omitempty,min=1,max=3600"`
+	ImapHost             *string   `json:"imapHost" validate:"omitempty,hostname_rfc1123"`
+	ImapPort             *int      `json:"imapPort" validate:"omitempty,min=1,max=65535"`
+	SentFolder           *string   `json:"sentFolder" validate:"omitempty,max=255"`
+	DraftsFolder         *string   `json:"draftsFolder" validate:"omitempty,max=255"`
 	DkimPrivateKey       *string   `json:"dkimPrivateKey"`
 	DkimDomain           *string   `json:"dkimDomain"`
 	DkimCanonicalization *string   `json:"dkimCanonicalization"`
+	DkimSelector         *string   `json:"dkimSelector"`
+	DkimHeaders          []string  `json:"dkimHeaders"`
 	Primary              bool      `json:"primary"`
 	UpdatedAt            time.Time `json:"updatedAt"`
 }
@@ -0,0 +1,7 @@
+package requests
+
+// UpdateTemplate struct for updating the name and description of a template.
+type UpdateTemplate struct {
+	Name        string  `json:"name" validate:"required"`
+	Description *string `json:"description"`
+}
@@ -0,0 +1,23 @@
+package responses
+
+import "api-mail/main/src/models"
+
+// AppMail struct for the app mail response.
+type AppMail struct {
+	ID           uint    `json:"id"`
+	App          string  `json:"app"`
+	Mail         string  `json:"mail"`
+	PrimaryType  *string `json:"primaryType"`
+	SaveSentCopy bool    `json:"saveSentCopy"`
+}
+
+// SetAppMail sets the app mail response.
+func (response *AppMail) SetAppMail(appMail *models.AppMail) {
+	response.ID = appMail.ID
+	response.App = appMail.AppName
+	response.Mail = appMail.MailName
+	if appMail.PrimaryType.Valid {
+		response.PrimaryType = &appMail.PrimaryType.String
+	}
+	response.SaveSentCopy = appMail.SaveSentCopy
+}
@@ -0,0 +1,37 @@
+package responses
+
+import "api-mail/main/src/models"
+
+// AppMailCapabilities struct for the capabilities response of the providers of an app mail.
+type AppMailCapabilities struct {
+	App       string                 `json:"app"`
+	Mail      string                 `json:"mail"`
+	Providers []ProviderCapabilities `json:"providers"`
+}
+
+// ProviderCapabilities struct for the capabilities of a single provider.
+type ProviderCapabilities struct {
+	Type           string `json:"type"`
+	SentCopy       string `json:"sentCopy"`
+	SavesSentCopy  bool   `json:"savesSentCopy"`
+	Smime          bool   `json:"smime"`
+	Pgp            bool   `json:"pgp"`
+	CalendarEvents bool   `json:"calendarEvents"`
+}
+
+// SetAppMailCapabilities sets the capabilities response.
+func (response *AppMailCapabilities) SetAppMailCapabilities(appMail *models.AppMail, capabilities []models.ProviderCapabilities) {
+	response.App = appMail.AppName
+	response.Mail = appMail.MailName
+	response.Providers = make([]ProviderCapabilities, len(capabilities))
+	for i := range capabilities {
+		response.Providers[i] = ProviderCapabilities{
+			Type:           string(capabilities[i].Type),
+			SentCopy:       string(capabilities[i].SentCopy),
+			SavesSentCopy:  capabilities[i].SavesSentCopy,
+			Smime:          capabilities[i].Smime,
+			Pgp:            capabilities[i].Pgp,
+			CalendarEvents: capabilities[i].CalendarEvents,
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package responses
+
+import (
+	"api-mail/main/src/models"
+	"time"
+)
+
+// Attachment struct for the attachment library response.
+type Attachment struct {
+	ID        uint       `json:"id"`
+	App       string     `json:"app"`
+	FileName  string     `json:"fileName"`
+	FileType  string     `json:"fileType"`
+	FileSize  int64      `json:"fileSize"`
+	FileHash  string     `json:"fileHash"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// SetAttachment sets the attachment response.
+func (response *Attachment) SetAttachment(attachment *models.Attachment) {
+	response.ID = attachment.ID
+	response.App = attachment.AppName
+	response.FileName = attachment.FileName
+	response.FileType = attachment.FileType
+	response.FileSize = attachment.FileSize
+	response.FileHash = attachment.FileHash
+	response.CreatedAt = attachment.CreatedAt
+	response.UpdatedAt = attachment.UpdatedAt
+
+	if attachment.ExpiresAt.Valid {
+		response.ExpiresAt = &attachment.ExpiresAt.Time
+	}
+}
@@ -0,0 +1,46 @@
+package responses
+
+import (
+	"api-mail/main/src/models"
+	"strings"
+	"time"
+)
+
+// AttachmentPolicy struct for the attachment policy response.
+type AttachmentPolicy struct {
+	ID                uint      `json:"id"`
+	App               string    `json:"app"`
+	AllowedTypes      []string  `json:"allowedTypes"`
+	BlockedTypes      []string  `json:"blockedTypes"`
+	AllowedExtensions []string  `json:"allowedExtensions"`
+	BlockedExtensions []string  `json:"blockedExtensions"`
+	MaxFileSize       *int64    `json:"maxFileSize"`
+	MaxTotalSize      *int64    `json:"maxTotalSize"`
+	MaxCount          *int      `json:"maxCount"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+// SetAttachmentPolicy sets the attachment policy response.
+func (response *AttachmentPolicy) SetAttachmentPolicy(policy *models.AttachmentPolicy) {
+	response.ID = policy.ID
+	response.App = policy.AppName
+	response.AllowedTypes = splitPolicyList(policy.AllowedTypes)
+	response.BlockedTypes = splitPolicyList(policy.BlockedTypes)
+	response.AllowedExtensions = splitPolicyList(policy.AllowedExtensions)
+	response.BlockedExtensions = splitPolicyList(policy.BlockedExtensions)
+	response.MaxFileSize = policy.MaxFileSize
+	response.MaxTotalSize = policy.MaxTotalSize
+	response.MaxCount = policy.MaxCount
+	response.CreatedAt = policy.CreatedAt
+	response.UpdatedAt = policy.UpdatedAt
+}
+
+// splitPolicyList splits a comma separated list of the policy, nil is an empty list.
+func splitPolicyList(list *string) []string {
+	if list == nil {
+		return []string{}
+	}
+
+	return strings.Split(*list, ",")
+}
@@ -8,18 +8,23 @@ import (
 
 // Azure struct for the Azure response.
 type Azure struct {
-	ID          uint      `json:"id"`
-	AppMailID   uint      `json:"appMailId"`
-	App         string    `json:"app"`
-	Mail        string    `json:"mail"`
-	ClientID    string    `json:"clientId"`
-	TenantID    string    `json:"tenantId"`
-	Secret      string    `json:"secret"`
-	User        string    `json:"user"`
-	Primary     bool      `json:"primary"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	AuthCodeURL *string   `json:"authCodeUrl"`
+	ID           uint      `json:"id"`
+	AppMailID    uint      `json:"appMailId"`
+	App          string    `json:"app"`
+	Mail         string    `json:"mail"`
+	ClientID     string    `json:"clientId"`
+	TenantID     string    `json:"tenantId"`
+	Secret       string    `json:"secret"`
+	User         string    `json:"user"`
+	SmtpDelivery bool      `json:"smtpDelivery"`
+	VerifiedMail *string   `json:"verifiedMail"`
+	Cloud        string    `json:"cloud"`
+	AuthorityURL *string   `json:"authorityUrl"`
+	GraphURL     *string   `json:"graphUrl"`
+	Primary      bool      `json:"primary"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	AuthCodeURL  *string   `json:"authCodeUrl"`
 }
 
 // SetAzure sets the Azure response.
@@ -32,6 +37,13 @@ func (response *Azure) SetAzure(azure *models.Azure, authCodeURL ...string) {
 	response.TenantID = azure.TenantID
 	response.Secret = azure.Secret
 	response.User = azure.User
+	response.SmtpDelivery = azure.SmtpDelivery
+	if azure.VerifiedMail.Valid {
+		response.VerifiedMail = &azure.VerifiedMail.String
+	}
+	response.Cloud = string(azure.Cloud())
+	response.AuthorityURL = azure.AuthorityURL
+	response.GraphURL = azure.GraphURL
 	response.CreatedAt = azure.CreatedAt
 	response.UpdatedAt = azure.UpdatedAt
 
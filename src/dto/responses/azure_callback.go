@@ -18,6 +18,7 @@ type AzureCallback struct {
 	Expiry       *time.Time `json:"expiry"`
 	ExpiresIn    *int64     `json:"expiresIn"`
 	User         string     `json:"user"`
+	VerifiedMail *string    `json:"verifiedMail"`
 	CreatedAt    time.Time  `json:"createdAt"`
 	UpdatedAt    time.Time  `json:"updatedAt"`
 }
@@ -47,6 +48,9 @@ func (response *AzureCallback) SetAzureCallback(azure *models.Azure) {
 	}
 
 	response.User = azure.User
+	if azure.VerifiedMail.Valid {
+		response.VerifiedMail = &azure.VerifiedMail.String
+	}
 	response.CreatedAt = azure.CreatedAt
 	response.UpdatedAt = azure.UpdatedAt
 }
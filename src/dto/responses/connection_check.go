@@ -0,0 +1,38 @@
+package responses
+
+import "api-mail/main/src/models"
+
+// ConnectionCheck struct for the connection test response.
+type ConnectionCheck struct {
+	Success bool                  `json:"success"`
+	Steps   []ConnectionCheckStep `json:"steps"`
+}
+
+// ConnectionCheckStep struct for a single step of the connection test.
+type ConnectionCheckStep struct {
+	Name       string  `json:"name"`
+	Success    bool    `json:"success"`
+	DurationMs int64   `json:"durationMs"`
+	Response   string  `json:"response"`
+	Error      *string `json:"error"`
+}
+
+// SetConnectionCheck sets the connection test response.
+func (response *ConnectionCheck) SetConnectionCheck(check *models.ConnectionCheck) {
+	response.Success = check.Success()
+	response.Steps = make([]ConnectionCheckStep, len(check.Steps))
+
+	for i := range check.Steps {
+		step := &check.Steps[i]
+		response.Steps[i] = ConnectionCheckStep{
+			Name:       step.Name,
+			Success:    step.Success,
+			DurationMs: step.Duration.Milliseconds(),
+			Response:   step.Response,
+		}
+
+		if step.Error != "" {
+			response.Steps[i].Error = &step.Error
+		}
+	}
+}
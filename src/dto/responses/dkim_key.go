@@ -0,0 +1,40 @@
+package responses
+
+import (
+	"api-mail/main/src/models"
+	"time"
+)
+
+// DkimKey struct for the DKIM key response, with the DNS record to publish.
+type DkimKey struct {
+	ID            uint      `json:"id"`
+	App           string    `json:"app"`
+	Domain        string    `json:"domain"`
+	Selector      string    `json:"selector"`
+	Algorithm     string    `json:"algorithm"`
+	PublicKey     string    `json:"publicKey"`
+	Active        bool      `json:"active"`
+	RecordType    string    `json:"recordType"`
+	RecordName    string    `json:"recordName"`
+	RecordValue   string    `json:"recordValue"`
+	RecordStrings []string  `json:"recordStrings"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// SetDkimKey sets the DKIM key response.
+func (response *DkimKey) SetDkimKey(dkimKey *models.DkimKey) {
+	response.ID = dkimKey.ID
+	response.App = dkimKey.AppName
+	response.Domain = dkimKey.Domain
+	response.Selector = dkimKey.Selector
+	response.Algorithm = dkimKey.DkimAlgorithmName
+	response.PublicKey = dkimKey.PublicKey
+	response.Active = dkimKey.Active
+	response.RecordType = "TXT"
+	response.RecordName = dkimKey.RecordName()
+	response.RecordValue = dkimKey.RecordValue()
+	response.RecordStrings = dkimKey.RecordStrings()
+	response.CreatedAt = dkimKey.CreatedAt
+	response.UpdatedAt = dkimKey.UpdatedAt
+}
@@ -0,0 +1,49 @@
+package responses
+
+import (
+	"api-mail/main/src/models"
+	"time"
+)
+
+// DomainVerification struct for the domain verification response.
+type DomainVerification struct {
+	SmtpID     uint                    `json:"smtpId"`
+	FromDomain string                  `json:"fromDomain"`
+	Valid      bool                    `json:"valid"`
+	Dkim       DomainVerificationCheck `json:"dkim"`
+	Spf        DomainVerificationCheck `json:"spf"`
+	Dmarc      DomainVerificationCheck `json:"dmarc"`
+	CheckedAt  time.Time               `json:"checkedAt"`
+}
+
+// DomainVerificationCheck struct for the result of a single DNS check.
+type DomainVerificationCheck struct {
+	Domain    string `json:"domain,omitempty"`
+	Selector  string `json:"selector,omitempty"`
+	Status    string `json:"status"`
+	Diagnosis string `json:"diagnosis"`
+}
+
+// SetDomainVerification sets the domain verification response.
+func (response *DomainVerification) SetDomainVerification(verification *models.DomainVerification) {
+	response.SmtpID = verification.SmtpID
+	response.FromDomain = verification.FromDomain
+	response.Valid = verification.Valid
+	response.Dkim = DomainVerificationCheck{
+		Domain:    verification.DkimDomain,
+		Selector:  verification.DkimSelector,
+		Status:    verification.DkimStatus,
+		Diagnosis: verification.DkimDiagnosis,
+	}
+	response.Spf = DomainVerificationCheck{
+		Domain:    verification.FromDomain,
+		Status:    verification.SpfStatus,
+		Diagnosis: verification.SpfDiagnosis,
+	}
+	response.Dmarc = DomainVerificationCheck{
+		Domain:    verification.FromDomain,
+		Status:    verification.DmarcStatus,
+		Diagnosis: verification.DmarcDiagnosis,
+	}
+	response.CheckedAt = verification.CheckedAt
+}
@@ -8,17 +8,19 @@ import (
 
 // Gmail struct for the Gmail response.
 type Gmail struct {
-	ID          uint      `json:"id"`
-	AppMailID   uint      `json:"appMailId"`
-	App         string    `json:"app"`
-	Mail        string    `json:"mail"`
-	ClientID    string    `json:"clientId"`
-	Secret      string    `json:"secret"`
-	User        string    `json:"user"`
-	Primary     bool      `json:"primary"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	AuthCodeURL *string   `json:"authCodeUrl"`
+	ID           uint      `json:"id"`
+	AppMailID    uint      `json:"appMailId"`
+	App          string    `json:"app"`
+	Mail         string    `json:"mail"`
+	ClientID     string    `json:"clientId"`
+	Secret       string    `json:"secret"`
+	User         string    `json:"user"`
+	SmtpDelivery bool      `json:"smtpDelivery"`
+	VerifiedMail *string   `json:"verifiedMail"`
+	Primary      bool      `json:"primary"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	AuthCodeURL  *string   `json:"authCodeUrl"`
 }
 
 // SetGmail sets the Gmail response.
@@ -30,6 +32,10 @@ func (response *Gmail) SetGmail(gmail *models.Gmail, authCodeURL ...string) {
 	response.ClientID = gmail.ClientID
 	response.Secret = gmail.Secret
 	response.User = gmail.User
+	response.SmtpDelivery = gmail.SmtpDelivery
+	if gmail.VerifiedMail.Valid {
+		response.VerifiedMail = &gmail.VerifiedMail.String
+	}
 	response.CreatedAt = gmail.CreatedAt
 	response.UpdatedAt = gmail.UpdatedAt
 
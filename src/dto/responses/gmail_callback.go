@@ -17,6 +17,7 @@ type GmailCallback struct {
 	Expiry       *time.Time `json:"expiry"`
 	ExpiresIn    *int64     `json:"expiresIn"`
 	User         string     `json:"user"`
+	VerifiedMail *string    `json:"verifiedMail"`
 	CreatedAt    time.Time  `json:"createdAt"`
 	UpdatedAt    time.Time  `json:"updatedAt"`
 }
@@ -45,6 +46,9 @@ func (response *GmailCallback) SetGmailCallback(gmail *models.Gmail) {
 	}
 
 	response.User = gmail.User
+	if gmail.VerifiedMail.Valid {
+		response.VerifiedMail = &gmail.VerifiedMail.String
+	}
 	response.CreatedAt = gmail.CreatedAt
 	response.UpdatedAt = gmail.UpdatedAt
 }
@@ -0,0 +1,47 @@
+package responses
+
+import (
+	"api-mail/main/src/models"
+	"time"
+)
+
+// GmailSendAs struct for the send-as aliases response of a gmail.
+type GmailSendAs struct {
+	GmailID  uint               `json:"gmailId"`
+	SyncedAt *time.Time         `json:"syncedAt"`
+	Aliases  []GmailSendAsAlias `json:"aliases"`
+}
+
+// GmailSendAsAlias struct for a single send-as alias.
+type GmailSendAsAlias struct {
+	SendAsEmail        string `json:"sendAsEmail"`
+	DisplayName        string `json:"displayName"`
+	ReplyToAddress     string `json:"replyToAddress"`
+	IsPrimary          bool   `json:"isPrimary"`
+	IsDefault          bool   `json:"isDefault"`
+	TreatAsAlias       bool   `json:"treatAsAlias"`
+	VerificationStatus string `json:"verificationStatus"`
+	CanSend            bool   `json:"canSend"`
+}
+
+// SetGmailSendAs sets the send-as aliases response.
+func (response *GmailSendAs) SetGmailSendAs(gmail *models.Gmail, sendAs []models.GmailSendAs) {
+	response.GmailID = gmail.ID
+	if gmail.SendAsSyncedAt.Valid {
+		response.SyncedAt = &gmail.SendAsSyncedAt.Time
+	}
+
+	response.Aliases = make([]GmailSendAsAlias, len(sendAs))
+	for i := range sendAs {
+		response.Aliases[i] = GmailSendAsAlias{
+			SendAsEmail:        sendAs[i].SendAsEmail,
+			DisplayName:        sendAs[i].DisplayName,
+			ReplyToAddress:     sendAs[i].ReplyToAddress,
+			IsPrimary:          sendAs[i].IsPrimary,
+			IsDefault:          sendAs[i].IsDefault,
+			TreatAsAlias:       sendAs[i].TreatAsAlias,
+			VerificationStatus: sendAs[i].VerificationStatus,
+			CanSend:            sendAs[i].CanSend(),
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package responses
+
+import (
+	"api-mail/main/src/models"
+	"time"
+)
+
+// PgpKey struct for the OpenPGP private key response, without the key itself.
+type PgpKey struct {
+	ID          uint      `json:"id"`
+	AppMailID   uint      `json:"appMailId"`
+	App         string    `json:"app"`
+	Mail        string    `json:"mail"`
+	Fingerprint string    `json:"fingerprint"`
+	UserID      string    `json:"userId"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// SetPgpKey sets the OpenPGP private key response.
+func (response *PgpKey) SetPgpKey(pgpKey *models.PgpKey) {
+	response.ID = pgpKey.ID
+	response.AppMailID = pgpKey.AppMailID
+	response.App = pgpKey.AppMail.AppName
+	response.Mail = pgpKey.AppMail.MailName
+	response.Fingerprint = pgpKey.Fingerprint
+	response.UserID = pgpKey.UserID
+	response.CreatedAt = pgpKey.CreatedAt
+	response.UpdatedAt = pgpKey.UpdatedAt
+}
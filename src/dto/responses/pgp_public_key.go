@@ -0,0 +1,30 @@
+package responses
+
+import (
+	"api-mail/main/src/models"
+	"time"
+)
+
+// PgpPublicKey struct for the OpenPGP public key response.
+type PgpPublicKey struct {
+	ID          uint      `json:"id"`
+	App         string    `json:"app"`
+	Mail        string    `json:"mail"`
+	PublicKey   string    `json:"publicKey"`
+	Fingerprint string    `json:"fingerprint"`
+	UserID      string    `json:"userId"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// SetPgpPublicKey sets the OpenPGP public key response.
+func (response *PgpPublicKey) SetPgpPublicKey(publicKey *models.PgpPublicKey) {
+	response.ID = publicKey.ID
+	response.App = publicKey.AppName
+	response.Mail = publicKey.Mail
+	response.PublicKey = publicKey.PublicKey
+	response.Fingerprint = publicKey.Fingerprint
+	response.UserID = publicKey.UserID
+	response.CreatedAt = publicKey.CreatedAt
+	response.UpdatedAt = publicKey.UpdatedAt
+}
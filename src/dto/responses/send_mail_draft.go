@@ -0,0 +1,17 @@
+package responses
+
+import "api-mail/main/src/models"
+
+// SendMailDraft struct for the response of a created draft.
+type SendMailDraft struct {
+	ID      uint   `json:"id"`
+	Type    string `json:"type"`
+	DraftID string `json:"draftId"`
+}
+
+// SetSendMailDraft sets the draft response.
+func (response *SendMailDraft) SetSendMailDraft(sendMail *models.SendMail) {
+	response.ID = sendMail.ID
+	response.Type = sendMail.DraftType.String
+	response.DraftID = sendMail.DraftID.String
+}
@@ -0,0 +1,32 @@
+package responses
+
+import (
+	"api-mail/main/src/models"
+	"time"
+)
+
+// Smime struct for the S/MIME response.
+type Smime struct {
+	ID          uint      `json:"id"`
+	AppMailID   uint      `json:"appMailId"`
+	App         string    `json:"app"`
+	Mail        string    `json:"mail"`
+	Certificate string    `json:"certificate"`
+	Subject     string    `json:"subject"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// SetSmime sets the S/MIME response.
+func (response *Smime) SetSmime(smime *models.Smime) {
+	response.ID = smime.ID
+	response.AppMailID = smime.AppMailID
+	response.App = smime.AppMail.AppName
+	response.Mail = smime.AppMail.MailName
+	response.Certificate = smime.Certificate
+	response.Subject = smime.Subject
+	response.ExpiresAt = smime.ExpiresAt
+	response.CreatedAt = smime.CreatedAt
+	response.UpdatedAt = smime.UpdatedAt
+}
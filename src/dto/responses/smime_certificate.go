@@ -0,0 +1,30 @@
+package responses
+
+import (
+	"api-mail/main/src/models"
+	"time"
+)
+
+// SmimeCertificate struct for the S/MIME recipient certificate response.
+type SmimeCertificate struct {
+	ID          uint      `json:"id"`
+	App         string    `json:"app"`
+	Mail        string    `json:"mail"`
+	Certificate string    `json:"certificate"`
+	Subject     string    `json:"subject"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// SetSmimeCertificate sets the S/MIME recipient certificate response.
+func (response *SmimeCertificate) SetSmimeCertificate(certificate *models.SmimeCertificate) {
+	response.ID = certificate.ID
+	response.App = certificate.AppName
+	response.Mail = certificate.Mail
+	response.Certificate = certificate.Certificate
+	response.Subject = certificate.Subject
+	response.ExpiresAt = certificate.ExpiresAt
+	response.CreatedAt = certificate.CreatedAt
+	response.UpdatedAt = certificate.UpdatedAt
+}
@@ -3,6 +3,7 @@ package responses
 import (
 	"api-mail/main/src/enums"
 	"api-mail/main/src/models"
+	"strings"
 	"time"
 )
 
@@ -15,9 +16,25 @@ type Smtp struct {
 	Username             string    `json:"username"`
 	Host                 string    `json:"host"`
 	Port                 int       `json:"port"`
+	Encryption           string    `json:"encryption"`
+	Auth                 string    `json:"auth"`
+	TlsServerName        *string   `json:"tlsServerName"`
+	CaBundle             *string   `json:"caBundle"`
+	ConnectTimeout       *int      `json:"connectTimeout"`
+	SendTimeout          *int      `json:"sendTimeout"`
+	HeloName             *string   `json:"heloName"`
+	KeepAlive            *bool     `json:"keepAlive"`
+	MaxConnections       *int      `json:"maxConnections"`
+	IdleTimeout          *int      `json:"idleTimeout"`
+	ImapHost             *string   `json:"imapHost"`
+	ImapPort             *int      `json:"imapPort"`
+	SentFolder           *string   `json:"sentFolder"`
+	DraftsFolder         *string   `json:"draftsFolder"`
 	DkimPrivateKey       *string   `json:"dkimPrivateKey"`
 	DkimDomain           *string   `json:"dkimDomain"`
 	DkimCanonicalization *string   `json:"dkimCanonicalization"`
+	DkimSelector         *string   `json:"dkimSelector"`
+	DkimHeaders          []string  `json:"dkimHeaders"`
 	Primary              bool      `json:"primary"`
 	CreatedAt            time.Time `json:"createdAt"`
 	UpdatedAt            time.Time `json:"updatedAt"`
@@ -32,9 +49,27 @@ func (response *Smtp) SetSmtp(smtp *models.Smtp) {
 	response.Username = smtp.Username
 	response.Host = smtp.Host
 	response.Port = smtp.Port
+	response.Encryption = string(smtp.Encryption())
+	response.Auth = string(smtp.Auth())
+	response.TlsServerName = smtp.TlsServerName
+	response.CaBundle = smtp.CaBundle
+	response.ConnectTimeout = smtp.ConnectTimeout
+	response.SendTimeout = smtp.SendTimeout
+	response.HeloName = smtp.HeloName
+	response.KeepAlive = smtp.KeepAlive
+	response.MaxConnections = smtp.MaxConnections
+	response.IdleTimeout = smtp.IdleTimeout
+	response.ImapHost = smtp.ImapHost
+	response.ImapPort = smtp.ImapPort
+	response.SentFolder = smtp.SentFolder
+	response.DraftsFolder = smtp.DraftsFolder
 	response.DkimPrivateKey = smtp.DkimPrivateKey
 	response.DkimDomain = smtp.DkimDomain
 	response.DkimCanonicalization = smtp.DkimCanonicalizationName
+	response.DkimSelector = smtp.DkimSelector
+	if smtp.DkimHeaders != nil {
+		response.DkimHeaders = strings.Split(*smtp.DkimHeaders, ",")
+	}
 	response.CreatedAt = smtp.CreatedAt
 	response.UpdatedAt = smtp.UpdatedAt
 
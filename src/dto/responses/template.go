@@ -0,0 +1,32 @@
+package responses
+
+import (
+	"api-mail/main/src/models"
+	"time"
+)
+
+// Template struct for the template response, with the version that is sent.
+type Template struct {
+	ID            uint             `json:"id"`
+	App           string           `json:"app"`
+	Name          string           `json:"name"`
+	Description   *string          `json:"description"`
+	ActiveVersion *TemplateVersion `json:"activeVersion"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	UpdatedAt     time.Time        `json:"updatedAt"`
+}
+
+// SetTemplate sets the template response, the active version is nil when it is not loaded.
+func (response *Template) SetTemplate(template *models.Template, activeVersion *models.TemplateVersion) {
+	response.ID = template.ID
+	response.App = template.AppName
+	response.Name = template.Name
+	response.Description = template.Description
+	response.CreatedAt = template.CreatedAt
+	response.UpdatedAt = template.UpdatedAt
+
+	if activeVersion != nil && activeVersion.ID != 0 {
+		response.ActiveVersion = &TemplateVersion{}
+		response.ActiveVersion.SetTemplateVersion(activeVersion)
+	}
+}
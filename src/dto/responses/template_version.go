@@ -0,0 +1,28 @@
+package responses
+
+import (
+	"api-mail/main/src/models"
+	"time"
+)
+
+// TemplateVersion struct for the template version response.
+type TemplateVersion struct {
+	ID         uint      `json:"id"`
+	TemplateID uint      `json:"templateId"`
+	Version    int       `json:"version"`
+	Subject    string    `json:"subject"`
+	Html       *string   `json:"html"`
+	Text       *string   `json:"text"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// SetTemplateVersion sets the template version response.
+func (response *TemplateVersion) SetTemplateVersion(version *models.TemplateVersion) {
+	response.ID = version.ID
+	response.TemplateID = version.TemplateID
+	response.Version = version.Version
+	response.Subject = version.Subject
+	response.Html = version.Html
+	response.Text = version.Text
+	response.CreatedAt = version.CreatedAt
+}
@@ -0,0 +1,25 @@
+package enums
+
+// AzureCloud is an enum that contains the Microsoft clouds Global, USGovernment (GCC High) and China (21Vianet).
+type AzureCloud string
+
+const (
+	CloudGlobal       AzureCloud = "Global"
+	CloudUSGovernment AzureCloud = "USGovernment"
+	CloudChina        AzureCloud = "China"
+)
+
+// ToAzureCloud converts a string to an AzureCloud enum.
+// Default is Global on failure.
+func ToAzureCloud(s string) AzureCloud {
+	switch s {
+	case string(CloudGlobal):
+		return CloudGlobal
+	case string(CloudUSGovernment):
+		return CloudUSGovernment
+	case string(CloudChina):
+		return CloudChina
+	default:
+		return CloudGlobal
+	}
+}
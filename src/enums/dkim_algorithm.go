@@ -0,0 +1,22 @@
+package enums
+
+// DkimAlgorithm is an enum that contains RSA and Ed25519 for the Dkim keys.
+type DkimAlgorithm string
+
+const (
+	RSA     DkimAlgorithm = "RSA"
+	Ed25519 DkimAlgorithm = "Ed25519"
+)
+
+// ToDkimAlgorithm converts a string to a DkimAlgorithm enum.
+// Default is RSA on failure.
+func ToDkimAlgorithm(s string) DkimAlgorithm {
+	switch s {
+	case string(RSA):
+		return RSA
+	case string(Ed25519):
+		return Ed25519
+	default:
+		return RSA
+	}
+}
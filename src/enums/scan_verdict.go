@@ -0,0 +1,9 @@
+package enums
+
+// ScanVerdict is an enum that contains Clean and Infected for the malware scan of an attachment.
+type ScanVerdict string
+
+const (
+	Clean    ScanVerdict = "Clean"
+	Infected ScanVerdict = "Infected"
+)
@@ -0,0 +1,9 @@
+package enums
+
+// SendMode is an enum that contains send and draft for the mode of a send request.
+type SendMode string
+
+const (
+	ModeSend  SendMode = "send"
+	ModeDraft SendMode = "draft"
+)
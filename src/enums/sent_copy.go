@@ -0,0 +1,9 @@
+package enums
+
+// SentCopy is an enum that describes if a provider keeps a copy of a sent mail in the Sent folder of the mailbox.
+type SentCopy string
+
+const (
+	SentCopyAlways   SentCopy = "Always"
+	SentCopyOptional SentCopy = "Optional"
+)
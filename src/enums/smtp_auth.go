@@ -0,0 +1,31 @@
+package enums
+
+// SmtpAuth is an enum that contains the SASL mechanisms for the SMTP authentication.
+type SmtpAuth string
+
+const (
+	AuthNone    SmtpAuth = "None"
+	AuthPlain   SmtpAuth = "PLAIN"
+	AuthLogin   SmtpAuth = "LOGIN"
+	AuthCramMD5 SmtpAuth = "CRAM-MD5"
+	AuthXOAuth2 SmtpAuth = "XOAUTH2"
+)
+
+// ToSmtpAuth converts a string to a SmtpAuth enum.
+// Default is LOGIN on failure.
+func ToSmtpAuth(s string) SmtpAuth {
+	switch s {
+	case string(AuthNone):
+		return AuthNone
+	case string(AuthPlain):
+		return AuthPlain
+	case string(AuthLogin):
+		return AuthLogin
+	case string(AuthCramMD5):
+		return AuthCramMD5
+	case string(AuthXOAuth2):
+		return AuthXOAuth2
+	default:
+		return AuthLogin
+	}
+}
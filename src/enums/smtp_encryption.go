@@ -0,0 +1,25 @@
+package enums
+
+// SmtpEncryption is an enum that contains None, SSL/TLS and STARTTLS for the SMTP transport security.
+type SmtpEncryption string
+
+const (
+	EncryptionNone     SmtpEncryption = "None"
+	EncryptionSSLTLS   SmtpEncryption = "SSL/TLS"
+	EncryptionSTARTTLS SmtpEncryption = "STARTTLS"
+)
+
+// ToSmtpEncryption converts a string to a SmtpEncryption enum.
+// Default is STARTTLS on failure.
+func ToSmtpEncryption(s string) SmtpEncryption {
+	switch s {
+	case string(EncryptionNone):
+		return EncryptionNone
+	case string(EncryptionSSLTLS):
+		return EncryptionSSLTLS
+	case string(EncryptionSTARTTLS):
+		return EncryptionSTARTTLS
+	default:
+		return EncryptionSTARTTLS
+	}
+}
@@ -0,0 +1,10 @@
+package enums
+
+// VerificationStatus is an enum that contains Pass, Warning and Fail for the DNS checks of a domain.
+type VerificationStatus string
+
+const (
+	Pass    VerificationStatus = "Pass"
+	Warning VerificationStatus = "Warning"
+	Fail    VerificationStatus = "Fail"
+)
@@ -2,16 +2,65 @@ package errors
 
 // Define error codes as constants.
 const (
-	AppExists      = "appExists"
-	MailExists     = "mailExists"
-	MailTypeExists = "mailTypeExists"
-	SmtpAvailable  = "smtpAvailable"
-	SmtpExists     = "smtpExists"
-	GmailAvailable = "gmailAvailable"
-	GmailExists    = "gmailExists"
-	SendMail       = "sendMail"
-	OauthExchange  = "oauthExchange"
-	AzureAvailable = "azureAvailable"
-	AzureExists    = "azureExists"
+	AppExists                 = "appExists"
+	MailExists                = "mailExists"
+	MailTypeExists            = "mailTypeExists"
+	SmtpAvailable             = "smtpAvailable"
+	SmtpExists                = "smtpExists"
+	GmailAvailable            = "gmailAvailable"
+	GmailExists               = "gmailExists"
+	SendMail                  = "sendMail"
+	OauthExchange             = "oauthExchange"
+	AzureAvailable            = "azureAvailable"
+	AzureExists               = "azureExists"
+	RawMailParse              = "rawMailParse"
+	CalendarEvent             = "calendarEvent"
+	SmimeAvailable            = "smimeAvailable"
+	SmimeExists               = "smimeExists"
+	SmimeInvalid              = "smimeInvalid"
+	SmimeUnsupported          = "smimeUnsupported"
+	SmimeCertificateAvailable = "smimeCertificateAvailable"
+	SmimeCertificateExists    = "smimeCertificateExists"
+	PgpKeyAvailable           = "pgpKeyAvailable"
+	PgpKeyExists              = "pgpKeyExists"
+	PgpKeyInvalid             = "pgpKeyInvalid"
+	PgpUnsupported            = "pgpUnsupported"
+	PgpPublicKeyAvailable     = "pgpPublicKeyAvailable"
+	PgpPublicKeyExists        = "pgpPublicKeyExists"
+	MailProtection            = "mailProtection"
+	DkimKeyAvailable          = "dkimKeyAvailable"
+	DkimKeyExists             = "dkimKeyExists"
+	DkimHeaders               = "dkimHeaders"
+	DkimDomain                = "dkimDomain"
+	DomainVerification        = "domainVerification"
+	SmtpCaBundle              = "smtpCaBundle"
+	OauthIdentity             = "oauthIdentity"
+	OauthAccountMismatch      = "oauthAccountMismatch"
+	OauthRevoke               = "oauthRevoke"
+	GmailSendAs               = "gmailSendAs"
+	AppMailExists             = "appMailExists"
+	SendMailExists            = "sendMailExists"
+	Draft                     = "draft"
+	BodyLimit                 = "bodyLimit"
+	AttachmentSize            = "attachmentSize"
+	AttachmentSizeMismatch    = "attachmentSizeMismatch"
+	AttachmentType            = "attachmentType"
+	AttachmentTypeMismatch    = "attachmentTypeMismatch"
+	AttachmentExtension       = "attachmentExtension"
+	AttachmentPolicyExists    = "attachmentPolicyExists"
+	AttachmentExtensionDenied = "attachmentExtensionDenied"
+	AttachmentCount           = "attachmentCount"
+	AttachmentScan            = "attachmentScan"
+	AttachmentInfected        = "attachmentInfected"
+	AttachmentExists          = "attachmentExists"
+	AttachmentExpired         = "attachmentExpired"
+	AttachmentLink            = "attachmentLink"
+	AttachmentLinkExists      = "attachmentLinkExists"
+	AttachmentLinkExpired     = "attachmentLinkExpired"
+	TemplateAvailable         = "templateAvailable"
+	TemplateExists            = "templateExists"
+	TemplateVersionExists     = "templateVersionExists"
+	TemplateParse             = "templateParse"
+	TemplateRender            = "templateRender"
 	// Add more error codes as needed.
 )
@@ -0,0 +1,48 @@
+package jobs
+
+import (
+	"api-mail/main/src/services"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// StartAttachmentExpiryJob periodically deletes the expired attachments of the attachment library and the expired download links.
+// The interval is read from ATTACHMENT_EXPIRY_INTERVAL, the job is disabled when it is empty.
+func StartAttachmentExpiryJob(ctx context.Context) error {
+	interval := os.Getenv("ATTACHMENT_EXPIRY_INTERVAL")
+	if interval == "" {
+		return nil
+	}
+
+	duration, err := time.ParseDuration(interval)
+	if err != nil {
+		return err
+	} else if duration <= 0 {
+		return fmt.Errorf("ATTACHMENT_EXPIRY_INTERVAL must be positive, got %s", interval)
+	}
+
+	go func() {
+		ticker := time.NewTicker(duration)
+		defer ticker.Stop()
+
+		for {
+			if _, err := services.DeleteExpiredAttachments(ctx); err != nil {
+				log.Printf("attachment expiry: %s", err.Error())
+			}
+			if _, err := services.DeleteExpiredAttachmentLinks(ctx); err != nil {
+				log.Printf("attachment link expiry: %s", err.Error())
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"api-mail/main/src/services"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// StartDomainVerificationJob periodically verifies the DNS records of all smtps with a DKIM domain.
+// The interval is read from DOMAIN_VERIFICATION_INTERVAL, the job is disabled when it is empty.
+func StartDomainVerificationJob(ctx context.Context) error {
+	interval := os.Getenv("DOMAIN_VERIFICATION_INTERVAL")
+	if interval == "" {
+		return nil
+	}
+
+	duration, err := time.ParseDuration(interval)
+	if err != nil {
+		return err
+	} else if duration <= 0 {
+		return fmt.Errorf("DOMAIN_VERIFICATION_INTERVAL must be positive, got %s", interval)
+	}
+
+	go func() {
+		ticker := time.NewTicker(duration)
+		defer ticker.Stop()
+
+		for {
+			verifyDomains(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil
+}
+
+// verifyDomains verifies the domain of each smtp, a failing smtp does not stop the others.
+func verifyDomains(ctx context.Context) {
+	smtpIDs, err := services.GetSmtpIDsWithDkimDomain()
+	if err != nil {
+		log.Printf("domain verification: %s", err.Error())
+		return
+	}
+
+	for _, smtpID := range smtpIDs {
+		smtp, err := services.GetSmtp(smtpID)
+		if err != nil {
+			log.Printf("domain verification of smtp %d: %s", smtpID, err.Error())
+			continue
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		if _, err := services.VerifySmtpDomain(checkCtx, smtp); err != nil {
+			log.Printf("domain verification of smtp %d: %s", smtpID, err.Error())
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"api-mail/main/src/errors"
+	"fmt"
+	errorutil "github.com/ArnoldPMolenaar/api-utils/errors"
+	"github.com/gofiber/fiber/v2"
+	"io"
+)
+
+// BodyLimit rejects a request body larger than the limit with 413.
+// The body is streamed, so Fiber only limits the part it reads ahead and a larger body must be checked here.
+// Requests for which next returns true read the body stream with their own limits and are skipped.
+func BodyLimit(limit int, next func(c *fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if next != nil && next(c) {
+			return c.Next()
+		}
+
+		request := c.Request()
+		if length := request.Header.ContentLength(); length > limit {
+			// The rest of the body is not read, so the connection can't be reused.
+			c.Context().SetConnectionClose()
+			return errorutil.Response(c, fiber.StatusRequestEntityTooLarge, errors.BodyLimit, fmt.Sprintf("The request body is larger than %d bytes.", limit))
+		} else if length >= 0 || !request.IsBodyStream() {
+			return c.Next()
+		}
+
+		// A chunked body has no length, it is read up to the limit.
+		body, err := io.ReadAll(io.LimitReader(request.BodyStream(), int64(limit)+1))
+		if err != nil {
+			return errorutil.Response(c, fiber.StatusBadRequest, errorutil.BodyParse, err.Error())
+		} else if len(body) > limit {
+			c.Context().SetConnectionClose()
+			return errorutil.Response(c, fiber.StatusRequestEntityTooLarge, errors.BodyLimit, fmt.Sprintf("The request body is larger than %d bytes.", limit))
+		}
+		request.SetBody(body)
+
+		return c.Next()
+	}
+}
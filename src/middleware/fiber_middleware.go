@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"api-mail/main/src/configs"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -31,5 +32,26 @@ func FiberMiddleware(a *fiber.App) {
 
 		// Catch a panic and return a 500 response.
 		recover.New(),
+
+		// Limit the request body, except for the multipart mails and uploads that stream their attachments.
+		BodyLimit(configs.BodyLimit(), func(c *fiber.Ctx) bool {
+			return c.Method() == fiber.MethodPost && isAttachmentStreamPath(c.Path()) &&
+				strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm)
+		}),
 	)
 }
+
+// isAttachmentStreamPath checks if the path is /v1/mail/send or the upload of the attachment library of an app.
+func isAttachmentStreamPath(path string) bool {
+	if path == "/v1/mail/send" {
+		return true
+	}
+
+	name, ok := strings.CutPrefix(path, "/v1/apps/")
+	if !ok {
+		return false
+	}
+	name, ok = strings.CutSuffix(name, "/attachments")
+
+	return ok && name != "" && !strings.Contains(name, "/")
+}
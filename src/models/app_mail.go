@@ -3,10 +3,11 @@ package models
 import "database/sql"
 
 type AppMail struct {
-	ID          uint   `gorm:"primaryKey"`
-	AppName     string `gorm:"not null;index:idx_app_mail,unique,priority:1"`
-	MailName    string `gorm:"not null;index:idx_app_mail,unique,priority:2"`
-	PrimaryType sql.NullString
+	ID           uint   `gorm:"primaryKey"`
+	AppName      string `gorm:"not null;index:idx_app_mail,unique,priority:1"`
+	MailName     string `gorm:"not null;index:idx_app_mail,unique,priority:2"`
+	PrimaryType  sql.NullString
+	SaveSentCopy bool `gorm:"not null;default:false"`
 
 	// Relationships.
 	App   App                 `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppName;references:Name"`
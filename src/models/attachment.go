@@ -0,0 +1,28 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Attachment is a file of the attachment library of an app, that send-mails reference by ID.
+// The content is in the blob store under FileHash, an expired attachment can't be sent anymore.
+type Attachment struct {
+	ID        uint   `gorm:"primaryKey"`
+	AppName   string `gorm:"not null;index"`
+	FileName  string `gorm:"not null"`
+	FileType  string `gorm:"not null"`
+	FileSize  int64  `gorm:"not null"`
+	FileHash  string `gorm:"not null;index"`
+	ExpiresAt sql.NullTime
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Relationships.
+	App App `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppName;references:Name"`
+}
+
+// IsExpired checks if the expiry of the attachment has passed.
+func (a *Attachment) IsExpired() bool {
+	return a.ExpiresAt.Valid && !a.ExpiresAt.Time.After(time.Now())
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// AttachmentLink is a large attachment of a mail that is sent as download link instead of in the message.
+// The content is in the blob store under FileHash, the link can't be downloaded after ExpiresAt.
+type AttachmentLink struct {
+	ID               uint      `gorm:"primaryKey"`
+	AppName          string    `gorm:"not null;index"`
+	SendMailID       *uint     `gorm:"index"`
+	FileName         string    `gorm:"not null"`
+	FileType         string    `gorm:"not null"`
+	FileSize         int64     `gorm:"not null"`
+	FileHash         string    `gorm:"not null;index"`
+	ExpiresAt        time.Time `gorm:"not null;index"`
+	Downloads        int       `gorm:"not null;default:0"`
+	LastDownloadedAt sql.NullTime
+	CreatedAt        time.Time
+
+	// Relationships.
+	App      App       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppName;references:Name"`
+	SendMail *SendMail `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:SendMailID;references:ID"`
+}
+
+// IsExpired checks if the expiry of the link has passed.
+func (l *AttachmentLink) IsExpired() bool {
+	return !l.ExpiresAt.After(time.Now())
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AttachmentPolicy limits the attachments of the mails of an app.
+// The types and extensions are stored comma separated, a nil limit is not checked.
+type AttachmentPolicy struct {
+	ID                uint   `gorm:"primaryKey"`
+	AppName           string `gorm:"not null;uniqueIndex"`
+	AllowedTypes      *string
+	BlockedTypes      *string
+	AllowedExtensions *string
+	BlockedExtensions *string
+	MaxFileSize       *int64
+	MaxTotalSize      *int64
+	MaxCount          *int
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+
+	// Relationships.
+	App App `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppName;references:Name"`
+}
+
+// AllowsType checks if the MIME type is allowed and not blocked, a type ending in /* matches the whole group.
+func (p *AttachmentPolicy) AllowsType(fileType string) bool {
+	fileType = strings.ToLower(fileType)
+	match := func(pattern string) bool {
+		if group, ok := strings.CutSuffix(pattern, "/*"); ok {
+			return strings.HasPrefix(fileType, group+"/")
+		}
+
+		return pattern == fileType
+	}
+
+	return !anyOf(p.BlockedTypes, match) && (p.AllowedTypes == nil || anyOf(p.AllowedTypes, match))
+}
+
+// AllowsExtension checks if the extension of the file name is allowed and not blocked.
+func (p *AttachmentPolicy) AllowsExtension(fileName string) bool {
+	extension := strings.ToLower(filepath.Ext(fileName))
+	match := func(pattern string) bool {
+		return pattern == extension
+	}
+
+	return !anyOf(p.BlockedExtensions, match) && (p.AllowedExtensions == nil || anyOf(p.AllowedExtensions, match))
+}
+
+// anyOf checks if an item of the comma separated list matches.
+func anyOf(list *string, match func(string) bool) bool {
+	if list == nil {
+		return false
+	}
+
+	for _, item := range strings.Split(*list, ",") {
+		if match(item) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,23 +1,39 @@
 package models
 
 import (
+	"api-mail/main/src/enums"
 	"database/sql"
 	"gorm.io/gorm"
 )
 
 type Azure struct {
 	gorm.Model
-	AppMailID    uint   `gorm:"not null"`
-	ClientID     string `gorm:"primaryKey:true;not null;autoIncrement:false"`
-	TenantID     string `gorm:"not null"`
-	Secret       string `gorm:"not null"`
-	AccessToken  sql.NullString
-	RefreshToken sql.NullString
-	TokenType    sql.NullString
-	Expiry       sql.NullTime
-	ExpiresIn    sql.NullInt64
-	User         string `gorm:"not null"`
+	AppMailID      uint   `gorm:"not null"`
+	ClientID       string `gorm:"primaryKey:true;not null;autoIncrement:false"`
+	TenantID       string `gorm:"not null"`
+	Secret         string `gorm:"not null"`
+	AccessToken    sql.NullString
+	RefreshToken   sql.NullString
+	TokenType      sql.NullString
+	Expiry         sql.NullTime
+	ExpiresIn      sql.NullInt64
+	User           string `gorm:"not null"`
+	SmtpDelivery   bool   `gorm:"not null;default:false"`
+	VerifiedMail   sql.NullString
+	AzureCloudName *string
+	AuthorityURL   *string
+	GraphURL       *string
 
 	// Relationships.
-	AppMail AppMail `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppMailID;references:ID"`
+	AppMail    AppMail     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppMailID;references:ID"`
+	AzureCloud *AzureCloud `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AzureCloudName;references:Name"`
+}
+
+// Cloud returns the Microsoft cloud of the azure, Global when none is set.
+func (a *Azure) Cloud() enums.AzureCloud {
+	if a.AzureCloudName != nil && *a.AzureCloudName != "" {
+		return enums.ToAzureCloud(*a.AzureCloudName)
+	}
+
+	return enums.CloudGlobal
 }
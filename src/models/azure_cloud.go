@@ -0,0 +1,5 @@
+package models
+
+type AzureCloud struct {
+	Name string `gorm:"primaryKey:true;not null;autoIncrement:false"`
+}
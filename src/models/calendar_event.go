@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarEvent is the last sent state of a calendar invitation, used to update and cancel it by UID.
+type CalendarEvent struct {
+	ID        uint      `gorm:"primarykey"`
+	AppMailID uint      `gorm:"not null;index:idx_calendar_event,unique,priority:1"`
+	UID       string    `gorm:"not null;index:idx_calendar_event,unique,priority:2"`
+	Sequence  int       `gorm:"not null"`
+	Method    string    `gorm:"not null"`
+	Event     []byte    `gorm:"type:jsonb;not null"`
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+
+	// Relationships.
+	AppMail AppMail `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppMailID;references:ID"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// ConnectionCheck is the result of a connection and credential test of a smtp, gmail or azure, it is not stored.
+type ConnectionCheck struct {
+	Steps []ConnectionCheckStep
+}
+
+// ConnectionCheckStep is a single step of a connection check with the exact responses of the server.
+type ConnectionCheckStep struct {
+	Name     string
+	Success  bool
+	Duration time.Duration
+	Response string
+	Error    string
+}
+
+// Success checks if all steps of the connection check succeeded.
+func (c *ConnectionCheck) Success() bool {
+	for i := range c.Steps {
+		if !c.Steps[i].Success {
+			return false
+		}
+	}
+
+	return len(c.Steps) > 0
+}
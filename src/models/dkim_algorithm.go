@@ -0,0 +1,5 @@
+package models
+
+type DkimAlgorithm struct {
+	Name string `gorm:"primaryKey:true;not null;autoIncrement:false"`
+}
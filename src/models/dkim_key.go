@@ -0,0 +1,73 @@
+package models
+
+import (
+	"api-mail/main/src/enums"
+	"fmt"
+	"github.com/ArnoldPMolenaar/api-utils/utils"
+	"gorm.io/gorm"
+	"os"
+)
+
+// DkimKey is a generated DKIM key of a domain, the active key of the domain signs the mail.
+type DkimKey struct {
+	gorm.Model
+	AppName           string `gorm:"not null;index:idx_dkim_key_domain"`
+	Domain            string `gorm:"not null;index:idx_dkim_key_domain"`
+	Selector          string `gorm:"not null"`
+	DkimAlgorithmName string `gorm:"not null"`
+	PrivateKey        string `gorm:"not null"`
+	PublicKey         string `gorm:"not null"`
+	Active            bool   `gorm:"not null;default:false"`
+
+	// Relationships.
+	App           App           `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppName;references:Name"`
+	DkimAlgorithm DkimAlgorithm `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:DkimAlgorithmName;references:Name"`
+}
+
+// EncryptPrivateKey encrypts the DKIM private key.
+func (d *DkimKey) EncryptPrivateKey() error {
+	key := os.Getenv("PASSWORD_ENCRYPTION_KEY")
+	encryptedPrivateKey, err := utils.Encrypt(key, d.PrivateKey)
+
+	if err != nil {
+		return err
+	}
+
+	d.PrivateKey = encryptedPrivateKey
+
+	return nil
+}
+
+// DecryptPrivateKey decrypts the DKIM private key.
+func (d *DkimKey) DecryptPrivateKey() (string, error) {
+	key := os.Getenv("PASSWORD_ENCRYPTION_KEY")
+
+	return utils.Decrypt(key, d.PrivateKey)
+}
+
+// RecordName returns the DNS name of the TXT record of the key.
+func (d *DkimKey) RecordName() string {
+	return fmt.Sprintf("%s._domainkey.%s", d.Selector, d.Domain)
+}
+
+// RecordValue returns the value of the TXT record of the key.
+func (d *DkimKey) RecordValue() string {
+	keyType := "rsa"
+	if enums.ToDkimAlgorithm(d.DkimAlgorithmName) == enums.Ed25519 {
+		keyType = "ed25519"
+	}
+
+	return fmt.Sprintf("v=DKIM1; k=%s; p=%s", keyType, d.PublicKey)
+}
+
+// RecordStrings returns the value of the TXT record in strings of at most 255 characters, as DNS requires.
+func (d *DkimKey) RecordStrings() []string {
+	value := d.RecordValue()
+	strings := make([]string, 0, len(value)/255+1)
+	for len(value) > 255 {
+		strings = append(strings, value[:255])
+		value = value[255:]
+	}
+
+	return append(strings, value)
+}
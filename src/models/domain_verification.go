@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// DomainVerification is the last result of the SPF, DKIM and DMARC checks of a smtp.
+type DomainVerification struct {
+	ID             uint      `gorm:"primaryKey"`
+	SmtpID         uint      `gorm:"not null;uniqueIndex"`
+	FromDomain     string    `gorm:"not null"`
+	DkimDomain     string    `gorm:"not null"`
+	DkimSelector   string    `gorm:"not null"`
+	DkimStatus     string    `gorm:"not null"`
+	DkimDiagnosis  string    `gorm:"not null"`
+	SpfStatus      string    `gorm:"not null"`
+	SpfDiagnosis   string    `gorm:"not null"`
+	DmarcStatus    string    `gorm:"not null"`
+	DmarcDiagnosis string    `gorm:"not null"`
+	Valid          bool      `gorm:"not null"`
+	CheckedAt      time.Time `gorm:"not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+
+	// Relationships.
+	Smtp Smtp `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:SmtpID;references:ID"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// GmailSendAs is a send-as alias of the Google account of a gmail, fetched from the Gmail settings.
+type GmailSendAs struct {
+	ID                 uint   `gorm:"primaryKey"`
+	GmailID            uint   `gorm:"not null;index"`
+	SendAsEmail        string `gorm:"not null"`
+	DisplayName        string
+	ReplyToAddress     string
+	IsPrimary          bool   `gorm:"not null;default:false"`
+	IsDefault          bool   `gorm:"not null;default:false"`
+	TreatAsAlias       bool   `gorm:"not null;default:false"`
+	VerificationStatus string `gorm:"not null"`
+	CreatedAt          time.Time
+
+	// Relationships.
+	Gmail Gmail `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:GmailID;references:ID"`
+}
+
+// CanSend checks if the alias can be used as From address, Gmail rewrites the From of an unverified alias.
+func (s *GmailSendAs) CanSend() bool {
+	return s.IsPrimary || s.VerificationStatus == "accepted"
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"github.com/ArnoldPMolenaar/api-utils/utils"
+	"gorm.io/gorm"
+	"os"
+)
+
+// PgpKey is the OpenPGP private key of an app mail, used to sign outgoing mail.
+type PgpKey struct {
+	gorm.Model
+	AppMailID   uint   `gorm:"not null"`
+	PrivateKey  string `gorm:"not null"`
+	Passphrase  string
+	Fingerprint string `gorm:"not null"`
+	UserID      string
+
+	// Relationships.
+	AppMail AppMail `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppMailID;references:ID"`
+}
+
+// EncryptPrivateKey encrypts the private key and the passphrase.
+func (p *PgpKey) EncryptPrivateKey() error {
+	key := os.Getenv("PASSWORD_ENCRYPTION_KEY")
+	encryptedPrivateKey, err := utils.Encrypt(key, p.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	p.PrivateKey = encryptedPrivateKey
+
+	if p.Passphrase != "" {
+		encryptedPassphrase, err := utils.Encrypt(key, p.Passphrase)
+		if err != nil {
+			return err
+		}
+
+		p.Passphrase = encryptedPassphrase
+	}
+
+	return nil
+}
+
+// DecryptPrivateKey decrypts the private key and the passphrase.
+func (p *PgpKey) DecryptPrivateKey() (string, string, error) {
+	key := os.Getenv("PASSWORD_ENCRYPTION_KEY")
+
+	privateKey, err := utils.Decrypt(key, p.PrivateKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	if p.Passphrase == "" {
+		return privateKey, "", nil
+	}
+
+	passphrase, err := utils.Decrypt(key, p.Passphrase)
+	if err != nil {
+		return "", "", err
+	}
+
+	return privateKey, passphrase, nil
+}
@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// PgpPublicKey is the OpenPGP public key of a recipient, used to encrypt mail to the recipient.
+type PgpPublicKey struct {
+	gorm.Model
+	AppName     string `gorm:"not null;index"`
+	Mail        string `gorm:"not null;index"`
+	PublicKey   string `gorm:"not null"`
+	Fingerprint string `gorm:"not null"`
+	UserID      string
+
+	// Relationships.
+	App App `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppName;references:Name"`
+}
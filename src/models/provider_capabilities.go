@@ -0,0 +1,13 @@
+package models
+
+import "api-mail/main/src/enums"
+
+// ProviderCapabilities are the features of a configured provider of an app mail, they are not stored.
+type ProviderCapabilities struct {
+	Type           enums.AppMailPrimaryType
+	SentCopy       enums.SentCopy
+	SavesSentCopy  bool
+	Smime          bool
+	Pgp            bool
+	CalendarEvents bool
+}
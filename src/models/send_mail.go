@@ -1,6 +1,7 @@
 package models
 
 import (
+	"database/sql"
 	"time"
 )
 
@@ -15,11 +16,19 @@ type SendMail struct {
 	Body        string    `gorm:"not null"`
 	MimeType    string    `gorm:"not null"`
 	CreatedAt   time.Time `gorm:"not null"`
+	DraftID     sql.NullString
+	DraftType   sql.NullString
+	DraftSentAt sql.NullTime
+
+	// The plain text alternative of an HTML body and the template version the mail is rendered from.
+	TextBody          sql.NullString
+	TemplateVersionID *uint
 
 	// Relationships.
-	AppMail     AppMail              `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppMailID;references:ID"`
-	Type        AppMailPrimaryType   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:PrimaryType;references:Name"`
-	Ccs         []SendMailCc         `gorm:"foreignKey:SendMailID"`
-	Bccs        []SendMailBcc        `gorm:"foreignKey:SendMailID"`
-	Attachments []SendMailAttachment `gorm:"foreignKey:SendMailID"`
+	AppMail         AppMail              `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppMailID;references:ID"`
+	Type            AppMailPrimaryType   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:PrimaryType;references:Name"`
+	Ccs             []SendMailCc         `gorm:"foreignKey:SendMailID"`
+	Bccs            []SendMailBcc        `gorm:"foreignKey:SendMailID"`
+	Attachments     []SendMailAttachment `gorm:"foreignKey:SendMailID"`
+	TemplateVersion *TemplateVersion     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:TemplateVersionID;references:ID"`
 }
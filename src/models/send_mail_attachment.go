@@ -1,18 +1,27 @@
 package models
 
 import (
+	"database/sql"
 	"time"
 )
 
+// SendMailAttachment is the metadata of an attachment of a send-mail.
+// The content is in the blob store under FileHash, the hex SHA-256 hash of the content.
 type SendMailAttachment struct {
 	ID         uint      `gorm:"primarykey"`
 	SendMailID uint      `gorm:"not null"`
 	FileName   string    `gorm:"not null"`
 	FileType   string    `gorm:"not null"`
 	FileSize   int64     `gorm:"not null"`
-	FileData   []byte    `gorm:"not null"`
+	FileHash   string    `gorm:"index"`
 	CreatedAt  time.Time `gorm:"not null"`
 
+	// The verdict of the malware scan, null when the attachment was not scanned.
+	ScanVerdict   sql.NullString
+	ScanSignature sql.NullString
+	ScanScanner   sql.NullString
+	ScannedAt     sql.NullTime
+
 	// Relationships.
 	SendMail SendMail `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:SendMailID;references:ID"`
 }
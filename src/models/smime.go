@@ -0,0 +1,42 @@
+package models
+
+import (
+	"github.com/ArnoldPMolenaar/api-utils/utils"
+	"gorm.io/gorm"
+	"os"
+	"time"
+)
+
+// Smime is the S/MIME certificate and private key of an app mail, used to sign outgoing mail.
+type Smime struct {
+	gorm.Model
+	AppMailID   uint      `gorm:"not null"`
+	Certificate string    `gorm:"not null"`
+	PrivateKey  string    `gorm:"not null"`
+	Subject     string    `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null"`
+
+	// Relationships.
+	AppMail AppMail `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppMailID;references:ID"`
+}
+
+// EncryptPrivateKey encrypts the S/MIME private key.
+func (s *Smime) EncryptPrivateKey() error {
+	key := os.Getenv("PASSWORD_ENCRYPTION_KEY")
+	encryptedPrivateKey, err := utils.Encrypt(key, s.PrivateKey)
+
+	if err != nil {
+		return err
+	}
+
+	s.PrivateKey = encryptedPrivateKey
+
+	return nil
+}
+
+// DecryptPrivateKey decrypts the S/MIME private key.
+func (s *Smime) DecryptPrivateKey() (string, error) {
+	key := os.Getenv("PASSWORD_ENCRYPTION_KEY")
+
+	return utils.Decrypt(key, s.PrivateKey)
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// SmimeCertificate is the S/MIME certificate of a recipient, used to encrypt mail to the recipient.
+type SmimeCertificate struct {
+	gorm.Model
+	AppName     string    `gorm:"not null;index"`
+	Mail        string    `gorm:"not null;index"`
+	Certificate string    `gorm:"not null"`
+	Subject     string    `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null"`
+
+	// Relationships.
+	App App `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppName;references:Name"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"api-mail/main/src/enums"
 	"github.com/ArnoldPMolenaar/api-utils/utils"
 	"gorm.io/gorm"
 	"os"
@@ -13,12 +14,30 @@ type Smtp struct {
 	Password                 string `gorm:"not null"`
 	Host                     string `gorm:"not null"`
 	Port                     int    `gorm:"not null"`
+	SmtpEncryptionName       *string
+	SmtpAuthName             *string
+	TlsServerName            *string
+	CaBundle                 *string
+	ConnectTimeout           *int
+	SendTimeout              *int
+	HeloName                 *string
+	KeepAlive                *bool
+	MaxConnections           *int
+	IdleTimeout              *int
+	ImapHost                 *string
+	ImapPort                 *int
+	SentFolder               *string
+	DraftsFolder             *string
 	DkimPrivateKey           *string
 	DkimDomain               *string
 	DkimCanonicalizationName *string
+	DkimSelector             *string
+	DkimHeaders              *string
 
 	// Relationships.
 	AppMail              AppMail               `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppMailID;references:ID"`
+	SmtpEncryption       *SmtpEncryption       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:SmtpEncryptionName;references:Name"`
+	SmtpAuth             *SmtpAuth             `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:SmtpAuthName;references:Name"`
 	DkimCanonicalization *DkimCanonicalization `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:DkimCanonicalizationName;references:Name"`
 }
 
@@ -42,3 +61,29 @@ func (s *Smtp) DecryptPassword() (string, error) {
 
 	return utils.Decrypt(key, s.Password)
 }
+
+// Encryption returns the transport security of the SMTP.
+// Records without an encryption mode keep the old behaviour that derives it from the port.
+func (s *Smtp) Encryption() enums.SmtpEncryption {
+	if s.SmtpEncryptionName != nil && *s.SmtpEncryptionName != "" {
+		return enums.ToSmtpEncryption(*s.SmtpEncryptionName)
+	}
+
+	switch s.Port {
+	case 465:
+		return enums.EncryptionSSLTLS
+	case 587:
+		return enums.EncryptionSTARTTLS
+	default:
+		return enums.EncryptionNone
+	}
+}
+
+// Auth returns the authentication mechanism of the SMTP, LOGIN when none is set.
+func (s *Smtp) Auth() enums.SmtpAuth {
+	if s.SmtpAuthName != nil && *s.SmtpAuthName != "" {
+		return enums.ToSmtpAuth(*s.SmtpAuthName)
+	}
+
+	return enums.AuthLogin
+}
@@ -0,0 +1,5 @@
+package models
+
+type SmtpEncryption struct {
+	Name string `gorm:"primaryKey:true;not null;autoIncrement:false"`
+}
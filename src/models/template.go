@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Template is a stored mail template of an app, the versions hold the content and ActiveVersionID is the version that is sent.
+// ActiveVersionID has no foreign key, as the versions reference the template and are only deleted with it.
+type Template struct {
+	ID              uint   `gorm:"primaryKey"`
+	AppName         string `gorm:"not null;uniqueIndex:idx_template_app_name"`
+	Name            string `gorm:"not null;uniqueIndex:idx_template_app_name"`
+	Description     *string
+	ActiveVersionID *uint
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+
+	// Relationships.
+	App App `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AppName;references:Name"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// TemplateVersion is an immutable version of a template, the subject and text are text templates and the HTML an HTML template.
+type TemplateVersion struct {
+	ID         uint   `gorm:"primaryKey"`
+	TemplateID uint   `gorm:"not null;uniqueIndex:idx_template_version"`
+	Version    int    `gorm:"not null;uniqueIndex:idx_template_version"`
+	Subject    string `gorm:"not null"`
+	Html       *string
+	Text       *string
+	CreatedAt  time.Time
+
+	// Relationships.
+	Template Template `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:TemplateID;references:ID"`
+}
@@ -14,9 +14,46 @@ func PrivateRoutes(a *fiber.App) {
 	// Register route for /v1/apps.
 	route.Post("/apps", middleware.MachineProtected(), controllers.CreateApp)
 
+	// Register routes for /v1/apps/:name/attachment-policy.
+	attachmentPolicy := route.Group("/apps/:name/attachment-policy", middleware.MachineProtected())
+	attachmentPolicy.Get("/", controllers.GetAttachmentPolicy)
+	attachmentPolicy.Put("/", controllers.UpdateAttachmentPolicy)
+	attachmentPolicy.Delete("/", controllers.DeleteAttachmentPolicy)
+
+	// Register routes for /v1/apps/:name/attachments.
+	attachments := route.Group("/apps/:name/attachments", middleware.MachineProtected())
+	attachments.Get("/", controllers.GetAttachments)
+	attachments.Post("/", controllers.CreateAttachment)
+	attachments.Get("/:id", controllers.GetAttachment)
+	attachments.Delete("/:id", controllers.DeleteAttachment)
+
+	// Register routes for /v1/apps/:name/templates.
+	templates := route.Group("/apps/:name/templates", middleware.MachineProtected())
+	templates.Get("/", controllers.GetTemplates)
+	templates.Post("/", controllers.CreateTemplate)
+	templates.Get("/:id", controllers.GetTemplate)
+	templates.Put("/:id", controllers.UpdateTemplate)
+	templates.Delete("/:id", controllers.DeleteTemplate)
+	templates.Get("/:id/versions", controllers.GetTemplateVersions)
+	templates.Post("/:id/versions", controllers.CreateTemplateVersion)
+	templates.Get("/:id/versions/:version", controllers.GetTemplateVersion)
+	templates.Put("/:id/versions/:version/activate", controllers.ActivateTemplateVersion)
+
+	// Register routes for /v1/apps/:name/mails/:mail.
+	appMails := route.Group("/apps/:name/mails/:mail", middleware.MachineProtected())
+	appMails.Get("/", controllers.GetAppMail)
+	appMails.Put("/", controllers.UpdateAppMail)
+	appMails.Get("/capabilities", controllers.GetAppMailCapabilities)
+
 	// Register route for POST /v1/mail/send.
 	route.Post("/mail/send", middleware.MachineProtected(), controllers.SendMail)
 
+	// Register route for POST /v1/mail/send/raw.
+	route.Post("/mail/send/raw", middleware.MachineProtected(), controllers.SendRawMail)
+
+	// Register route for POST /v1/mail/:id/send-draft.
+	route.Post("/mail/:id/send-draft", middleware.MachineProtected(), controllers.SendDraft)
+
 	// Register CRUD routes for /v1/smtps.
 	smtps := route.Group("/smtps", middleware.MachineProtected())
 	smtps.Get("/", controllers.GetSmtps)
@@ -25,6 +62,9 @@ func PrivateRoutes(a *fiber.App) {
 	smtps.Put("/:id", controllers.UpdateSmtp)
 	smtps.Delete("/:id", controllers.DeleteSmtp)
 	smtps.Put("/:id/restore", controllers.RestoreSmtp)
+	smtps.Post("/:id/verify-domain", controllers.VerifySmtpDomain)
+	smtps.Get("/:id/domain-verification", controllers.GetSmtpDomainVerification)
+	smtps.Post("/:id/test", controllers.TestSmtp)
 
 	// Register CRUD routes for /v1/gmails.
 	gmails := route.Group("/gmails", middleware.MachineProtected())
@@ -34,6 +74,10 @@ func PrivateRoutes(a *fiber.App) {
 	gmails.Put("/:id", controllers.UpdateGmail)
 	gmails.Delete("/:id", controllers.DeleteGmail)
 	gmails.Put("/:id/restore", controllers.RestoreGmail)
+	gmails.Post("/:id/test", controllers.TestGmail)
+	gmails.Post("/:id/reauthorize", controllers.ReauthorizeGmail)
+	gmails.Get("/:id/send-as", controllers.GetGmailSendAs)
+	gmails.Post("/:id/send-as/sync", controllers.SyncGmailSendAs)
 
 	// Register CRUD routes for /v1/azures.
 	azures := route.Group("/azures", middleware.MachineProtected())
@@ -43,4 +87,51 @@ func PrivateRoutes(a *fiber.App) {
 	azures.Put("/:id", controllers.UpdateAzure)
 	azures.Delete("/:id", controllers.DeleteAzure)
 	azures.Put("/:id/restore", controllers.RestoreAzure)
+	azures.Post("/:id/test", controllers.TestAzure)
+	azures.Post("/:id/reauthorize", controllers.ReauthorizeAzure)
+
+	// Register CRUD routes for /v1/smimes.
+	smimes := route.Group("/smimes", middleware.MachineProtected())
+	smimes.Get("/", controllers.GetSmimes)
+	smimes.Post("/", controllers.CreateSmime)
+	smimes.Get("/:id", controllers.GetSmime)
+	smimes.Put("/:id", controllers.UpdateSmime)
+	smimes.Delete("/:id", controllers.DeleteSmime)
+	smimes.Put("/:id/restore", controllers.RestoreSmime)
+
+	// Register CRUD routes for /v1/smime-certificates.
+	smimeCertificates := route.Group("/smime-certificates", middleware.MachineProtected())
+	smimeCertificates.Get("/", controllers.GetSmimeCertificates)
+	smimeCertificates.Post("/", controllers.CreateSmimeCertificate)
+	smimeCertificates.Get("/:id", controllers.GetSmimeCertificate)
+	smimeCertificates.Put("/:id", controllers.UpdateSmimeCertificate)
+	smimeCertificates.Delete("/:id", controllers.DeleteSmimeCertificate)
+	smimeCertificates.Put("/:id/restore", controllers.RestoreSmimeCertificate)
+
+	// Register CRUD routes for /v1/pgp-keys.
+	pgpKeys := route.Group("/pgp-keys", middleware.MachineProtected())
+	pgpKeys.Get("/", controllers.GetPgpKeys)
+	pgpKeys.Post("/", controllers.CreatePgpKey)
+	pgpKeys.Get("/:id", controllers.GetPgpKey)
+	pgpKeys.Put("/:id", controllers.UpdatePgpKey)
+	pgpKeys.Delete("/:id", controllers.DeletePgpKey)
+	pgpKeys.Put("/:id/restore", controllers.RestorePgpKey)
+
+	// Register CRUD routes for /v1/pgp-public-keys.
+	pgpPublicKeys := route.Group("/pgp-public-keys", middleware.MachineProtected())
+	pgpPublicKeys.Get("/", controllers.GetPgpPublicKeys)
+	pgpPublicKeys.Post("/", controllers.CreatePgpPublicKey)
+	pgpPublicKeys.Get("/:id", controllers.GetPgpPublicKey)
+	pgpPublicKeys.Put("/:id", controllers.UpdatePgpPublicKey)
+	pgpPublicKeys.Delete("/:id", controllers.DeletePgpPublicKey)
+	pgpPublicKeys.Put("/:id/restore", controllers.RestorePgpPublicKey)
+
+	// Register routes for /v1/dkim-keys.
+	dkimKeys := route.Group("/dkim-keys", middleware.MachineProtected())
+	dkimKeys.Get("/", controllers.GetDkimKeys)
+	dkimKeys.Post("/", controllers.CreateDkimKey)
+	dkimKeys.Get("/:id", controllers.GetDkimKey)
+	dkimKeys.Put("/:id/activate", controllers.ActivateDkimKey)
+	dkimKeys.Delete("/:id", controllers.DeleteDkimKey)
+	dkimKeys.Put("/:id/restore", controllers.RestoreDkimKey)
 }
@@ -14,4 +14,7 @@ func PublicRoutes(a *fiber.App) {
 	oauth := route.Group("/oauth2")
 	oauth.Get("/gmails/callback", controllers.Oauth2GmailCallback)
 	oauth.Get("/azures/callback", controllers.Oauth2AzureCallback)
+
+	// Download of the attachments that are sent as download link.
+	route.Get("/attachment-links/:id", controllers.DownloadAttachmentLink)
 }
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/enums"
+	"api-mail/main/src/models"
+)
+
+// UpdateAppMail updates the settings of an existing app mail.
+func UpdateAppMail(appMail *models.AppMail, req *requests.UpdateAppMail) (*models.AppMail, error) {
+	appMail.SaveSentCopy = req.SaveSentCopy
+
+	if result := database.Pg.Model(appMail).Update("save_sent_copy", appMail.SaveSentCopy); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return appMail, nil
+}
+
+// GetAppMailCapabilities returns the capabilities of the configured providers of the app mail.
+// The providers of the app mail must be preloaded.
+func GetAppMailCapabilities(appMail *models.AppMail) []models.ProviderCapabilities {
+	capabilities := make([]models.ProviderCapabilities, 0, 3)
+
+	// SMTP appends the sent copy to the Sent folder over IMAP.
+	if appMail.Smtp != nil {
+		capabilities = append(capabilities, models.ProviderCapabilities{
+			Type:           enums.SMTP,
+			SentCopy:       enums.SentCopyOptional,
+			SavesSentCopy:  appMail.SaveSentCopy,
+			Smime:          true,
+			Pgp:            true,
+			CalendarEvents: true,
+		})
+	}
+
+	// Gmail always keeps the sent mail in the Sent label.
+	if appMail.Gmail != nil {
+		capabilities = append(capabilities, models.ProviderCapabilities{
+			Type:           enums.Gmail,
+			SentCopy:       enums.SentCopyAlways,
+			SavesSentCopy:  true,
+			Smime:          true,
+			Pgp:            true,
+			CalendarEvents: true,
+		})
+	}
+
+	// Azure saves the sent copy with saveToSentItems of Graph sendMail, and protects mails that are delivered over SMTP.
+	if appMail.Azure != nil {
+		capabilities = append(capabilities, models.ProviderCapabilities{
+			Type:           enums.Azure,
+			SentCopy:       enums.SentCopyOptional,
+			SavesSentCopy:  appMail.SaveSentCopy,
+			Smime:          appMail.Azure.SmtpDelivery,
+			Pgp:            appMail.Azure.SmtpDelivery,
+			CalendarEvents: true,
+		})
+	}
+
+	return capabilities
+}
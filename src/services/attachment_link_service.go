@@ -0,0 +1,267 @@
+package services
+
+import (
+	"api-mail/main/src/configs"
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/enums"
+	"api-mail/main/src/models"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"html"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrAttachmentLinkExpired is returned when an expired download link is downloaded.
+var ErrAttachmentLinkExpired = errors.New("the download link is expired")
+
+// LinkLargeAttachments replaces the attachments of the threshold and larger by download links when the message is too
+// large for the provider. The attachments are stored in the blob store and the links are added to the end of the body.
+// The scans of the remaining attachments are returned in their order, the links get the send-mail when it is saved.
+func LinkLargeAttachments(ctx context.Context, appMail *models.AppMail, primaryType enums.AppMailPrimaryType, sendMail *requests.SendMail, scans []AttachmentScan) ([]AttachmentScan, []models.AttachmentLink, error) {
+	if maxSize, err := maxMessageSize(appMail, primaryType, sendMail); err != nil {
+		return nil, nil, err
+	} else if estimateMessageSize(sendMail) <= maxSize {
+		return scans, nil, nil
+	}
+
+	settings := configs.AttachmentLinkConfig()
+	if settings.Secret == "" || settings.BaseURL == "" {
+		return nil, nil, errors.New("download links need ATTACHMENT_LINK_SECRET and ATTACHMENT_LINK_BASE_URL")
+	}
+
+	expiresAt := time.Now().Add(settings.Ttl)
+	attachments := make([]requests.SendMailAttachment, 0, len(sendMail.Attachments))
+	links := make([]models.AttachmentLink, 0)
+	linked := make([][]byte, 0)
+	var remainingScans []AttachmentScan
+
+	for i := range sendMail.Attachments {
+		attachment := sendMail.Attachments[i]
+		if int64(len(attachment.FileData)) < settings.Threshold {
+			attachments = append(attachments, attachment)
+			if scans != nil {
+				remainingScans = append(remainingScans, scans[i])
+			}
+			continue
+		}
+
+		links = append(links, models.AttachmentLink{
+			AppName:   appMail.AppName,
+			FileName:  attachment.FileName,
+			FileType:  attachment.FileType,
+			FileSize:  int64(len(attachment.FileData)),
+			FileHash:  BlobKey(attachment.FileData),
+			ExpiresAt: expiresAt,
+		})
+		linked = append(linked, attachment.FileData)
+	}
+
+	if len(links) == 0 {
+		return scans, nil, nil
+	}
+
+	if err := database.Pg.Transaction(func(tx *gorm.DB) error {
+		keys := make([]string, len(links))
+		for i := range links {
+			keys[i] = links[i].FileHash
+		}
+
+		if err := LockBlobs(tx, keys...); err != nil {
+			return err
+		}
+
+		for i := range linked {
+			if _, err := PutBlob(ctx, linked[i]); err != nil {
+				return err
+			}
+		}
+
+		if result := tx.Omit("App", "SendMail").Create(&links); result.Error != nil {
+			return result.Error
+		}
+
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	sendMail.Attachments = attachments
+	sendMail.Body = appendAttachmentLinks(sendMail.Body, sendMail.MimeType, links, settings)
+	if sendMail.TextBody != "" {
+		sendMail.TextBody = appendAttachmentLinks(sendMail.TextBody, "text/plain", links, settings)
+	}
+
+	return remainingScans, links, nil
+}
+
+// DeleteAttachmentLinks deletes the download links of a mail that is not sent, with their content when nothing else has it.
+func DeleteAttachmentLinks(ctx context.Context, links []models.AttachmentLink) error {
+	for i := range links {
+		if result := database.Pg.Delete(&links[i]); result.Error != nil {
+			return result.Error
+		}
+
+		if err := deleteUnreferencedBlob(ctx, links[i].FileHash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAttachmentLink gets the download link, also when it is expired.
+func GetAttachmentLink(id uint) (*models.AttachmentLink, error) {
+	link := &models.AttachmentLink{}
+
+	if result := database.Pg.Find(link, "id = ?", id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return link, nil
+}
+
+// IsAttachmentLinkSigned checks the expires and signature query parameters of the download URL of the link.
+func IsAttachmentLinkSigned(link *models.AttachmentLink, expires, signature string) bool {
+	secret := configs.AttachmentLinkConfig().Secret
+	if secret == "" || expires != strconv.FormatInt(link.ExpiresAt.Unix(), 10) {
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(expected, signAttachmentLink(link, secret))
+}
+
+// DownloadAttachmentLink counts the download of the link and opens its content in the blob store, the caller closes it.
+// ErrAttachmentLinkExpired is returned when the link is expired.
+func DownloadAttachmentLink(ctx context.Context, link *models.AttachmentLink) (io.ReadCloser, error) {
+	now := time.Now()
+	result := database.Pg.Model(&models.AttachmentLink{}).
+		Where("id = ? AND expires_at > ?", link.ID, now).
+		Updates(map[string]any{"downloads": gorm.Expr("downloads + 1"), "last_downloaded_at": now})
+	if result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, ErrAttachmentLinkExpired
+	}
+
+	return OpenBlob(ctx, link.FileHash)
+}
+
+// DeleteExpiredAttachmentLinks deletes the expired download links and returns how many were deleted.
+// The content is removed from the blob store when nothing else has the same content.
+func DeleteExpiredAttachmentLinks(ctx context.Context) (int, error) {
+	links := make([]models.AttachmentLink, 0)
+
+	if result := database.Pg.Where("expires_at <= ?", time.Now()).Find(&links); result.Error != nil {
+		return 0, result.Error
+	}
+
+	for i := range links {
+		if err := DeleteAttachmentLinks(ctx, links[i:i+1]); err != nil {
+			return i, err
+		}
+	}
+
+	return len(links), nil
+}
+
+// AttachmentLinkURL returns the signed download URL of the link.
+func AttachmentLinkURL(link *models.AttachmentLink, settings configs.AttachmentLinkSettings) string {
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(link.ExpiresAt.Unix(), 10))
+	query.Set("signature", hex.EncodeToString(signAttachmentLink(link, settings.Secret)))
+
+	return fmt.Sprintf("%s/v1/attachment-links/%d?%s", strings.TrimSuffix(settings.BaseURL, "/"), link.ID, query.Encode())
+}
+
+// signAttachmentLink returns the HMAC-SHA256 of the ID and expiry of the link.
+func signAttachmentLink(link *models.AttachmentLink, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%d:%d", link.ID, link.ExpiresAt.Unix())))
+
+	return mac.Sum(nil)
+}
+
+// appendAttachmentLinks adds the download links to the body, as list in an HTML body and as lines in a text body.
+func appendAttachmentLinks(body, mimeType string, links []models.AttachmentLink, settings configs.AttachmentLinkSettings) string {
+	expiresAt := links[0].ExpiresAt.UTC().Format("2006-01-02 15:04 MST")
+
+	if mimeType == "text/plain" {
+		var text strings.Builder
+		text.WriteString("\n\nThe following attachments can be downloaded until " + expiresAt + ":\n")
+		for i := range links {
+			text.WriteString(fmt.Sprintf("- %s (%s): %s\n", links[i].FileName, formatFileSize(links[i].FileSize), AttachmentLinkURL(&links[i], settings)))
+		}
+
+		return body + text.String()
+	}
+
+	var list strings.Builder
+	list.WriteString("<p>The following attachments can be downloaded until " + expiresAt + ":</p><ul>")
+	for i := range links {
+		list.WriteString(fmt.Sprintf(`<li><a href="%s">%s</a> (%s)</li>`, html.EscapeString(AttachmentLinkURL(&links[i], settings)), html.EscapeString(links[i].FileName), formatFileSize(links[i].FileSize)))
+	}
+	list.WriteString("</ul>")
+
+	// The list is added inside the body element of a complete HTML document.
+	if index := strings.LastIndex(strings.ToLower(body), "</body>"); index != -1 {
+		return body[:index] + list.String() + body[index:]
+	}
+
+	return body + list.String()
+}
+
+// estimateMessageSize estimates the size of the message, with the attachments base64 encoded in lines of 76 characters.
+func estimateMessageSize(sendMail *requests.SendMail) int64 {
+	size := int64(len(sendMail.Body) + len(sendMail.TextBody))
+	for i := range sendMail.Attachments {
+		size += (int64(len(sendMail.Attachments[i].FileData)) + 2) / 3 * 4 * 78 / 76
+	}
+
+	return size
+}
+
+// maxMessageSize returns the largest message the delivery path of the provider sends.
+// Outlook sends a mail in the MIME format, such as a calendar invitation, in one Graph request.
+func maxMessageSize(appMail *models.AppMail, primaryType enums.AppMailPrimaryType, sendMail *requests.SendMail) (int64, error) {
+	switch primaryType {
+	case enums.Gmail:
+		return gmailMaxMessageSize, nil
+	case enums.Azure:
+		azure, err := getSendAzure(appMail)
+		if err != nil {
+			return 0, err
+		}
+
+		draft := sendMail.Mode != nil && enums.SendMode(*sendMail.Mode) == enums.ModeDraft
+		return azureMaxMessageSize(azure.SmtpDelivery && !draft, sendMail.Event != nil), nil
+	default:
+		return configs.SmtpMessageSize(), nil
+	}
+}
+
+// formatFileSize returns the size in B, KB or MB.
+func formatFileSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"api-mail/main/src/configs"
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/models"
+	"strings"
+)
+
+// defaultAllowedFileTypes are the allowed types of an app without a policy, or with a policy without allowed types.
+var defaultAllowedFileTypes = []string{
+	"application/pdf",
+	"image/jpeg",
+	"image/png",
+	"text/plain",
+	"text/html",
+	"application/msword",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.ms-excel",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"application/vnd.ms-powerpoint",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	"application/zip",
+	"application/x-rar-compressed",
+	"application/x-7z-compressed",
+	"application/x-tar",
+}
+
+// GetAttachmentPolicy gets the stored attachment policy of the app.
+func GetAttachmentPolicy(appName string) (*models.AttachmentPolicy, error) {
+	policy := &models.AttachmentPolicy{}
+
+	if result := database.Pg.Find(policy, "app_name = ?", appName); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return policy, nil
+}
+
+// GetAppAttachmentPolicy gets the attachment policy that applies to the mails of the app.
+// The allowed types default to defaultAllowedFileTypes and the sizes to the attachment limits of the environment.
+func GetAppAttachmentPolicy(appName string) (*models.AttachmentPolicy, error) {
+	policy, err := GetAttachmentPolicy(appName)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy.AllowedTypes == nil {
+		policy.AllowedTypes = joinPolicyList(defaultAllowedFileTypes, strings.ToLower)
+	}
+
+	limits := configs.AttachmentConfig()
+	if policy.MaxFileSize == nil {
+		policy.MaxFileSize = &limits.MaxFileSize
+	}
+	if policy.MaxTotalSize == nil {
+		policy.MaxTotalSize = &limits.MaxTotalSize
+	}
+
+	return policy, nil
+}
+
+// SaveAttachmentPolicy creates or replaces the attachment policy of the app.
+func SaveAttachmentPolicy(appName string, policy *models.AttachmentPolicy, req *requests.UpdateAttachmentPolicy) (*models.AttachmentPolicy, error) {
+	policy.AppName = appName
+	policy.AllowedTypes = joinPolicyList(req.AllowedTypes, strings.ToLower)
+	policy.BlockedTypes = joinPolicyList(req.BlockedTypes, strings.ToLower)
+	policy.AllowedExtensions = joinPolicyList(req.AllowedExtensions, normalizeExtension)
+	policy.BlockedExtensions = joinPolicyList(req.BlockedExtensions, normalizeExtension)
+	policy.MaxFileSize = req.MaxFileSize
+	policy.MaxTotalSize = req.MaxTotalSize
+	policy.MaxCount = req.MaxCount
+
+	if result := database.Pg.Omit("App").Save(policy); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return policy, nil
+}
+
+// DeleteAttachmentPolicy deletes the attachment policy, the mails of the app get the default policy again.
+func DeleteAttachmentPolicy(policy *models.AttachmentPolicy) error {
+	if result := database.Pg.Delete(policy); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// joinPolicyList normalizes and joins the items of a policy list, an empty list is nil.
+func joinPolicyList(items []string, normalize func(string) string) *string {
+	if len(items) == 0 {
+		return nil
+	}
+
+	normalized := make([]string, len(items))
+	for i := range items {
+		normalized[i] = normalize(strings.TrimSpace(items[i]))
+	}
+
+	joined := strings.Join(normalized, ",")
+
+	return &joined
+}
+
+// normalizeExtension returns the extension in lower case with a leading dot.
+func normalizeExtension(extension string) string {
+	extension = strings.ToLower(extension)
+	if !strings.HasPrefix(extension, ".") {
+		extension = "." + extension
+	}
+
+	return extension
+}
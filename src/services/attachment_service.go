@@ -0,0 +1,137 @@
+package services
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/models"
+	"context"
+	"database/sql"
+	"gorm.io/gorm"
+	"time"
+)
+
+// GetAttachment gets the attachment of the app from the attachment library, also when it is expired.
+func GetAttachment(appName string, id uint) (*models.Attachment, error) {
+	attachment := &models.Attachment{}
+
+	if result := database.Pg.Find(attachment, "app_name = ? AND id = ?", appName, id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return attachment, nil
+}
+
+// GetAttachmentsByIDs gets the attachments of the app with the IDs, an unknown ID is left out.
+func GetAttachmentsByIDs(appName string, ids []uint) ([]models.Attachment, error) {
+	attachments := make([]models.Attachment, 0)
+
+	if result := database.Pg.Where("app_name = ? AND id IN ?", appName, ids).Find(&attachments); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return attachments, nil
+}
+
+// CreateAttachment stores the content in the blob store and adds the attachment to the library of the app.
+func CreateAttachment(ctx context.Context, appName string, req *requests.CreateAttachment) (*models.Attachment, error) {
+	attachment := &models.Attachment{
+		AppName:  appName,
+		FileName: req.FileName,
+		FileType: req.FileType,
+		FileSize: int64(len(req.FileData)),
+		FileHash: BlobKey(req.FileData),
+	}
+	if req.ExpiresAt != nil {
+		attachment.ExpiresAt = sql.NullTime{Valid: true, Time: *req.ExpiresAt}
+	}
+
+	if err := database.Pg.Transaction(func(tx *gorm.DB) error {
+		if err := LockBlobs(tx, attachment.FileHash); err != nil {
+			return err
+		}
+
+		if _, err := PutBlob(ctx, req.FileData); err != nil {
+			return err
+		}
+
+		if result := tx.Omit("App").Create(attachment); result.Error != nil {
+			return result.Error
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// ReadAttachments reads the content of the attachments from the blob store as attachments of a send-mail.
+func ReadAttachments(ctx context.Context, attachments []models.Attachment) ([]requests.SendMailAttachment, error) {
+	sendMailAttachments := make([]requests.SendMailAttachment, len(attachments))
+
+	for i := range attachments {
+		data, err := GetBlob(ctx, attachments[i].FileHash)
+		if err != nil {
+			return nil, err
+		}
+
+		sendMailAttachments[i] = requests.SendMailAttachment{
+			FileName: attachments[i].FileName,
+			FileType: attachments[i].FileType,
+			FileSize: attachments[i].FileSize,
+			FileData: data,
+		}
+	}
+
+	return sendMailAttachments, nil
+}
+
+// DeleteAttachment deletes the attachment from the library.
+// The content is removed from the blob store when no other attachment, send-mail or link has the same content.
+func DeleteAttachment(ctx context.Context, attachment *models.Attachment) error {
+	if result := database.Pg.Delete(attachment); result.Error != nil {
+		return result.Error
+	}
+
+	return deleteUnreferencedBlob(ctx, attachment.FileHash)
+}
+
+// DeleteExpiredAttachments deletes the expired attachments of all apps and returns how many were deleted.
+func DeleteExpiredAttachments(ctx context.Context) (int, error) {
+	attachments := make([]models.Attachment, 0)
+
+	if result := database.Pg.Where("expires_at <= ?", time.Now()).Find(&attachments); result.Error != nil {
+		return 0, result.Error
+	}
+
+	for i := range attachments {
+		if err := DeleteAttachment(ctx, &attachments[i]); err != nil {
+			return i, err
+		}
+	}
+
+	return len(attachments), nil
+}
+
+// deleteUnreferencedBlob removes the content from the blob store when no attachment, send-mail or link has it anymore.
+// It runs after the reference is deleted and holds the lock of the blob, so a reference that is stored in the meantime
+// keeps the content. When the removal fails the content stays behind without a reference.
+func deleteUnreferencedBlob(ctx context.Context, fileHash string) error {
+	return database.Pg.Transaction(func(tx *gorm.DB) error {
+		if err := LockBlobs(tx, fileHash); err != nil {
+			return err
+		}
+
+		for _, model := range []any{&models.Attachment{}, &models.SendMailAttachment{}, &models.AttachmentLink{}} {
+			count := int64(0)
+			if result := tx.Model(model).Where("file_hash = ?", fileHash).Count(&count); result.Error != nil {
+				return result.Error
+			} else if count > 0 {
+				return nil
+			}
+		}
+
+		return DeleteBlob(ctx, fileHash)
+	})
+}
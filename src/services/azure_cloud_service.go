@@ -0,0 +1,75 @@
+package services
+
+import (
+	"api-mail/main/src/enums"
+	"api-mail/main/src/models"
+	"golang.org/x/oauth2"
+	"strings"
+)
+
+// azureCloud holds the login authority, Graph root and Exchange Online SMTP server of a Microsoft cloud.
+type azureCloud struct {
+	authority        string
+	graph            string
+	graphScopePrefix string
+	smtpHost         string
+	smtpScope        string
+}
+
+// azureClouds are the endpoints of the Microsoft national clouds.
+// Outside the global cloud the Graph scopes must name the Graph root of the cloud.
+var azureClouds = map[enums.AzureCloud]azureCloud{
+	enums.CloudGlobal: {
+		authority: "https://login.microsoftonline.com",
+		graph:     "https://graph.microsoft.com",
+		smtpHost:  "smtp.office365.com",
+		smtpScope: "https://outlook.office.com/SMTP.Send",
+	},
+	enums.CloudUSGovernment: {
+		authority:        "https://login.microsoftonline.us",
+		graph:            "https://graph.microsoft.us",
+		graphScopePrefix: "https://graph.microsoft.us/",
+		smtpHost:         "smtp.office365.us",
+		smtpScope:        "https://outlook.office365.us/SMTP.Send",
+	},
+	enums.CloudChina: {
+		authority:        "https://login.chinacloudapi.cn",
+		graph:            "https://microsoftgraph.chinacloudapi.cn",
+		graphScopePrefix: "https://microsoftgraph.chinacloudapi.cn/",
+		smtpHost:         "smtp.partner.outlook.cn",
+		smtpScope:        "https://partner.outlook.cn/SMTP.Send",
+	},
+}
+
+// getAzureCloud returns the endpoints of the cloud of the azure, with the authority and Graph root overrides of the azure.
+func getAzureCloud(azure *models.Azure) azureCloud {
+	cloud := azureClouds[azure.Cloud()]
+
+	if azure.AuthorityURL != nil && *azure.AuthorityURL != "" {
+		cloud.authority = strings.TrimRight(*azure.AuthorityURL, "/")
+	}
+
+	if azure.GraphURL != nil && *azure.GraphURL != "" {
+		cloud.graph = strings.TrimRight(*azure.GraphURL, "/")
+	}
+
+	return cloud
+}
+
+// endpoint returns the OAuth2 endpoint of the tenant at the authority of the cloud.
+func (c azureCloud) endpoint(tenantID string) oauth2.Endpoint {
+	return oauth2.Endpoint{
+		AuthURL:  c.authority + "/" + tenantID + "/oauth2/v2.0/authorize",
+		TokenURL: c.authority + "/" + tenantID + "/oauth2/v2.0/token",
+	}
+}
+
+// graphURL returns the Graph v1.0 url of the path, e.g. /me/sendMail.
+func (c azureCloud) graphURL(path string) string {
+	return c.graph + "/v1.0" + path
+}
+
+// graphScope returns the delegated Graph permission as scope of the cloud.
+func (c azureCloud) graphScope(permission string) string {
+	return c.graphScopePrefix + permission
+}
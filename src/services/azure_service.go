@@ -7,13 +7,19 @@ import (
 	"api-mail/main/src/enums"
 	"api-mail/main/src/models"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/valkey-io/valkey-go"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/microsoft"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -108,19 +114,26 @@ func GetAzureFromCache(id uint) (*models.Azure, error) {
 	return &azure, nil
 }
 
-// CreateAzureOauthConfig creates a new oauth config.
-func CreateAzureOauthConfig(clientID, tenantID, secret string) *oauth2.Config {
+// CreateAzureOauthConfig creates a new oauth config at the authority of the cloud of the azure.
+// SMTP delivery adds the consent for the SMTP.Send scope of Exchange Online, the token of the callback stays a Graph token.
+func CreateAzureOauthConfig(azure *models.Azure) *oauth2.Config {
+	cloud := getAzureCloud(azure)
 	redirectUrl := fmt.Sprintf(
 		"%sv1/oauth2/azures/callback",
 		os.Getenv("DOMAIN_NAME"),
 	)
 
+	scopes := []string{"openid", "offline_access", cloud.graphScope("User.Read"), cloud.graphScope("Mail.Send"), cloud.graphScope("Mail.ReadWrite")}
+	if azure.SmtpDelivery {
+		scopes = append(scopes, cloud.smtpScope)
+	}
+
 	return &oauth2.Config{
-		ClientID:     clientID,
-		ClientSecret: secret,
-		Endpoint:     microsoft.AzureADEndpoint(tenantID),
+		ClientID:     azure.ClientID,
+		ClientSecret: azure.Secret,
+		Endpoint:     cloud.endpoint(azure.TenantID),
 		RedirectURL:  redirectUrl,
-		Scopes:       []string{"openid", "offline_access", "user.read", "mail.send"},
+		Scopes:       scopes,
 	}
 }
 
@@ -128,10 +141,14 @@ func CreateAzureOauthConfig(clientID, tenantID, secret string) *oauth2.Config {
 func CreateAzure(req *requests.CreateAzure) (*models.Azure, error) {
 	azureType := enums.Azure
 	azure := &models.Azure{
-		ClientID: req.ClientID,
-		TenantID: req.TenantID,
-		Secret:   req.Secret,
-		User:     req.User,
+		ClientID:       req.ClientID,
+		TenantID:       req.TenantID,
+		Secret:         req.Secret,
+		User:           req.User,
+		SmtpDelivery:   req.SmtpDelivery,
+		AzureCloudName: req.Cloud,
+		AuthorityURL:   req.AuthorityURL,
+		GraphURL:       req.GraphURL,
 		AppMail: models.AppMail{
 			AppName:  req.App,
 			MailName: req.Mail,
@@ -226,6 +243,10 @@ func UpdateAzure(oldAzure *models.Azure, req *requests.UpdateAzure) (*models.Azu
 	oldAzure.TenantID = req.TenantID
 	oldAzure.Secret = req.Secret
 	oldAzure.User = req.User
+	oldAzure.SmtpDelivery = req.SmtpDelivery
+	oldAzure.AzureCloudName = req.Cloud
+	oldAzure.AuthorityURL = req.AuthorityURL
+	oldAzure.GraphURL = req.GraphURL
 
 	if req.Primary && (!oldAzure.AppMail.PrimaryType.Valid || oldAzure.AppMail.PrimaryType.String != *azureType.ToString()) {
 		oldAzure.AppMail.PrimaryType = sql.NullString{String: *azureType.ToString(), Valid: true}
@@ -269,6 +290,45 @@ func DeleteAzure(azure *models.Azure) error {
 	return nil
 }
 
+// RevokeAzureToken revokes the refresh tokens of the user with Graph revokeSignInSessions and clears the stored token.
+// Microsoft revokes all refresh tokens of the user, so the user is also signed out of the other applications.
+// A token that Microsoft rejects, or that can't be refreshed anymore, is already revoked or expired.
+func RevokeAzureToken(ctx context.Context, azure *models.Azure) error {
+	if token, err := GetAzureToken(azure); err == nil {
+		client := CreateAzureOauthConfig(azure).Client(ctx, token)
+
+		resp, err := client.Post(getAzureCloud(azure).graphURL("/me/revokeSignInSessions"), "application/json", nil)
+		if err != nil {
+			var retrieveErr *oauth2.RetrieveError
+			if !errors.As(err, &retrieveErr) || retrieveErr.ErrorCode != "invalid_grant" {
+				return fmt.Errorf("error revoking azure token: %s", err.Error())
+			}
+		} else {
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+				return fmt.Errorf("error revoking azure token: %s, Body: %s", resp.Status, string(body))
+			}
+		}
+	}
+
+	azure.AccessToken = sql.NullString{}
+	azure.RefreshToken = sql.NullString{}
+	azure.TokenType = sql.NullString{}
+	azure.Expiry = sql.NullTime{}
+	azure.ExpiresIn = sql.NullInt64{}
+	azure.VerifiedMail = sql.NullString{}
+
+	if result := database.Pg.Model(azure).
+		Select("AccessToken", "RefreshToken", "TokenType", "Expiry", "ExpiresIn", "VerifiedMail").
+		Updates(azure); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
 // DeleteAzureFromCache deletes a existing azure from the cache.
 func DeleteAzureFromCache(id uint) error {
 	key := azureCacheKey(id)
@@ -290,7 +350,217 @@ func RestoreAzure(azure *models.Azure) error {
 	return nil
 }
 
+// GetAzureToken gets the stored OAuth2 token of the azure.
+func GetAzureToken(azure *models.Azure) (*oauth2.Token, error) {
+	if !azure.AccessToken.Valid ||
+		!azure.RefreshToken.Valid ||
+		!azure.TokenType.Valid ||
+		!azure.Expiry.Valid ||
+		!azure.ExpiresIn.Valid {
+		return nil, errors.New("azure not authenticated")
+	}
+
+	return &oauth2.Token{
+		AccessToken:  azure.AccessToken.String,
+		TokenType:    azure.TokenType.String,
+		RefreshToken: azure.RefreshToken.String,
+		Expiry:       azure.Expiry.Time,
+		ExpiresIn:    azure.ExpiresIn.Int64,
+	}, nil
+}
+
+// RefreshAzureToken refreshes the Graph token of the azure when it is expired, or always when forced.
+// A refreshed token is stored.
+func RefreshAzureToken(ctx context.Context, azure *models.Azure, force bool) (*oauth2.Token, error) {
+	token, err := GetAzureToken(azure)
+	if err != nil {
+		return nil, err
+	}
+
+	if force {
+		token.Expiry = time.Unix(1, 0)
+	}
+
+	oauthConfig := CreateAzureOauthConfig(azure)
+	newToken, err := oauthConfig.TokenSource(ctx, token).Token()
+	if err != nil {
+		return nil, fmt.Errorf("error refreshing azure token: %s", err.Error())
+	}
+
+	if newToken.AccessToken != azure.AccessToken.String {
+		if _, err := UpdateAzureToken(azure, newToken); err != nil {
+			return nil, err
+		}
+
+		if err := SetAzureToCache(azure); err != nil {
+			return nil, err
+		}
+	}
+
+	return newToken, nil
+}
+
+// GetAzureSmtpToken gets an access token of Exchange Online for SMTP XOAUTH2.
+// The stored token is a Graph token, so the refresh token is redeemed for the SMTP.Send scope. The access token is cached
+// until it expires, and the refresh token that Microsoft rotates is stored like with the refresh of the Graph token.
+func GetAzureSmtpToken(ctx context.Context, azure *models.Azure) (string, error) {
+	token, err := GetAzureToken(azure)
+	if err != nil {
+		return "", err
+	}
+
+	// The cached token belongs to the refresh token, so a new consent redeems a new token.
+	result := cache.Valkey.Do(ctx, cache.Valkey.B().Get().Key(azureSmtpTokenCacheKey(azure.ID, token.RefreshToken)).Build())
+	if accessToken, err := result.ToString(); err == nil {
+		return accessToken, nil
+	} else if !valkey.IsValkeyNil(err) {
+		return "", err
+	}
+
+	cloud := getAzureCloud(azure)
+	endpoint := cloud.endpoint(azure.TenantID)
+	form := url.Values{
+		"client_id":     {azure.ClientID},
+		"client_secret": {azure.Secret},
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+		"scope":         {cloud.smtpScope + " offline_access"},
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("error refreshing azure smtp token: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		RefreshToken     string `json:"refresh_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("error refreshing azure smtp token: %s", err.Error())
+	}
+
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		return "", fmt.Errorf("error refreshing azure smtp token: %s, %s %s", resp.Status, body.Error, body.ErrorDescription)
+	}
+
+	if body.RefreshToken != "" && body.RefreshToken != token.RefreshToken {
+		if err := updateAzureRefreshToken(azure, body.RefreshToken); err != nil {
+			return "", err
+		}
+	}
+
+	// The token is cached a minute shorter than it is valid, so it does not expire during a send.
+	if expiration := time.Duration(body.ExpiresIn)*time.Second - time.Minute; expiration > 0 {
+		key := azureSmtpTokenCacheKey(azure.ID, azure.RefreshToken.String)
+		result := cache.Valkey.Do(ctx, cache.Valkey.B().Set().Key(key).Value(body.AccessToken).Ex(expiration).Build())
+		if result.Error() != nil {
+			return "", result.Error()
+		}
+	}
+
+	return body.AccessToken, nil
+}
+
+// IsAzureSmtpDelivery checks if the azure of the app mail delivers the mails over SMTP.
+func IsAzureSmtpDelivery(appMail *models.AppMail) (bool, error) {
+	azure, err := getSendAzure(appMail)
+	if err != nil {
+		return false, err
+	}
+
+	return azure.SmtpDelivery, nil
+}
+
+// VerifyAzureIdentity checks that the Microsoft account that consented has the mailbox or user of the azure as address.
+// It returns the addresses of the account, the matching address is set as verified mail and stored with the token.
+func VerifyAzureIdentity(ctx context.Context, azure *models.Azure, token *oauth2.Token) ([]string, bool, error) {
+	identities, err := getAzureIdentities(ctx, azure, token)
+	if err != nil {
+		return nil, false, err
+	}
+
+	identity, ok := MatchOauthIdentity(identities, azure.AppMail.MailName, azure.User)
+	if !ok {
+		return identities, false, nil
+	}
+
+	azure.VerifiedMail = sql.NullString{Valid: true, String: identity}
+
+	return identities, true, nil
+}
+
+// getAzureIdentities reads the mail, user principal name and proxy addresses of the account from Microsoft Graph.
+func getAzureIdentities(ctx context.Context, azure *models.Azure, token *oauth2.Token) ([]string, error) {
+	oauthConfig := CreateAzureOauthConfig(azure)
+	client := oauthConfig.Client(ctx, token)
+
+	resp, err := client.Get(getAzureCloud(azure).graphURL("/me?$select=mail,userPrincipalName,proxyAddresses"))
+	if err != nil {
+		return nil, fmt.Errorf("error getting the azure profile: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("error getting the azure profile: %s, Body: %s", resp.Status, string(body))
+	}
+
+	var profile struct {
+		Mail              *string  `json:"mail"`
+		UserPrincipalName string   `json:"userPrincipalName"`
+		ProxyAddresses    []string `json:"proxyAddresses"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
+		return nil, fmt.Errorf("error reading the azure profile: %s", err.Error())
+	}
+
+	identities := make([]string, 0, len(profile.ProxyAddresses)+2)
+	if profile.Mail != nil && *profile.Mail != "" {
+		identities = append(identities, strings.ToLower(*profile.Mail))
+	}
+	if profile.UserPrincipalName != "" {
+		identities = append(identities, strings.ToLower(profile.UserPrincipalName))
+	}
+	for _, proxyAddress := range profile.ProxyAddresses {
+		if protocol, address, found := strings.Cut(proxyAddress, ":"); found && strings.EqualFold(protocol, "smtp") {
+			identities = append(identities, strings.ToLower(address))
+		}
+	}
+
+	return identities, nil
+}
+
+// updateAzureRefreshToken stores the rotated refresh token of the azure.
+// The updated at is not changed, the token is not a change of the settings.
+func updateAzureRefreshToken(azure *models.Azure, refreshToken string) error {
+	azure.RefreshToken = sql.NullString{Valid: true, String: refreshToken}
+
+	if result := database.Pg.Model(azure).UpdateColumn("refresh_token", azure.RefreshToken); result.Error != nil {
+		return result.Error
+	}
+
+	return SetAzureToCache(azure)
+}
+
 // azureCacheKey returns the key for the azure cache.
 func azureCacheKey(id uint) string {
 	return fmt.Sprintf("%s:%d", enums.Azure, id)
 }
+
+// azureSmtpTokenCacheKey returns the key of the cached SMTP access token of the refresh token of the azure.
+func azureSmtpTokenCacheKey(id uint, refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+
+	return fmt.Sprintf("%s:%d:smtp:%s", enums.Azure, id, hex.EncodeToString(hash[:8]))
+}
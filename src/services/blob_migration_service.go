@@ -0,0 +1,51 @@
+package services
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/models"
+	"context"
+)
+
+const blobMigrationBatchSize = 100
+
+// MigrateAttachmentBlobs moves the content of the former file_data column of the send-mail attachments to the blob
+// store and drops the column when every row is moved. An interrupted migration continues on the next start.
+func MigrateAttachmentBlobs(ctx context.Context) error {
+	migrator := database.Pg.Migrator()
+	if !migrator.HasColumn(&models.SendMailAttachment{}, "file_data") {
+		return nil
+	}
+
+	for {
+		var rows []struct {
+			ID       uint
+			FileData []byte
+		}
+
+		if result := database.Pg.Model(&models.SendMailAttachment{}).
+			Select("id", "file_data").
+			Where("file_hash IS NULL OR file_hash = ''").
+			Order("id").
+			Limit(blobMigrationBatchSize).
+			Find(&rows); result.Error != nil {
+			return result.Error
+		} else if len(rows) == 0 {
+			break
+		}
+
+		for _, row := range rows {
+			fileHash, err := PutBlob(ctx, row.FileData)
+			if err != nil {
+				return err
+			}
+
+			if result := database.Pg.Model(&models.SendMailAttachment{}).
+				Where("id = ?", row.ID).
+				Update("file_hash", fileHash); result.Error != nil {
+				return result.Error
+			}
+		}
+	}
+
+	return migrator.DropColumn(&models.SendMailAttachment{}, "file_data")
+}
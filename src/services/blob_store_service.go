@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+)
+
+const defaultLocalBlobPath = "data/blobs"
+
+// BlobStore stores file content under the SHA-256 hash of the content, so identical files are stored once.
+type BlobStore interface {
+	// Put stores the content under the key, content that is already stored is not written again.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get reads the content of the key.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Open opens the content of the key to stream it, the caller closes it.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the content of the key.
+	Delete(ctx context.Context, key string) error
+}
+
+// blobStore is the store of the attachment content.
+var blobStore BlobStore
+
+// SetupBlobStore sets the blob store of BLOB_STORE, "local" (default) in BLOB_STORE_PATH or "s3" in BLOB_S3_BUCKET.
+func SetupBlobStore() error {
+	switch os.Getenv("BLOB_STORE") {
+	case "", "local":
+		path := os.Getenv("BLOB_STORE_PATH")
+		if path == "" {
+			path = defaultLocalBlobPath
+		}
+
+		store, err := NewLocalBlobStore(path)
+		if err != nil {
+			return err
+		}
+		SetBlobStore(store)
+	case "s3":
+		useSsl, _ := strconv.ParseBool(os.Getenv("BLOB_S3_USE_SSL"))
+		store, err := NewS3BlobStore(
+			os.Getenv("BLOB_S3_ENDPOINT"),
+			os.Getenv("BLOB_S3_REGION"),
+			os.Getenv("BLOB_S3_BUCKET"),
+			os.Getenv("BLOB_S3_ACCESS_KEY"),
+			os.Getenv("BLOB_S3_SECRET_KEY"),
+			useSsl,
+		)
+		if err != nil {
+			return err
+		}
+		SetBlobStore(store)
+	default:
+		return fmt.Errorf("blob store %s is unknown, use local or s3", os.Getenv("BLOB_STORE"))
+	}
+
+	return nil
+}
+
+// SetBlobStore replaces the store of the attachment content.
+func SetBlobStore(store BlobStore) {
+	blobStore = store
+}
+
+// PutBlob stores the content in the blob store and returns its key, the hex SHA-256 hash of the content.
+func PutBlob(ctx context.Context, data []byte) (string, error) {
+	key := BlobKey(data)
+
+	if err := blobStore.Put(ctx, key, data); err != nil {
+		return "", fmt.Errorf("blob %s can't be stored: %s", key, err.Error())
+	}
+
+	return key, nil
+}
+
+// GetBlob reads the content of the key from the blob store.
+func GetBlob(ctx context.Context, key string) ([]byte, error) {
+	data, err := blobStore.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("blob %s can't be read: %s", key, err.Error())
+	}
+
+	return data, nil
+}
+
+// OpenBlob opens the content of the key in the blob store to stream it, the caller closes it.
+func OpenBlob(ctx context.Context, key string) (io.ReadCloser, error) {
+	content, err := blobStore.Open(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("blob %s can't be read: %s", key, err.Error())
+	}
+
+	return content, nil
+}
+
+// DeleteBlob removes the content of the key from the blob store.
+func DeleteBlob(ctx context.Context, key string) error {
+	if err := blobStore.Delete(ctx, key); err != nil {
+		return fmt.Errorf("blob %s can't be deleted: %s", key, err.Error())
+	}
+
+	return nil
+}
+
+// LockBlobs takes the transaction locks of the blob keys, in sorted order so two transactions can't wait on each other.
+// A transaction that stores a reference to a blob holds the lock, so deleteUnreferencedBlob can't remove the blob
+// between its PutBlob and its commit.
+func LockBlobs(tx *gorm.DB, keys ...string) error {
+	sorted := slices.Compact(slices.Sorted(slices.Values(keys)))
+
+	for _, key := range sorted {
+		if result := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	return nil
+}
+
+// BlobKey returns the key of the content, the hex SHA-256 hash.
+func BlobKey(data []byte) string {
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:])
+}
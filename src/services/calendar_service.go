@@ -0,0 +1,318 @@
+package services
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const iCalendarDateTime = "20060102T150405"
+
+// GetCalendarEvent gets the last sent state of the calendar event with the UID.
+func GetCalendarEvent(appMailID uint, uid string) (*models.CalendarEvent, error) {
+	calendarEvent := &models.CalendarEvent{}
+
+	if result := database.Pg.Find(calendarEvent, "app_mail_id = ? AND uid = ?", appMailID, uid); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return calendarEvent, nil
+}
+
+// MergeCalendarEvent completes the event with the defaults and the last sent state of the same UID.
+// An update or cancellation gets the next sequence, and a cancellation without details reuses the sent event.
+func MergeCalendarEvent(event *requests.SendMailEvent, calendarEvent *models.CalendarEvent, subject, fromName, fromMail string) error {
+	if calendarEvent != nil && calendarEvent.ID != 0 {
+		sent := requests.SendMailEvent{}
+		if err := json.Unmarshal(calendarEvent.Event, &sent); err != nil {
+			return err
+		}
+
+		if event.Sequence == nil {
+			sequence := calendarEvent.Sequence + 1
+			event.Sequence = &sequence
+		}
+
+		if event.Method == "CANCEL" {
+			if event.Start == nil && event.End == nil {
+				event.Start = sent.Start
+				event.End = sent.End
+				if event.TimeZone == "" {
+					event.TimeZone = sent.TimeZone
+				}
+			}
+			if event.Organizer == nil {
+				event.Organizer = sent.Organizer
+			}
+			if len(event.Attendees) == 0 {
+				event.Attendees = sent.Attendees
+			}
+			if event.Location == "" {
+				event.Location = sent.Location
+			}
+			if event.Summary == "" {
+				event.Summary = sent.Summary
+			}
+		}
+	}
+
+	if event.Sequence == nil {
+		sequence := 0
+		event.Sequence = &sequence
+	}
+
+	if event.Summary == "" {
+		event.Summary = subject
+	}
+
+	if event.Organizer == nil {
+		event.Organizer = &requests.SendMailEventAttendee{Name: fromName, Mail: fromMail}
+	}
+
+	if event.Start == nil || event.End == nil {
+		return errors.New("event start and end are required")
+	}
+
+	if !event.End.After(*event.Start) {
+		return errors.New("event end must be after the start")
+	}
+
+	if event.TimeZone != "" {
+		if _, err := time.LoadLocation(event.TimeZone); err != nil {
+			return fmt.Errorf("event timezone is invalid: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// SaveCalendarEvent stores the sent state of the calendar event.
+func SaveCalendarEvent(appMailID uint, event *requests.SendMailEvent) error {
+	calendarEvent, err := GetCalendarEvent(appMailID, event.UID)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	calendarEvent.AppMailID = appMailID
+	calendarEvent.UID = event.UID
+	calendarEvent.Sequence = *event.Sequence
+	calendarEvent.Method = event.Method
+	calendarEvent.Event = value
+
+	if result := database.Pg.Save(calendarEvent); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// CreateICalendar creates the iCalendar (RFC 5545) object of the event.
+func CreateICalendar(event *requests.SendMailEvent) (string, error) {
+	location := time.UTC
+	if event.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(event.TimeZone); err != nil {
+			return "", err
+		}
+	}
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"PRODID:-//api-mail//EN",
+		"VERSION:2.0",
+		"CALSCALE:GREGORIAN",
+		"METHOD:" + event.Method,
+	}
+
+	if location != time.UTC {
+		lines = append(lines, iCalendarTimeZone(location, event.Start.In(location).Year())...)
+	}
+
+	status := "CONFIRMED"
+	if event.Method == "CANCEL" {
+		status = "CANCELLED"
+	}
+
+	lines = append(lines,
+		"BEGIN:VEVENT",
+		"UID:"+escapeICalendarText(event.UID),
+		fmt.Sprintf("SEQUENCE:%d", *event.Sequence),
+		"DTSTAMP:"+time.Now().UTC().Format(iCalendarDateTime)+"Z",
+		iCalendarDateProperty("DTSTART", *event.Start, location),
+		iCalendarDateProperty("DTEND", *event.End, location),
+		"SUMMARY:"+escapeICalendarText(event.Summary),
+	)
+
+	if event.Location != "" {
+		lines = append(lines, "LOCATION:"+escapeICalendarText(event.Location))
+	}
+
+	if event.Description != "" {
+		lines = append(lines, "DESCRIPTION:"+escapeICalendarText(event.Description))
+	}
+
+	lines = append(lines, fmt.Sprintf("ORGANIZER%s:mailto:%s", iCalendarName(event.Organizer.Name), event.Organizer.Mail))
+
+	for _, attendee := range event.Attendees {
+		role := attendee.Role
+		if role == "" {
+			role = "REQ-PARTICIPANT"
+		}
+
+		lines = append(lines, fmt.Sprintf(
+			"ATTENDEE%s;ROLE=%s;PARTSTAT=NEEDS-ACTION;RSVP=%s:mailto:%s",
+			iCalendarName(attendee.Name),
+			role,
+			strings.ToUpper(fmt.Sprintf("%t", attendee.Rsvp)),
+			attendee.Mail,
+		))
+	}
+
+	lines = append(lines,
+		"STATUS:"+status,
+		"TRANSP:OPAQUE",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	)
+
+	var calendar strings.Builder
+	for _, line := range lines {
+		calendar.WriteString(foldICalendarLine(line))
+	}
+
+	return calendar.String(), nil
+}
+
+// iCalendarDateProperty formats a date-time property in UTC or local time with a TZID.
+func iCalendarDateProperty(name string, value time.Time, location *time.Location) string {
+	if location == time.UTC {
+		return fmt.Sprintf("%s:%sZ", name, value.UTC().Format(iCalendarDateTime))
+	}
+
+	return fmt.Sprintf("%s;TZID=%s:%s", name, location.String(), value.In(location).Format(iCalendarDateTime))
+}
+
+// iCalendarTimeZone creates the VTIMEZONE component with the observances around the year of the event.
+// The observances are written without recurrence rules, from the year before until the year after the event.
+func iCalendarTimeZone(location *time.Location, year int) []string {
+	lines := []string{"BEGIN:VTIMEZONE", "TZID:" + location.String()}
+
+	from := time.Date(year-1, time.January, 1, 0, 0, 0, 0, location)
+	until := time.Date(year+2, time.January, 1, 0, 0, 0, 0, location)
+	_, offset := from.Zone()
+
+	transitions := 0
+	for day := from; day.Before(until); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		if _, nextOffset := next.Zone(); nextOffset == offset {
+			continue
+		}
+
+		// Search the exact moment of the transition.
+		low, high := day, next
+		for high.Sub(low) > time.Second {
+			middle := low.Add(high.Sub(low) / 2)
+			if _, middleOffset := middle.Zone(); middleOffset == offset {
+				low = middle
+			} else {
+				high = middle
+			}
+		}
+
+		_, nextOffset := high.Zone()
+		lines = append(lines, iCalendarObservance(high, offset, nextOffset)...)
+		offset = nextOffset
+		transitions++
+	}
+
+	if transitions == 0 {
+		lines = append(lines, iCalendarObservance(time.Date(1970, time.January, 1, 0, 0, 0, 0, location), offset, offset)...)
+	}
+
+	return append(lines, "END:VTIMEZONE")
+}
+
+// iCalendarObservance creates a STANDARD or DAYLIGHT observance that starts at the transition.
+func iCalendarObservance(transition time.Time, offsetFrom, offsetTo int) []string {
+	component := "STANDARD"
+	if transition.IsDST() {
+		component = "DAYLIGHT"
+	}
+
+	name, _ := transition.Zone()
+	start := transition.UTC().Add(time.Duration(offsetFrom) * time.Second)
+
+	return []string{
+		"BEGIN:" + component,
+		"DTSTART:" + start.Format(iCalendarDateTime),
+		"TZOFFSETFROM:" + iCalendarOffset(offsetFrom),
+		"TZOFFSETTO:" + iCalendarOffset(offsetTo),
+		"TZNAME:" + escapeICalendarText(name),
+		"END:" + component,
+	}
+}
+
+// iCalendarOffset formats an UTC offset in seconds as +HHMM.
+func iCalendarOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+
+	return fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset%3600/60)
+}
+
+// iCalendarName formats the CN parameter of a calendar user.
+func iCalendarName(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(";CN=%q", strings.ReplaceAll(name, `"`, "'"))
+}
+
+// escapeICalendarText escapes a TEXT value.
+func escapeICalendarText(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(text)
+}
+
+// foldICalendarLine folds a content line at 75 octets without splitting UTF-8 characters.
+func foldICalendarLine(line string) string {
+	var folded strings.Builder
+	limit := 75
+
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		folded.WriteString(line[:cut])
+		folded.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+
+	folded.WriteString(line)
+	folded.WriteString("\r\n")
+
+	return folded.String()
+}
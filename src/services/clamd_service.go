@@ -0,0 +1,140 @@
+package services
+
+import (
+	"api-mail/main/src/enums"
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultClamdTimeout = 30 * time.Second
+	clamdChunkSize      = 64 * 1024
+)
+
+// ClamdScanner scans with the INSTREAM command of a clamd daemon over TCP or a Unix socket.
+type ClamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamdScanner creates a clamd scanner for an address such as tcp://127.0.0.1:3310 or unix:///run/clamav/clamd.ctl.
+// The timeout is a duration such as 30s and covers one scan, empty is 30 seconds.
+func NewClamdScanner(address, timeout string) (*ClamdScanner, error) {
+	clamdURL, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("clamd address %s is invalid: %s", address, err.Error())
+	}
+
+	scanner := &ClamdScanner{network: clamdURL.Scheme, timeout: defaultClamdTimeout}
+	switch clamdURL.Scheme {
+	case "tcp":
+		scanner.address = clamdURL.Host
+	case "unix":
+		scanner.address = clamdURL.Path
+	default:
+		return nil, fmt.Errorf("clamd address %s must start with tcp:// or unix://", address)
+	}
+
+	if scanner.address == "" {
+		return nil, fmt.Errorf("clamd address %s has no host or path", address)
+	}
+
+	if timeout != "" {
+		if scanner.timeout, err = time.ParseDuration(timeout); err != nil {
+			return nil, fmt.Errorf("clamd timeout %s is invalid: %s", timeout, err.Error())
+		}
+	}
+
+	return scanner, nil
+}
+
+// Name identifies clamd in the recorded verdicts.
+func (s *ClamdScanner) Name() string {
+	return "clamd"
+}
+
+// Scan streams the content in chunks to clamd and reads its reply.
+// clamd replies "stream: OK" for clean content, "stream: <signature> FOUND" for malware and "<reason> ERROR" otherwise.
+func (s *ClamdScanner) Scan(ctx context.Context, content io.Reader) (AttachmentScan, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return AttachmentScan{}, fmt.Errorf("clamd error: %s", err.Error())
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return AttachmentScan{}, fmt.Errorf("clamd error: %s", err.Error())
+		}
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return AttachmentScan{}, fmt.Errorf("clamd error: %s", err.Error())
+	}
+
+	// Every chunk is prefixed with its length, a chunk of length 0 ends the stream.
+	chunk := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := io.ReadFull(content, chunk[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(chunk[:4], uint32(n))
+			if _, err := conn.Write(chunk[:4+n]); err != nil {
+				return AttachmentScan{}, fmt.Errorf("clamd error: %s", err.Error())
+			}
+		}
+
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		} else if err != nil {
+			return AttachmentScan{}, err
+		}
+	}
+
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return AttachmentScan{}, fmt.Errorf("clamd error: %s", err.Error())
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return AttachmentScan{}, fmt.Errorf("clamd error: %s", err.Error())
+	}
+
+	scan, err := parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+	if err != nil {
+		return AttachmentScan{}, err
+	}
+	scan.Scanner = s.Name()
+
+	return scan, nil
+}
+
+// parseClamdReply returns the verdict of an INSTREAM reply.
+func parseClamdReply(reply string) (AttachmentScan, error) {
+	scan := AttachmentScan{ScannedAt: time.Now()}
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+
+	switch {
+	case result == "OK":
+		scan.Verdict = enums.Clean
+	case strings.HasSuffix(result, " FOUND"):
+		scan.Verdict = enums.Infected
+		scan.Signature = strings.TrimSuffix(result, " FOUND")
+	default:
+		return AttachmentScan{}, fmt.Errorf("clamd error: %s", reply)
+	}
+
+	return scan, nil
+}
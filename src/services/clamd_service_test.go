@@ -0,0 +1,153 @@
+package services
+
+import (
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/enums"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// infectedContent is the content that the clamd stub reports as malware, a stand-in for the EICAR test file.
+const infectedContent = "CLAMD-STUB-TEST-SIGNATURE"
+
+// serveClamd runs a clamd stub that answers the INSTREAM command of each connection and sends the streamed content.
+func serveClamd(t *testing.T, listener net.Listener, streams chan<- []byte) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+
+			reader := bufio.NewReader(conn)
+			if command, err := reader.ReadString(0); err != nil || command != "zINSTREAM\x00" {
+				t.Errorf("command is %q: %v", command, err)
+				return
+			}
+
+			var content bytes.Buffer
+			for {
+				var size uint32
+				if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+					t.Error(err)
+					return
+				} else if size == 0 {
+					break
+				} else if size > clamdChunkSize {
+					t.Errorf("chunk of %d bytes is larger than %d", size, clamdChunkSize)
+				}
+
+				if _, err := io.CopyN(&content, reader, int64(size)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			streams <- content.Bytes()
+
+			switch {
+			case bytes.Contains(content.Bytes(), []byte(infectedContent)):
+				_, _ = conn.Write([]byte("stream: Win.Test.EICAR_HDB-1 FOUND\x00"))
+			case bytes.Equal(content.Bytes(), []byte("limit")):
+				_, _ = conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+			default:
+				_, _ = conn.Write([]byte("stream: OK\x00"))
+			}
+		}()
+	}
+}
+
+// newClamdStub starts a clamd stub on a local TCP port and returns a scanner for it.
+func newClamdStub(t *testing.T) (*ClamdScanner, <-chan []byte) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	streams := make(chan []byte, 10)
+	go serveClamd(t, listener, streams)
+
+	scanner, err := NewClamdScanner("tcp://"+listener.Addr().String(), "5s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return scanner, streams
+}
+
+func TestClamdScanner(t *testing.T) {
+	scanner, streams := newClamdStub(t)
+	large := bytes.Repeat([]byte("a"), 3*clamdChunkSize+10)
+
+	tests := []struct {
+		name      string
+		content   []byte
+		verdict   enums.ScanVerdict
+		signature string
+	}{
+		{"clean", []byte("clean"), enums.Clean, ""},
+		{"chunked", large, enums.Clean, ""},
+		{"empty", []byte{}, enums.Clean, ""},
+		{"infected", []byte(infectedContent), enums.Infected, "Win.Test.EICAR_HDB-1"},
+	}
+
+	for _, test := range tests {
+		scan, err := scanner.Scan(context.Background(), bytes.NewReader(test.content))
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if streamed := <-streams; !bytes.Equal(streamed, test.content) {
+			t.Errorf("%s: streamed %d bytes, want %d", test.name, len(streamed), len(test.content))
+		}
+		if scan.Verdict != test.verdict || scan.Signature != test.signature || scan.Scanner != "clamd" {
+			t.Errorf("%s: scan is %+v", test.name, scan)
+		}
+	}
+}
+
+func TestClamdScannerError(t *testing.T) {
+	scanner, _ := newClamdStub(t)
+
+	if _, err := scanner.Scan(context.Background(), strings.NewReader("limit")); err == nil || !strings.Contains(err.Error(), "size limit exceeded") {
+		t.Errorf("error is %v", err)
+	}
+}
+
+func TestScanAttachmentsWithClamd(t *testing.T) {
+	scanner, _ := newClamdStub(t)
+	SetAttachmentScanner(scanner)
+	t.Cleanup(func() { SetAttachmentScanner(nil) })
+
+	scans, err := ScanAttachments(context.Background(), []requests.SendMailAttachment{
+		{FileName: "clean.txt", FileData: []byte("clean")},
+		{FileName: "infected.com", FileData: []byte(infectedContent)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scans) != 2 || scans[0].Verdict != enums.Clean || scans[1].Verdict != enums.Infected {
+		t.Errorf("scans are %+v", scans)
+	}
+}
+
+func TestNewClamdScanner(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:3310", "http://127.0.0.1:3310", "tcp://", "unix://"} {
+		if _, err := NewClamdScanner(address, ""); err == nil {
+			t.Errorf("%s is accepted", address)
+		}
+	}
+
+	if _, err := NewClamdScanner("tcp://127.0.0.1:3310", "soon"); err == nil {
+		t.Error("timeout soon is accepted")
+	}
+}
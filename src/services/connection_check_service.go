@@ -0,0 +1,461 @@
+package services
+
+import (
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/enums"
+	"api-mail/main/src/models"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	netsmtp "net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// connectionCheck records the steps of a connection check.
+type connectionCheck struct {
+	models.ConnectionCheck
+}
+
+// smtpCheckSession is a SMTP session of a connection check that keeps the raw replies of the server.
+type smtpCheckSession struct {
+	conn       net.Conn
+	text       *textproto.Conn
+	extensions map[string]string
+}
+
+// CheckSmtpConnection connects, starts TLS and authenticates with the smtp, and sends a test mail when to is set.
+func CheckSmtpConnection(smtp *models.Smtp, to *string) *models.ConnectionCheck {
+	check := &connectionCheck{}
+
+	password := ""
+	if smtp.Auth() != enums.AuthNone {
+		if !check.run("decrypt password", func() (string, error) {
+			var err error
+			password, err = smtp.DecryptPassword()
+			return "", err
+		}) {
+			return &check.ConnectionCheck
+		}
+	}
+
+	var msg []byte
+	if to != nil && *to != "" {
+		if !check.run("compose test mail", func() (string, error) {
+			var err error
+			if msg, err = newTestMessage(smtp.AppMail.MailName, *to); err != nil {
+				return "", err
+			}
+
+			msg, err = SignDkimMessage(smtp, smtp.AppMail.AppName, msg)
+			return "", err
+		}) {
+			return &check.ConnectionCheck
+		}
+	}
+
+	check.smtp(smtp, password, smtp.AppMail.MailName, to, msg)
+
+	return &check.ConnectionCheck
+}
+
+// CheckGmailConnection refreshes the token of the gmail and looks up the profile of the mailbox.
+// With SMTP delivery the login on smtp.gmail.com is checked as well.
+func CheckGmailConnection(ctx context.Context, gmail *models.Gmail) *models.ConnectionCheck {
+	check := &connectionCheck{}
+
+	accessToken := ""
+	if !check.run("token refresh", func() (string, error) {
+		token, err := RefreshGmailToken(ctx, gmail, true)
+		if err != nil {
+			return "", err
+		}
+		accessToken = token.AccessToken
+
+		return fmt.Sprintf("Token valid until %s.", token.Expiry.Format(time.RFC3339)), nil
+	}) {
+		return &check.ConnectionCheck
+	}
+
+	check.run("profile lookup", func() (string, error) {
+		gmailService, err := getSendGmailService(ctx, gmail)
+		if err != nil {
+			return "", err
+		}
+
+		profile, err := gmailService.Users.GetProfile("me").Context(ctx).Do()
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("Mailbox %s with %d messages.", profile.EmailAddress, profile.MessagesTotal), nil
+	})
+
+	if gmail.SmtpDelivery {
+		check.smtp(newOauthSmtp(gmailSmtpHost, gmail.AppMail.MailName), accessToken, gmail.AppMail.MailName, nil, nil)
+	}
+
+	return &check.ConnectionCheck
+}
+
+// CheckAzureConnection refreshes the token of the azure and looks up the profile of the mailbox in Microsoft Graph.
+// With SMTP delivery the SMTP token and the login on the Exchange Online SMTP server of the cloud are checked as well.
+func CheckAzureConnection(ctx context.Context, azure *models.Azure) *models.ConnectionCheck {
+	check := &connectionCheck{}
+
+	if !check.run("token refresh", func() (string, error) {
+		token, err := RefreshAzureToken(ctx, azure, true)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("Token valid until %s.", token.Expiry.Format(time.RFC3339)), nil
+	}) {
+		return &check.ConnectionCheck
+	}
+
+	check.run("profile lookup", func() (string, error) {
+		client, err := getSendAzureClient(ctx, azure)
+		if err != nil {
+			return "", err
+		}
+
+		resp, err := client.Get(getAzureCloud(azure).graphURL("/me?$select=displayName,mail,userPrincipalName"))
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+
+		response := fmt.Sprintf("%s %s", resp.Status, body)
+		if resp.StatusCode != 200 {
+			return response, errors.New("the profile lookup failed")
+		}
+
+		return response, nil
+	})
+
+	if azure.SmtpDelivery {
+		accessToken := ""
+		if check.run("smtp token", func() (string, error) {
+			var err error
+			accessToken, err = GetAzureSmtpToken(ctx, azure)
+			return "", err
+		}) {
+			check.smtp(newOauthSmtp(getAzureCloud(azure).smtpHost, azure.AppMail.MailName), accessToken, azure.AppMail.MailName, nil, nil)
+		}
+	}
+
+	return &check.ConnectionCheck
+}
+
+// run runs and times a step, and returns if it succeeded.
+func (check *connectionCheck) run(name string, step func() (string, error)) bool {
+	start := time.Now()
+	response, err := step()
+
+	result := models.ConnectionCheckStep{
+		Name:     name,
+		Success:  err == nil,
+		Duration: time.Since(start),
+		Response: response,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	check.Steps = append(check.Steps, result)
+
+	return err == nil
+}
+
+// smtp runs the SMTP steps: connect, greeting, EHLO, STARTTLS, AUTH, the optional test mail and QUIT.
+func (check *connectionCheck) smtp(smtp *models.Smtp, password, from string, to *string, msg []byte) {
+	session := &smtpCheckSession{}
+	defer func() {
+		if session.conn != nil {
+			_ = session.conn.Close()
+		}
+	}()
+
+	encryption := smtp.Encryption()
+	connectTimeout := smtpTimeout(smtp.ConnectTimeout, defaultSmtpConnectTimeout)
+
+	tlsConfig, err := newSmtpTlsConfig(smtp)
+	if err != nil {
+		check.run("connect", func() (string, error) { return "", err })
+		return
+	}
+
+	if !check.run("connect", func() (string, error) {
+		address := net.JoinHostPort(smtp.Host, strconv.Itoa(smtp.Port))
+		dialer := &net.Dialer{Timeout: connectTimeout}
+
+		if encryption == enums.EncryptionSSLTLS {
+			conn, err := tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+			if err != nil {
+				return "", err
+			}
+			session.conn = conn
+
+			return fmt.Sprintf("Connected to %s. %s", conn.RemoteAddr(), describeTlsState(conn.ConnectionState())), nil
+		}
+
+		conn, err := dialer.Dial("tcp", address)
+		if err != nil {
+			return "", err
+		}
+		session.conn = conn
+
+		return fmt.Sprintf("Connected to %s.", conn.RemoteAddr()), nil
+	}) {
+		return
+	}
+
+	_ = session.conn.SetDeadline(time.Now().Add(connectTimeout))
+	session.text = textproto.NewConn(session.conn)
+
+	if !check.run("greeting", func() (string, error) {
+		code, message, err := session.text.ReadResponse(220)
+		return formatSmtpReply(code, message, err), err
+	}) {
+		return
+	}
+
+	heloName := defaultSmtpHeloName
+	if smtp.HeloName != nil && *smtp.HeloName != "" {
+		heloName = *smtp.HeloName
+	}
+
+	if !check.run("ehlo", func() (string, error) { return session.ehlo(heloName) }) {
+		return
+	}
+
+	if encryption == enums.EncryptionSTARTTLS {
+		if !check.run("starttls", func() (string, error) {
+			if _, ok := session.extensions["STARTTLS"]; !ok {
+				return "", errors.New("the server does not support STARTTLS")
+			}
+
+			reply, err := session.cmd(220, "STARTTLS")
+			if err != nil {
+				return reply, err
+			}
+
+			conn := tls.Client(session.conn, tlsConfig)
+			if err := conn.Handshake(); err != nil {
+				return reply, err
+			}
+			session.conn = conn
+			session.text = textproto.NewConn(conn)
+
+			return reply + "\n" + describeTlsState(conn.ConnectionState()), nil
+		}) {
+			return
+		}
+
+		if !check.run("ehlo", func() (string, error) { return session.ehlo(heloName) }) {
+			return
+		}
+	}
+
+	if mechanism := smtp.Auth(); mechanism != enums.AuthNone {
+		if !check.run("auth "+strings.ToLower(string(mechanism)), func() (string, error) {
+			return session.auth(newSmtpAuth(mechanism, smtp.Username, password, smtp.Host, encryption == enums.EncryptionNone), smtp.Host)
+		}) {
+			return
+		}
+	}
+
+	if to != nil && *to != "" {
+		_ = session.conn.SetDeadline(time.Now().Add(smtpTimeout(smtp.SendTimeout, defaultSmtpSendTimeout)))
+
+		if !check.run("mail from", func() (string, error) { return session.cmd(250, "MAIL FROM:<%s>", from) }) {
+			return
+		}
+
+		if !check.run("rcpt to", func() (string, error) { return session.cmd(25, "RCPT TO:<%s>", *to) }) {
+			return
+		}
+
+		if !check.run("data", func() (string, error) { return session.data(msg) }) {
+			return
+		}
+	}
+
+	check.run("quit", func() (string, error) { return session.cmd(221, "QUIT") })
+}
+
+// cmd sends a command and returns the reply of the server.
+func (session *smtpCheckSession) cmd(expectCode int, format string, args ...any) (string, error) {
+	id, err := session.text.Cmd(format, args...)
+	if err != nil {
+		return "", err
+	}
+
+	session.text.StartResponse(id)
+	defer session.text.EndResponse(id)
+
+	code, message, err := session.text.ReadResponse(expectCode)
+
+	return formatSmtpReply(code, message, err), err
+}
+
+// ehlo greets the server and reads the supported extensions.
+func (session *smtpCheckSession) ehlo(heloName string) (string, error) {
+	reply, err := session.cmd(250, "EHLO %s", heloName)
+	if err != nil {
+		return reply, err
+	}
+
+	session.extensions = make(map[string]string)
+	lines := strings.Split(reply, "\n")
+	for _, line := range lines[1:] {
+		if len(line) < 4 {
+			continue
+		}
+
+		keyword, parameters, _ := strings.Cut(line[4:], " ")
+		session.extensions[strings.ToUpper(keyword)] = parameters
+	}
+
+	return reply, nil
+}
+
+// auth runs the SASL exchange like net/smtp, but keeps the replies of the server.
+func (session *smtpCheckSession) auth(auth netsmtp.Auth, host string) (string, error) {
+	mechanisms, ok := session.extensions["AUTH"]
+	if !ok {
+		return "", ErrSmtpAuthNotAdvertised
+	}
+
+	_, isTls := session.conn.(*tls.Conn)
+	mechanism, response, err := auth.Start(&netsmtp.ServerInfo{Name: host, TLS: isTls, Auth: strings.Fields(mechanisms)})
+	if err != nil {
+		return "", err
+	}
+
+	replies := make([]string, 0, 3)
+	reply, err := session.cmd(0, "%s", strings.TrimSpace("AUTH "+mechanism+" "+base64.StdEncoding.EncodeToString(response)))
+	for {
+		replies = append(replies, reply)
+		if err != nil {
+			return strings.Join(replies, "\n"), err
+		}
+
+		code, message := parseSmtpReply(reply)
+		var challenge []byte
+		switch code {
+		case 334:
+			if challenge, err = base64.StdEncoding.DecodeString(message); err != nil {
+				return strings.Join(replies, "\n"), err
+			}
+			// The decoded challenge often explains an XOAUTH2 failure.
+			replies[len(replies)-1] = reply + " (" + string(challenge) + ")"
+		case 235:
+			return strings.Join(replies, "\n"), nil
+		default:
+			if !hasSmtpMechanism(mechanisms, enums.SmtpAuth(mechanism)) {
+				return strings.Join(replies, "\n"), fmt.Errorf("authentication failed, the server advertises %s", mechanisms)
+			}
+
+			return strings.Join(replies, "\n"), errors.New("authentication failed")
+		}
+
+		if response, err = auth.Next(challenge, true); err != nil {
+			_, _ = session.cmd(0, "*")
+			return strings.Join(replies, "\n"), err
+		}
+
+		reply, err = session.cmd(0, "%s", base64.StdEncoding.EncodeToString(response))
+	}
+}
+
+// data sends the test mail.
+func (session *smtpCheckSession) data(msg []byte) (string, error) {
+	reply, err := session.cmd(354, "DATA")
+	if err != nil {
+		return reply, err
+	}
+
+	writer := session.text.DotWriter()
+	if _, err := writer.Write(msg); err != nil {
+		return reply, err
+	}
+	if err := writer.Close(); err != nil {
+		return reply, err
+	}
+
+	code, message, err := session.text.ReadResponse(250)
+
+	return reply + "\n" + formatSmtpReply(code, message, err), err
+}
+
+// newTestMessage creates the test mail of a connection check.
+func newTestMessage(from, to string) ([]byte, error) {
+	message := newMimeMessage(&requests.SendMail{
+		FromMail: from,
+		To:       to,
+		Subject:  "Test mail",
+		Body:     fmt.Sprintf("This is a test mail of the connection test of %s.", from),
+		MimeType: "text/plain",
+	})
+
+	return message.Bytes()
+}
+
+// formatSmtpReply formats a reply as the server sent it, with the code before every line.
+func formatSmtpReply(code int, message string, err error) string {
+	var protocolError *textproto.Error
+	if errors.As(err, &protocolError) {
+		code, message = protocolError.Code, protocolError.Msg
+	} else if code == 0 {
+		return ""
+	}
+
+	lines := strings.Split(message, "\n")
+	for i := range lines {
+		separator := "-"
+		if i == len(lines)-1 {
+			separator = " "
+		}
+		lines[i] = strconv.Itoa(code) + separator + lines[i]
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// parseSmtpReply returns the code and the text of the last line of a formatted reply.
+func parseSmtpReply(reply string) (int, string) {
+	lines := strings.Split(reply, "\n")
+	last := lines[len(lines)-1]
+	if len(last) < 4 {
+		return 0, last
+	}
+
+	code, _ := strconv.Atoi(last[:3])
+
+	return code, last[4:]
+}
+
+// describeTlsState describes the negotiated TLS version and cipher, and the certificate of the server.
+func describeTlsState(state tls.ConnectionState) string {
+	description := fmt.Sprintf("%s with %s.", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	if len(state.PeerCertificates) > 0 {
+		certificate := state.PeerCertificates[0]
+		description += fmt.Sprintf(" Certificate %s issued by %s, valid until %s.",
+			certificate.Subject.String(), certificate.Issuer.String(), certificate.NotAfter.Format(time.RFC3339))
+	}
+
+	return description
+}
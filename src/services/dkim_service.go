@@ -0,0 +1,334 @@
+package services
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/enums"
+	"api-mail/main/src/models"
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"github.com/emersion/go-msgauth/dkim"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// defaultDkimHeaders are the signed headers when the smtp has no own list (RFC 6376, section 5.4.1).
+var defaultDkimHeaders = []string{
+	"From", "Reply-To", "Subject", "Date", "To", "Cc", "Message-ID",
+	"MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+}
+
+// IsDkimSelectorAvailable checks if the selector is already used by a key of the domain.
+func IsDkimSelectorAvailable(app, domain, selector string) (bool, error) {
+	var count int64
+	if result := database.Pg.Model(&models.DkimKey{}).
+		Where("app_name = ? AND LOWER(domain) = LOWER(?) AND selector = ?", app, domain, selector).
+		Count(&count); result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+// IsValidDkimHeaders checks if the headers to sign are valid, the From header must always be signed.
+func IsValidDkimHeaders(headers []string) bool {
+	if len(headers) == 0 {
+		return true
+	}
+
+	hasFrom := false
+	for _, header := range headers {
+		header = strings.TrimSpace(header)
+		if header == "" || strings.ContainsAny(header, ",: ") {
+			return false
+		}
+		if strings.EqualFold(header, "From") {
+			hasFrom = true
+		}
+	}
+
+	return hasFrom
+}
+
+// GetDkimKey gets the DKIM key.
+func GetDkimKey(id uint, unscoped ...bool) (*models.DkimKey, error) {
+	dkimKey := &models.DkimKey{}
+	query := database.Pg
+
+	if len(unscoped) > 0 && unscoped[0] {
+		query = query.Unscoped()
+	}
+
+	if result := query.Find(dkimKey, "id = ?", id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return dkimKey, nil
+}
+
+// GetActiveDkimKey gets the active DKIM key of the domain.
+func GetActiveDkimKey(app, domain string) (*models.DkimKey, error) {
+	dkimKey := &models.DkimKey{}
+
+	if result := database.Pg.
+		Where("app_name = ? AND LOWER(domain) = LOWER(?) AND active = ?", app, domain, true).
+		Order("updated_at DESC").
+		Limit(1).
+		Find(dkimKey); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return dkimKey, nil
+}
+
+// GenerateDkimKey generates a new RSA or Ed25519 DKIM key for the domain.
+func GenerateDkimKey(req *requests.CreateDkimKey) (*models.DkimKey, error) {
+	algorithm := enums.ToDkimAlgorithm(req.Algorithm)
+
+	var privateKey crypto.Signer
+	switch algorithm {
+	case enums.Ed25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		privateKey = key
+	default:
+		bits := req.Bits
+		if bits == 0 {
+			bits = 2048
+		}
+
+		key, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, err
+		}
+		privateKey = key
+	}
+
+	privateDer, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := dkimPublicKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	dkimKey := &models.DkimKey{
+		AppName:           req.App,
+		Domain:            strings.ToLower(req.Domain),
+		Selector:          req.Selector,
+		DkimAlgorithmName: string(algorithm),
+		PrivateKey:        string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDer})),
+		PublicKey:         publicKey,
+	}
+
+	if err := dkimKey.EncryptPrivateKey(); err != nil {
+		return nil, err
+	}
+
+	if result := database.Pg.Create(dkimKey); result.Error != nil {
+		return nil, result.Error
+	}
+
+	if req.Active {
+		if err := ActivateDkimKey(dkimKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return dkimKey, nil
+}
+
+// ActivateDkimKey makes the key the signing key of its domain and deactivates the other keys of the domain.
+// The DNS record of the key must be published before, the records of the old keys can be removed after.
+func ActivateDkimKey(dkimKey *models.DkimKey) error {
+	return database.Pg.Transaction(func(tx *gorm.DB) error {
+		if result := tx.Model(&models.DkimKey{}).
+			Where("app_name = ? AND domain = ? AND id <> ?", dkimKey.AppName, dkimKey.Domain, dkimKey.ID).
+			Update("active", false); result.Error != nil {
+			return result.Error
+		}
+
+		dkimKey.Active = true
+		if result := tx.Save(dkimKey); result.Error != nil {
+			return result.Error
+		}
+
+		return nil
+	})
+}
+
+// DeleteDkimKey deletes a existing DKIM key.
+func DeleteDkimKey(dkimKey *models.DkimKey) error {
+	if result := database.Pg.Delete(dkimKey); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// RestoreDkimKey restores a deleted DKIM key, it is restored inactive.
+func RestoreDkimKey(dkimKey *models.DkimKey) error {
+	if result := database.Pg.Model(&dkimKey).Unscoped().Updates(map[string]interface{}{
+		"deleted_at": nil,
+		"active":     false,
+	}); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// SignDkimMessage adds the DKIM-Signature header to the message when the smtp has a DKIM key.
+func SignDkimMessage(smtp *models.Smtp, app string, msg []byte) ([]byte, error) {
+	signer, selector, err := GetDkimSigner(smtp, app)
+	if err != nil {
+		return nil, err
+	} else if signer == nil {
+		return msg, nil
+	}
+
+	var canonicalization dkim.Canonicalization = dkim.CanonicalizationRelaxed
+	if smtp.DkimCanonicalizationName != nil && enums.ToDkimCanonicalization(*smtp.DkimCanonicalizationName) == enums.Simple {
+		canonicalization = dkim.CanonicalizationSimple
+	}
+
+	headers := defaultDkimHeaders
+	if smtp.DkimHeaders != nil && *smtp.DkimHeaders != "" {
+		headers = strings.Split(*smtp.DkimHeaders, ",")
+	}
+
+	options := &dkim.SignOptions{
+		Domain:                 *smtp.DkimDomain,
+		Selector:               selector,
+		Signer:                 signer,
+		Hash:                   crypto.SHA256,
+		HeaderCanonicalization: canonicalization,
+		BodyCanonicalization:   canonicalization,
+		HeaderKeys:             headers,
+		Expiration:             time.Now().Add(time.Hour),
+	}
+
+	var signed bytes.Buffer
+	if err := dkim.Sign(&signed, bytes.NewReader(msg), options); err != nil {
+		return nil, err
+	}
+
+	return signed.Bytes(), nil
+}
+
+// GetDkimSigner gets the signing key and selector of the smtp, the signer is nil when the smtp has no key.
+// An own private key of the smtp is used before the active generated key of the domain.
+func GetDkimSigner(smtp *models.Smtp, app string) (crypto.Signer, string, error) {
+	if smtp.DkimDomain == nil || *smtp.DkimDomain == "" {
+		return nil, "", nil
+	}
+
+	if smtp.DkimPrivateKey != nil && *smtp.DkimPrivateKey != "" {
+		signer, err := ParseDkimPrivateKey(*smtp.DkimPrivateKey)
+		if err != nil {
+			return nil, "", err
+		}
+
+		selector := "default"
+		if smtp.DkimSelector != nil && *smtp.DkimSelector != "" {
+			selector = *smtp.DkimSelector
+		}
+
+		return signer, selector, nil
+	}
+
+	dkimKey, err := GetActiveDkimKey(app, *smtp.DkimDomain)
+	if err != nil {
+		return nil, "", err
+	} else if dkimKey.ID == 0 {
+		return nil, "", nil
+	}
+
+	privateKey, err := dkimKey.DecryptPrivateKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	signer, err := ParseDkimPrivateKey(privateKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return signer, dkimKey.Selector, nil
+}
+
+// ParseDkimPrivateKey parses a PEM encoded RSA or Ed25519 private key.
+func ParseDkimPrivateKey(privateKey string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, errors.New("no PEM private key found")
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typed := key.(type) {
+	case *rsa.PrivateKey:
+		return typed, nil
+	case ed25519.PrivateKey:
+		return typed, nil
+	default:
+		return nil, errors.New("unsupported DKIM private key type")
+	}
+}
+
+// joinDkimHeaders joins the headers to sign for storage, no headers means the default list.
+func joinDkimHeaders(headers []string) *string {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	trimmed := make([]string, len(headers))
+	for i := range headers {
+		trimmed[i] = strings.TrimSpace(headers[i])
+	}
+
+	joined := strings.Join(trimmed, ",")
+
+	return &joined
+}
+
+// dkimPublicKey returns the base64 public key as published in the p= tag.
+// RSA keys are published as SubjectPublicKeyInfo, Ed25519 keys as the raw key (RFC 8463).
+func dkimPublicKey(privateKey crypto.Signer) (string, error) {
+	switch typed := privateKey.Public().(type) {
+	case ed25519.PublicKey:
+		return base64.StdEncoding.EncodeToString(typed), nil
+	case *rsa.PublicKey:
+		der, err := x509.MarshalPKIXPublicKey(typed)
+		if err != nil {
+			return "", err
+		}
+
+		return base64.StdEncoding.EncodeToString(der), nil
+	default:
+		return "", errors.New("unsupported DKIM public key type")
+	}
+}
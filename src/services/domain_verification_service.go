@@ -0,0 +1,308 @@
+package services
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/enums"
+	"api-mail/main/src/models"
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/net/publicsuffix"
+	"net"
+	"strings"
+	"time"
+)
+
+// DnsResolver looks up the TXT records of a name, it is replaced to check domains without network.
+type DnsResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Resolver is the resolver of the domain verification.
+var Resolver DnsResolver = net.DefaultResolver
+
+// dnsCheck is the result of a single DNS check.
+type dnsCheck struct {
+	status    enums.VerificationStatus
+	diagnosis string
+}
+
+// GetDomainVerification gets the last domain verification of the smtp.
+func GetDomainVerification(smtpID uint) (*models.DomainVerification, error) {
+	verification := &models.DomainVerification{}
+
+	if result := database.Pg.Find(verification, "smtp_id = ?", smtpID); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return verification, nil
+}
+
+// GetSmtpIDsWithDkimDomain gets the ids of all smtps with a DKIM domain.
+func GetSmtpIDsWithDkimDomain() ([]uint, error) {
+	smtpIDs := make([]uint, 0)
+
+	if result := database.Pg.Model(&models.Smtp{}).
+		Where("dkim_domain IS NOT NULL AND dkim_domain <> ''").
+		Pluck("id", &smtpIDs); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return smtpIDs, nil
+}
+
+// VerifySmtpDomain checks the DKIM record of the smtp against its key, and the SPF and DMARC policy of the From domain.
+// The result is stored as the last domain verification of the smtp.
+func VerifySmtpDomain(ctx context.Context, smtp *models.Smtp) (*models.DomainVerification, error) {
+	if smtp.DkimDomain == nil || *smtp.DkimDomain == "" {
+		return nil, errors.New("the smtp has no DKIM domain")
+	}
+
+	verification, err := GetDomainVerification(smtp.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	fromDomain := smtp.AppMail.MailName
+	if _, host, found := strings.Cut(fromDomain, "@"); found {
+		fromDomain = host
+	}
+	fromDomain = strings.ToLower(fromDomain)
+	dkimDomain := strings.ToLower(*smtp.DkimDomain)
+
+	signer, selector, err := GetDkimSigner(smtp, smtp.AppMail.AppName)
+	if err != nil {
+		return nil, err
+	}
+
+	var dkimResult dnsCheck
+	if signer == nil {
+		dkimResult = dnsCheck{enums.Fail, "The smtp has no DKIM private key and the domain has no active DKIM key."}
+	} else {
+		publicKey, err := dkimPublicKey(signer)
+		if err != nil {
+			return nil, err
+		}
+
+		dkimResult = checkDkimRecord(ctx, DkimRecordName(selector, dkimDomain), publicKey)
+	}
+
+	if dkimResult.status == enums.Pass && !isAlignedDomain(dkimDomain, fromDomain) {
+		dkimResult = dnsCheck{enums.Warning, fmt.Sprintf("%s The DKIM domain %s is not aligned with the From domain %s, so it does not count for DMARC.", dkimResult.diagnosis, dkimDomain, fromDomain)}
+	}
+
+	spfResult := checkSpfRecord(ctx, fromDomain)
+	dmarcResult := checkDmarcRecord(ctx, fromDomain)
+
+	verification.SmtpID = smtp.ID
+	verification.FromDomain = fromDomain
+	verification.DkimDomain = dkimDomain
+	verification.DkimSelector = selector
+	verification.DkimStatus = string(dkimResult.status)
+	verification.DkimDiagnosis = dkimResult.diagnosis
+	verification.SpfStatus = string(spfResult.status)
+	verification.SpfDiagnosis = spfResult.diagnosis
+	verification.DmarcStatus = string(dmarcResult.status)
+	verification.DmarcDiagnosis = dmarcResult.diagnosis
+	verification.Valid = dkimResult.status != enums.Fail && spfResult.status != enums.Fail && dmarcResult.status != enums.Fail
+	verification.CheckedAt = time.Now()
+
+	if result := database.Pg.Omit("Smtp").Save(verification); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return verification, nil
+}
+
+// DkimRecordName returns the DNS name of the DKIM TXT record of the selector.
+func DkimRecordName(selector, domain string) string {
+	return fmt.Sprintf("%s._domainkey.%s", selector, domain)
+}
+
+// checkDkimRecord checks that the published DKIM record contains the public key of the private key.
+func checkDkimRecord(ctx context.Context, name, publicKey string) dnsCheck {
+	records, err := lookupTXT(ctx, name)
+	if err != nil {
+		return dnsCheck{enums.Fail, fmt.Sprintf("The DKIM record %s could not be resolved: %s.", name, err.Error())}
+	}
+
+	dkimRecords := make([]map[string]string, 0, len(records))
+	for _, record := range records {
+		tags := parseTagList(record)
+		if version, ok := tags["v"]; ok && version != "DKIM1" {
+			continue
+		}
+		if _, ok := tags["p"]; ok {
+			dkimRecords = append(dkimRecords, tags)
+		}
+	}
+
+	switch len(dkimRecords) {
+	case 0:
+		return dnsCheck{enums.Fail, fmt.Sprintf("No DKIM record found at %s.", name)}
+	case 1:
+	default:
+		return dnsCheck{enums.Fail, fmt.Sprintf("Multiple DKIM records found at %s, receivers can't choose between them.", name)}
+	}
+
+	tags := dkimRecords[0]
+	published := strings.Join(strings.Fields(tags["p"]), "")
+	if published == "" {
+		return dnsCheck{enums.Fail, fmt.Sprintf("The DKIM record at %s has an empty p= tag, the key is revoked.", name)}
+	}
+
+	if published != publicKey {
+		return dnsCheck{enums.Fail, fmt.Sprintf("The public key in the DKIM record at %s does not match the private key of the smtp.", name)}
+	}
+
+	if flags, ok := tags["t"]; ok && strings.Contains(flags, "y") {
+		return dnsCheck{enums.Warning, fmt.Sprintf("The DKIM record at %s matches, but is in test mode (t=y).", name)}
+	}
+
+	return dnsCheck{enums.Pass, fmt.Sprintf("The DKIM record at %s matches the private key.", name)}
+}
+
+// checkSpfRecord checks that the domain has a single SPF record with a restrictive policy.
+func checkSpfRecord(ctx context.Context, domain string) dnsCheck {
+	records, err := lookupTXT(ctx, domain)
+	if err != nil {
+		return dnsCheck{enums.Fail, fmt.Sprintf("The SPF record of %s could not be resolved: %s.", domain, err.Error())}
+	}
+
+	spfRecords := make([]string, 0, 1)
+	for _, record := range records {
+		if strings.EqualFold(record, "v=spf1") || strings.HasPrefix(strings.ToLower(record), "v=spf1 ") {
+			spfRecords = append(spfRecords, record)
+		}
+	}
+
+	switch len(spfRecords) {
+	case 0:
+		return dnsCheck{enums.Fail, fmt.Sprintf("No SPF record found for %s.", domain)}
+	case 1:
+	default:
+		return dnsCheck{enums.Fail, fmt.Sprintf("Multiple SPF records found for %s, which is a permanent error for receivers.", domain)}
+	}
+
+	lookups := 0
+	all := ""
+	redirect := false
+	for _, term := range strings.Fields(spfRecords[0])[1:] {
+		term = strings.ToLower(term)
+		mechanism := strings.TrimLeft(term, "+-~?")
+		name, _, _ := strings.Cut(mechanism, ":")
+		name, _, _ = strings.Cut(name, "/")
+
+		switch {
+		case name == "include" || name == "a" || name == "mx" || name == "ptr" || name == "exists":
+			lookups++
+		case strings.HasPrefix(mechanism, "redirect="):
+			lookups++
+			redirect = true
+		case name == "all":
+			all = term
+		}
+	}
+
+	if lookups > 10 {
+		return dnsCheck{enums.Fail, fmt.Sprintf("The SPF record of %s needs %d DNS lookups, more than the limit of 10.", domain, lookups)}
+	}
+
+	switch all {
+	case "-all", "~all":
+		return dnsCheck{enums.Pass, fmt.Sprintf("The SPF record of %s ends with %s.", domain, all)}
+	case "+all", "all":
+		return dnsCheck{enums.Fail, fmt.Sprintf("The SPF record of %s ends with %s, which allows every server to send for the domain.", domain, all)}
+	case "?all":
+		return dnsCheck{enums.Warning, fmt.Sprintf("The SPF record of %s ends with ?all, which is neutral for servers that are not listed.", domain)}
+	}
+
+	if redirect {
+		return dnsCheck{enums.Pass, fmt.Sprintf("The SPF record of %s redirects to another policy.", domain)}
+	}
+
+	return dnsCheck{enums.Warning, fmt.Sprintf("The SPF record of %s has no all mechanism, so servers that are not listed are neutral.", domain)}
+}
+
+// checkDmarcRecord checks the DMARC policy of the domain, or of its organizational domain when the domain has none.
+func checkDmarcRecord(ctx context.Context, domain string) dnsCheck {
+	names := []string{"_dmarc." + domain}
+	if organizational := organizationalDomain(domain); organizational != domain {
+		names = append(names, "_dmarc."+organizational)
+	}
+
+	for _, name := range names {
+		records, err := lookupTXT(ctx, name)
+		if err != nil {
+			return dnsCheck{enums.Fail, fmt.Sprintf("The DMARC record %s could not be resolved: %s.", name, err.Error())}
+		}
+
+		dmarcRecords := make([]map[string]string, 0, 1)
+		for _, record := range records {
+			if tags := parseTagList(record); tags["v"] == "DMARC1" {
+				dmarcRecords = append(dmarcRecords, tags)
+			}
+		}
+
+		if len(dmarcRecords) == 0 {
+			continue
+		} else if len(dmarcRecords) > 1 {
+			return dnsCheck{enums.Fail, fmt.Sprintf("Multiple DMARC records found at %s, receivers ignore them all.", name)}
+		}
+
+		policy := strings.ToLower(dmarcRecords[0]["p"])
+		switch policy {
+		case "reject", "quarantine":
+			return dnsCheck{enums.Pass, fmt.Sprintf("The DMARC record at %s has policy %s.", name, policy)}
+		case "none":
+			return dnsCheck{enums.Warning, fmt.Sprintf("The DMARC record at %s has policy none, failing mail is only reported.", name)}
+		default:
+			return dnsCheck{enums.Fail, fmt.Sprintf("The DMARC record at %s has no valid p= policy.", name)}
+		}
+	}
+
+	return dnsCheck{enums.Fail, fmt.Sprintf("No DMARC record found at %s.", strings.Join(names, " or "))}
+}
+
+// lookupTXT looks up the TXT records, a name that does not exist has no records.
+func lookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, err := Resolver.LookupTXT(ctx, name)
+	if err != nil {
+		var dnsError *net.DNSError
+		if errors.As(err, &dnsError) && dnsError.IsNotFound {
+			return []string{}, nil
+		}
+
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// parseTagList parses a DKIM or DMARC tag=value list.
+func parseTagList(record string) map[string]string {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(record, ";") {
+		if key, value, found := strings.Cut(part, "="); found {
+			tags[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		}
+	}
+
+	return tags
+}
+
+// isAlignedDomain checks the relaxed alignment of the DKIM domain with the From domain, they have the same organizational domain.
+func isAlignedDomain(dkimDomain, fromDomain string) bool {
+	return organizationalDomain(dkimDomain) == organizationalDomain(fromDomain)
+}
+
+// organizationalDomain returns the registered domain below the public suffix, such as example.co.uk for mail.example.co.uk.
+// The domain itself is returned when it has no registered domain.
+func organizationalDomain(domain string) string {
+	organizational, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return domain
+	}
+
+	return organizational
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"api-mail/main/src/enums"
+	"context"
+	"net"
+	"testing"
+)
+
+// fakeResolver resolves the TXT records from a map, a missing name does not exist.
+type fakeResolver map[string][]string
+
+func (resolver fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if records, ok := resolver[name]; ok {
+		return records, nil
+	}
+
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func useResolver(t *testing.T, resolver DnsResolver) {
+	previous := Resolver
+	Resolver = resolver
+	t.Cleanup(func() { Resolver = previous })
+}
+
+func TestCheckDkimRecord(t *testing.T) {
+	useResolver(t, fakeResolver{
+		"match._domainkey.example.com":    {"v=DKIM1; k=rsa; p=KEY"},
+		"test._domainkey.example.com":     {"v=DKIM1; t=y; p=KEY"},
+		"other._domainkey.example.com":    {"v=DKIM1; p=OTHER"},
+		"revoked._domainkey.example.com":  {"v=DKIM1; p="},
+		"multiple._domainkey.example.com": {"v=DKIM1; p=KEY", "v=DKIM1; p=OTHER"},
+	})
+
+	tests := map[string]enums.VerificationStatus{
+		"match._domainkey.example.com":    enums.Pass,
+		"test._domainkey.example.com":     enums.Warning,
+		"other._domainkey.example.com":    enums.Fail,
+		"revoked._domainkey.example.com":  enums.Fail,
+		"multiple._domainkey.example.com": enums.Fail,
+		"missing._domainkey.example.com":  enums.Fail,
+	}
+
+	for name, want := range tests {
+		if got := checkDkimRecord(context.Background(), name, "KEY"); got.status != want {
+			t.Errorf("%s: status is %s, want %s: %s", name, got.status, want, got.diagnosis)
+		}
+	}
+}
+
+func TestCheckSpfRecord(t *testing.T) {
+	useResolver(t, fakeResolver{
+		"fail.example.com":     {"v=spf1 include:_spf.example.net -all"},
+		"softfail.example.com": {"google-site-verification=x", "v=spf1 mx ~all"},
+		"neutral.example.com":  {"v=spf1 mx ?all"},
+		"pass.example.com":     {"v=spf1 +all"},
+		"redirect.example.com": {"v=spf1 redirect=_spf.example.net"},
+		"multiple.example.com": {"v=spf1 -all", "v=spf1 ~all"},
+		"lookups.example.com":  {"v=spf1 a mx include:a include:b include:c include:d include:e include:f include:g include:h include:i -all"},
+	})
+
+	tests := map[string]enums.VerificationStatus{
+		"fail.example.com":     enums.Pass,
+		"softfail.example.com": enums.Pass,
+		"neutral.example.com":  enums.Warning,
+		"pass.example.com":     enums.Fail,
+		"redirect.example.com": enums.Pass,
+		"multiple.example.com": enums.Fail,
+		"lookups.example.com":  enums.Fail,
+		"missing.example.com":  enums.Fail,
+	}
+
+	for domain, want := range tests {
+		if got := checkSpfRecord(context.Background(), domain); got.status != want {
+			t.Errorf("%s: status is %s, want %s: %s", domain, got.status, want, got.diagnosis)
+		}
+	}
+}
+
+func TestCheckDmarcRecord(t *testing.T) {
+	useResolver(t, fakeResolver{
+		"_dmarc.example.com":       {"v=DMARC1; p=reject"},
+		"_dmarc.none.example.com":  {"v=DMARC1; p=none"},
+		"_dmarc.example.co.uk":     {"v=DMARC1; p=quarantine"},
+		"_dmarc.co.uk":             {"v=DMARC1; p=reject"},
+		"_dmarc.multiple.example":  {"v=DMARC1; p=reject", "v=DMARC1; p=none"},
+		"_dmarc.invalid.example":   {"v=DMARC1; p=block"},
+		"_dmarc.unrelated.example": {"v=spf1 -all"},
+	})
+
+	tests := map[string]enums.VerificationStatus{
+		"example.com":            enums.Pass,
+		"mail.example.com":       enums.Pass,
+		"none.example.com":       enums.Warning,
+		"mail.example.co.uk":     enums.Pass,
+		"mail.other.co.uk":       enums.Fail,
+		"multiple.example":       enums.Fail,
+		"invalid.example":        enums.Fail,
+		"unrelated.example":      enums.Fail,
+		"mail.unrelated.example": enums.Fail,
+	}
+
+	for domain, want := range tests {
+		if got := checkDmarcRecord(context.Background(), domain); got.status != want {
+			t.Errorf("%s: status is %s, want %s: %s", domain, got.status, want, got.diagnosis)
+		}
+	}
+}
+
+func TestIsAlignedDomain(t *testing.T) {
+	tests := []struct {
+		dkimDomain string
+		fromDomain string
+		want       bool
+	}{
+		{"example.com", "example.com", true},
+		{"example.com", "mail.example.com", true},
+		{"mail.example.com", "news.example.com", true},
+		{"example.co.uk", "mail.example.co.uk", true},
+		{"example.co.uk", "other.co.uk", false},
+		{"example.com", "example.net", false},
+	}
+
+	for _, test := range tests {
+		if got := isAlignedDomain(test.dkimDomain, test.fromDomain); got != test.want {
+			t.Errorf("isAlignedDomain(%s, %s) is %t, want %t", test.dkimDomain, test.fromDomain, got, test.want)
+		}
+	}
+}
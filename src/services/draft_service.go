@@ -0,0 +1,409 @@
+package services
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/enums"
+	"api-mail/main/src/models"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google.golang.org/api/gmail/v1"
+	"io"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultImapDraftsFolder = "Drafts"
+
+// GetSendMail gets the send-mail with its app mail.
+func GetSendMail(id uint) (*models.SendMail, error) {
+	sendMail := &models.SendMail{}
+
+	if result := database.Pg.Preload("AppMail").Find(sendMail, "id = ?", id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return sendMail, nil
+}
+
+// SetSendMailDraft stores the provider and ID of the draft of the send-mail.
+func SetSendMailDraft(sendMail *models.SendMail, primaryType enums.AppMailPrimaryType, draftID string) error {
+	sendMail.DraftID = sql.NullString{Valid: true, String: draftID}
+	sendMail.DraftType = sql.NullString{Valid: true, String: string(primaryType)}
+
+	if result := database.Pg.Model(sendMail).
+		Select("DraftID", "DraftType").
+		Updates(sendMail); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// ClaimSendMailDraft marks the draft as sent before it is sent, so a draft is sent only once by concurrent requests.
+// It returns false when the draft is already sent.
+func ClaimSendMailDraft(sendMail *models.SendMail) (bool, error) {
+	sentAt := sql.NullTime{Valid: true, Time: time.Now()}
+
+	result := database.Pg.Model(&models.SendMail{}).
+		Where("id = ? AND draft_sent_at IS NULL", sendMail.ID).
+		Update("draft_sent_at", sentAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected == 1 {
+		sendMail.DraftSentAt = sentAt
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// ReleaseSendMailDraft clears the sent mark of a draft that could not be sent.
+func ReleaseSendMailDraft(sendMail *models.SendMail) error {
+	sendMail.DraftSentAt = sql.NullTime{}
+
+	if result := database.Pg.Model(&models.SendMail{}).
+		Where("id = ?", sendMail.ID).
+		Update("draft_sent_at", nil); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// CreateSmtpDraft composes the message like SendSmtpMail and appends it as draft to the IMAP Drafts folder.
+// The message is DKIM signed when the draft is sent, the draft ID is its Message-ID.
+func CreateSmtpDraft(appMail *models.AppMail, sendMail *requests.SendMail) (string, error) {
+	message := newMimeMessage(sendMail)
+	message.IncludeBcc = true
+
+	msg, err := composeMimeMessage(appMail, message, sendMail)
+	if err != nil {
+		return "", fmt.Errorf("creating email error: %s", err.Error())
+	}
+
+	return CreateSmtpRawDraft(appMail, msg)
+}
+
+// CreateSmtpRawDraft appends the raw message as draft to the IMAP Drafts folder, a Message-ID is added when it has none.
+func CreateSmtpRawDraft(appMail *models.AppMail, raw []byte) (string, error) {
+	smtp, err := getSendSmtp(appMail)
+	if err != nil {
+		return "", err
+	}
+
+	message, err := netmail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return "", fmt.Errorf("raw message can't be parsed: %s", err.Error())
+	}
+
+	draftID := message.Header.Get("Message-ID")
+	if draftID == "" {
+		draftID = messageID(appMail.MailName)
+		raw = append([]byte("Message-ID: "+draftID+"\r\n"), raw...)
+	}
+
+	if err := appendImapMessage(smtp, smtpDraftsFolder(smtp), `\Draft \Seen`, raw); err != nil {
+		return "", err
+	}
+
+	return draftID, nil
+}
+
+// SendSmtpDraft fetches the draft from the IMAP Drafts folder, sends it like a raw mail and removes the draft.
+// Changes made to the draft in a mail client are sent as long as the Message-ID is kept.
+func SendSmtpDraft(appMail *models.AppMail, draftID string) error {
+	smtp, err := getSendSmtp(appMail)
+	if err != nil {
+		return err
+	}
+
+	// The IMAP session is closed before the send, so the SMTP delivery doesn't run into its deadline.
+	folder := smtpDraftsFolder(smtp)
+	raw, err := fetchImapDraft(smtp, folder, draftID)
+	if err != nil {
+		return err
+	}
+
+	draft, recipients, err := ParseRawMail(&requests.SendRawMail{Raw: raw})
+	if err != nil {
+		return err
+	}
+
+	msg, err := SignDkimMessage(smtp, appMail.AppName, stripBccHeader(raw))
+	if err != nil {
+		return fmt.Errorf("creating email error: cannot dkim sign message: %s", err.Error())
+	}
+
+	if err := sendSmtpMessage(smtp, draft.FromMail, recipients, msg); err != nil {
+		return fmt.Errorf("sending email error: %s", err.Error())
+	}
+
+	// The mail is sent, a draft that can't be removed is left behind instead of failing the send.
+	if err := deleteImapDraft(smtp, folder, draftID); err != nil {
+		log.Printf("draft %s of smtp %d: %s", draftID, smtp.ID, err.Error())
+	}
+
+	if appMail.SaveSentCopy {
+		saveSmtpSentCopy(smtp, raw)
+	}
+
+	return nil
+}
+
+// fetchImapDraft returns the message with the Message-ID from the Drafts folder of the smtp mailbox.
+func fetchImapDraft(smtp *models.Smtp, folder, draftID string) ([]byte, error) {
+	client, err := dialImap(smtp)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	uid, err := selectImapDraft(client, folder, draftID)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := client.fetchMessage(uid)
+	if err != nil {
+		return nil, fmt.Errorf("imap error: fetch failed: %s", err.Error())
+	}
+
+	_ = client.command("LOGOUT")
+
+	return raw, nil
+}
+
+// deleteImapDraft removes the message with the Message-ID from the Drafts folder of the smtp mailbox in a new session.
+func deleteImapDraft(smtp *models.Smtp, folder, draftID string) error {
+	client, err := dialImap(smtp)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	uid, err := selectImapDraft(client, folder, draftID)
+	if err != nil {
+		return err
+	}
+
+	if err := client.deleteMessage(uid); err != nil {
+		return fmt.Errorf("imap error: delete failed: %s", err.Error())
+	}
+
+	_ = client.command("LOGOUT")
+
+	return nil
+}
+
+// selectImapDraft opens the Drafts folder and returns the UID of the message with the Message-ID.
+func selectImapDraft(client *imapClient, folder, draftID string) (string, error) {
+	if err := client.selectFolder(folder); err != nil {
+		return "", fmt.Errorf("imap error: select %s failed: %s", folder, err.Error())
+	}
+
+	uid, err := client.searchMessageID(draftID)
+	if err != nil {
+		return "", fmt.Errorf("imap error: search failed: %s", err.Error())
+	} else if uid == "" {
+		return "", fmt.Errorf("draft %s not found in %s", draftID, folder)
+	}
+
+	return uid, nil
+}
+
+// CreateGmailDraft composes the message like SendGmailMail and creates it as Gmail draft.
+func CreateGmailDraft(appMail *models.AppMail, sendMail *requests.SendMail) (string, error) {
+	message := newMimeMessage(sendMail)
+	message.IncludeBcc = true
+
+	msg, err := composeMimeMessage(appMail, message, sendMail)
+	if err != nil {
+		return "", fmt.Errorf("error creating gmail message: %s", err.Error())
+	}
+
+	return CreateGmailRawDraft(appMail, msg)
+}
+
+// CreateGmailRawDraft creates the raw message as Gmail draft with Users.Drafts.Create.
+func CreateGmailRawDraft(appMail *models.AppMail, raw []byte) (string, error) {
+	gmailRecord, err := getSendGmail(appMail)
+	if err != nil {
+		return "", err
+	}
+
+	gmailService, err := getSendGmailService(context.Background(), gmailRecord)
+	if err != nil {
+		return "", err
+	}
+
+	message, media, err := gmailUploadMessage(raw)
+	if err != nil {
+		return "", fmt.Errorf("error creating gmail draft: %s", err.Error())
+	}
+
+	call := gmailService.Users.Drafts.Create("me", &gmail.Draft{Message: message})
+	if media != nil {
+		call = call.Media(bytes.NewReader(raw), media...)
+	}
+
+	draft, err := call.Do()
+	if err != nil {
+		return "", fmt.Errorf("error creating gmail draft: %s", err.Error())
+	}
+
+	return draft.Id, nil
+}
+
+// SendGmailDraft sends the Gmail draft with Users.Drafts.Send, also with SMTP delivery.
+func SendGmailDraft(appMail *models.AppMail, draftID string) error {
+	gmailRecord, err := getSendGmail(appMail)
+	if err != nil {
+		return err
+	}
+
+	gmailService, err := getSendGmailService(context.Background(), gmailRecord)
+	if err != nil {
+		return err
+	}
+
+	if _, err := gmailService.Users.Drafts.Send("me", &gmail.Draft{Id: draftID}).Do(); err != nil {
+		return fmt.Errorf("error sending gmail draft: %s", err.Error())
+	}
+
+	return nil
+}
+
+// CreateAzureDraft creates the message as draft with Graph /me/messages.
+// Like SendAzureMail a mail with an event is created in the MIME format.
+func CreateAzureDraft(appMail *models.AppMail, sendMail *requests.SendMail) (string, error) {
+	if sendMail.Event != nil {
+		message := newMimeMessage(sendMail)
+		message.FromName = ""
+		message.FromMail = appMail.MailName
+		message.IncludeBcc = true
+
+		msg, err := composeMimeMessage(appMail, message, sendMail)
+		if err != nil {
+			return "", fmt.Errorf("error creating mime message: %s", err.Error())
+		}
+
+		return CreateAzureRawDraft(appMail, msg)
+	}
+
+	if isAzureUploadRequired(sendMail) {
+		azure, err := getSendAzure(appMail)
+		if err != nil {
+			return "", err
+		}
+
+		client, err := getSendAzureClient(context.Background(), azure)
+		if err != nil {
+			return "", err
+		}
+
+		return createAzureUploadDraft(client, azure, sendMail)
+	}
+
+	body, err := serializeAzureBody(newAzureMessage(sendMail))
+	if err != nil {
+		return "", err
+	}
+
+	return createAzureDraft(appMail, "application/json", body)
+}
+
+// CreateAzureRawDraft creates the raw message as draft with the MIME format of Graph /me/messages.
+func CreateAzureRawDraft(appMail *models.AppMail, raw []byte) (string, error) {
+	return createAzureDraft(appMail, "text/plain", []byte(base64.StdEncoding.EncodeToString(raw)))
+}
+
+// createAzureDraft posts the message to Graph /me/messages and returns the ID of the created draft.
+func createAzureDraft(appMail *models.AppMail, contentType string, body []byte) (string, error) {
+	azure, err := getSendAzure(appMail)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := getSendAzureClient(context.Background(), azure)
+	if err != nil {
+		return "", err
+	}
+
+	return postAzureDraft(client, azure, contentType, body)
+}
+
+// postAzureDraft posts the message with the client to Graph /me/messages.
+func postAzureDraft(client *http.Client, azure *models.Azure, contentType string, body []byte) (string, error) {
+	resp, err := client.Post(getAzureCloud(azure).graphURL("/me/messages"), contentType, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("error while creating draft: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("error creating draft: %s, Body: %s", resp.Status, string(bodyBytes))
+	}
+
+	var message struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+		return "", fmt.Errorf("error reading draft: %s", err.Error())
+	} else if message.ID == "" {
+		return "", errors.New("error reading draft: the response has no id")
+	}
+
+	return message.ID, nil
+}
+
+// SendAzureDraft sends the draft with Graph /me/messages/{id}/send, Graph keeps the copy in Sent Items.
+func SendAzureDraft(appMail *models.AppMail, draftID string) error {
+	azure, err := getSendAzure(appMail)
+	if err != nil {
+		return err
+	}
+
+	client, err := getSendAzureClient(context.Background(), azure)
+	if err != nil {
+		return err
+	}
+
+	return postAzureDraftSend(client, azure, draftID)
+}
+
+// postAzureDraftSend sends the draft with the client.
+func postAzureDraftSend(client *http.Client, azure *models.Azure, draftID string) error {
+	resp, err := client.Post(getAzureCloud(azure).graphURL("/me/messages/"+url.PathEscape(draftID)+"/send"), "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("error while sending draft: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error sending draft: %s, Body: %s", resp.Status, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// smtpDraftsFolder returns the IMAP Drafts folder of the smtp.
+func smtpDraftsFolder(smtp *models.Smtp) string {
+	if smtp.DraftsFolder != nil && strings.TrimSpace(*smtp.DraftsFolder) != "" {
+		return *smtp.DraftsFolder
+	}
+
+	return defaultImapDraftsFolder
+}
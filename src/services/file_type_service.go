@@ -0,0 +1,194 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	oleSignature      = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	sevenZipSignature = []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}
+	mzSignature       = []byte{'M', 'Z'}
+	peSignature       = []byte{'P', 'E', 0, 0}
+	elfSignature      = []byte{0x7F, 'E', 'L', 'F'}
+	machoSignatures   = [][]byte{{0xFE, 0xED, 0xFA, 0xCE}, {0xFE, 0xED, 0xFA, 0xCF}, {0xCE, 0xFA, 0xED, 0xFE}, {0xCF, 0xFA, 0xED, 0xFE}}
+)
+
+// extensionFileTypes are the types the content of a file with the extension must be detected as.
+var extensionFileTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".txt":  "text/plain",
+	".csv":  "text/csv",
+	".ics":  "text/calendar",
+	".htm":  "text/html",
+	".html": "text/html",
+	".json": "application/json",
+	".xml":  "application/xml",
+	".doc":  "application/msword",
+	".xls":  "application/vnd.ms-excel",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".zip":  "application/zip",
+	".rar":  "application/x-rar-compressed",
+	".7z":   "application/x-7z-compressed",
+	".tar":  "application/x-tar",
+	".exe":  "application/x-msdownload",
+	".dll":  "application/x-msdownload",
+	".js":   "application/javascript",
+	".vbs":  "application/x-vbscript",
+	".ps1":  "application/x-powershell",
+	".bat":  "application/x-msdos-program",
+	".cmd":  "application/x-msdos-program",
+	".hta":  "application/hta",
+}
+
+// textFileTypes are the non-text/* types of text files, text is only detected as text/plain, text/html or text/xml.
+// Scripts have application types, so a policy that allows text/* doesn't allow them.
+var textFileTypes = map[string]bool{
+	"application/json":            true,
+	"application/xml":             true,
+	"application/javascript":      true,
+	"application/x-vbscript":      true,
+	"application/x-powershell":    true,
+	"application/x-msdos-program": true,
+	"application/hta":             true,
+}
+
+// oleFileTypes are the older Office formats, they share the OLE container and are told apart by the extension.
+var oleFileTypes = map[string]string{
+	".doc": "application/msword",
+	".xls": "application/vnd.ms-excel",
+	".ppt": "application/vnd.ms-powerpoint",
+}
+
+// ooxmlFileTypes are the Office Open XML formats by the folder of their zip entries.
+var ooxmlFileTypes = map[string]string{
+	"word/": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"xl/":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"ppt/":  "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// DetectFileType detects the MIME type of the file from its content, without parameters such as the charset.
+// Formats that http.DetectContentType does not know are recognised by their signature.
+func DetectFileType(fileName string, data []byte) string {
+	fileType, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil {
+		fileType = "application/octet-stream"
+	}
+
+	switch {
+	case fileType == "application/zip":
+		return detectZipFileType(data)
+	case bytes.HasPrefix(data, oleSignature):
+		if oleType, ok := oleFileTypes[strings.ToLower(filepath.Ext(fileName))]; ok {
+			return oleType
+		}
+
+		return "application/x-ole-storage"
+	case bytes.HasPrefix(data, sevenZipSignature):
+		return "application/x-7z-compressed"
+	case len(data) >= 262 && string(data[257:262]) == "ustar":
+		return "application/x-tar"
+	case isPortableExecutable(data):
+		return "application/x-msdownload"
+	case bytes.HasPrefix(data, elfSignature):
+		return "application/x-executable"
+	}
+
+	for _, signature := range machoSignatures {
+		if bytes.HasPrefix(data, signature) {
+			return "application/x-mach-binary"
+		}
+	}
+
+	return fileType
+}
+
+// IsFileTypeCompatible checks if the declared type of a file matches the type detected from its content.
+// Text files can't be told apart by their content, so every text type matches a detected text type.
+func IsFileTypeCompatible(detected, declared string) bool {
+	declared, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		return false
+	} else if strings.EqualFold(declared, detected) {
+		return true
+	}
+
+	return isTextFileType(detected) && isTextFileType(declared)
+}
+
+// IsExtensionCompatible checks if the type detected from the content matches the extension of the file name.
+// An unknown extension is not checked.
+func IsExtensionCompatible(fileName, detected string) bool {
+	extensionType, ok := extensionFileTypes[strings.ToLower(filepath.Ext(fileName))]
+	if !ok {
+		return true
+	}
+
+	return IsFileTypeCompatible(detected, extensionType)
+}
+
+// PolicyFileType returns the type of the file that the attachment policy is checked against.
+// Text files can't be told apart by their content, so the type of a known extension is used, and otherwise the
+// declared type that matched the content.
+func PolicyFileType(fileName, declared string) string {
+	if extensionType, ok := extensionFileTypes[strings.ToLower(filepath.Ext(fileName))]; ok {
+		return extensionType
+	}
+
+	declared, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		return "application/octet-stream"
+	}
+
+	return strings.ToLower(declared)
+}
+
+// isTextFileType checks if the type is a text type.
+func isTextFileType(fileType string) bool {
+	fileType = strings.ToLower(fileType)
+
+	return strings.HasPrefix(fileType, "text/") || textFileTypes[fileType]
+}
+
+// isPortableExecutable checks for the PE signature at the offset that the MZ header stores at 0x3C.
+// Only the MZ prefix would also match DOS programs and text files that start with MZ.
+func isPortableExecutable(data []byte) bool {
+	if len(data) < 0x40 || !bytes.HasPrefix(data, mzSignature) {
+		return false
+	}
+
+	offset := int64(binary.LittleEndian.Uint32(data[0x3C:0x40]))
+
+	return offset+int64(len(peSignature)) <= int64(len(data)) && bytes.Equal(data[offset:offset+int64(len(peSignature))], peSignature)
+}
+
+// detectZipFileType returns the Office Open XML type of the zip file, or application/zip for any other zip file.
+func detectZipFileType(data []byte) string {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "application/zip"
+	}
+
+	for _, file := range reader.File {
+		for folder, fileType := range ooxmlFileTypes {
+			if strings.HasPrefix(file.Name, folder) {
+				return fileType
+			}
+		}
+	}
+
+	return "application/zip"
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"api-mail/main/src/models"
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// zipData returns a zip file with an empty entry of the name.
+func zipData(t *testing.T, name string) []byte {
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	if _, err := writer.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+// peData returns a minimal PE file, the MZ header points to the PE signature.
+func peData() []byte {
+	data := make([]byte, 0x80)
+	copy(data, "MZ")
+	binary.LittleEndian.PutUint32(data[0x3C:], 0x40)
+	copy(data[0x40:], "PE\x00\x00")
+
+	return data
+}
+
+// policyOf returns a policy that allows only the types.
+func policyOf(allowedTypes ...string) *models.AttachmentPolicy {
+	return &models.AttachmentPolicy{AllowedTypes: joinPolicyList(allowedTypes, strings.ToLower)}
+}
+
+func TestAttachmentTypeMatrix(t *testing.T) {
+	csv := []byte("name,mail\nJohn,john@example.com\n")
+	ics := []byte("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nEND:VCALENDAR\r\n")
+	pdf := []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	exe := peData()
+	mz := append([]byte("MZ"), make([]byte, 64)...)
+	script := []byte("WScript.Echo \"Hello\"\r\n")
+
+	tests := []struct {
+		name       string
+		fileName   string
+		declared   string
+		data       []byte
+		policy     *models.AttachmentPolicy
+		compatible bool
+		extension  bool
+		allowed    bool
+	}{
+		{"csv allowed", "contacts.csv", "text/csv", csv, policyOf("text/csv"), true, true, true},
+		{"csv as text", "contacts.csv", "text/plain", csv, policyOf("text/csv"), true, true, true},
+		{"csv not allowed", "contacts.csv", "text/csv", csv, policyOf("text/plain"), true, true, false},
+		{"csv by group", "contacts.csv", "text/csv", csv, policyOf("text/*"), true, true, true},
+		{"ics allowed", "invite.ics", "text/calendar; method=REQUEST", ics, policyOf("text/calendar"), true, true, true},
+		{"ics not allowed", "invite.ics", "text/calendar", ics, policyOf(defaultAllowedFileTypes...), true, true, false},
+		{"text unknown extension", "notes.md", "text/markdown", []byte("# Notes\n"), policyOf("text/markdown"), true, true, true},
+		{"pdf", "report.pdf", "application/pdf", pdf, policyOf(defaultAllowedFileTypes...), true, true, true},
+		{"pdf declared as text", "report.pdf", "text/plain", pdf, policyOf(defaultAllowedFileTypes...), false, true, true},
+		{"text named pdf", "report.pdf", "application/pdf", csv, policyOf(defaultAllowedFileTypes...), false, false, true},
+		{"docx", "letter.docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", zipData(t, "word/document.xml"), policyOf(defaultAllowedFileTypes...), true, true, true},
+		{"zip", "archive.zip", "application/zip", zipData(t, "file.txt"), policyOf(defaultAllowedFileTypes...), true, true, true},
+		{"renamed exe", "invoice.pdf", "application/pdf", exe, policyOf(defaultAllowedFileTypes...), false, false, true},
+		{"exe", "setup.exe", "application/x-msdownload", exe, policyOf(defaultAllowedFileTypes...), true, true, false},
+		{"mz without pe", "data.bin", "application/octet-stream", mz, policyOf("application/octet-stream"), true, true, true},
+		{"vbs", "invoice.vbs", "text/plain", script, policyOf(defaultAllowedFileTypes...), true, true, false},
+		{"vbs by text group", "invoice.vbs", "text/plain", script, policyOf("text/*"), true, true, false},
+		{"js", "invoice.js", "application/javascript", script, policyOf(defaultAllowedFileTypes...), true, true, false},
+		{"ps1", "invoice.ps1", "text/plain", script, policyOf(defaultAllowedFileTypes...), true, true, false},
+		{"bat", "invoice.bat", "text/plain", script, policyOf(defaultAllowedFileTypes...), true, true, false},
+		{"cmd", "invoice.cmd", "text/plain", script, policyOf(defaultAllowedFileTypes...), true, true, false},
+		{"hta", "invoice.hta", "text/html", []byte("<html><script>close()</script></html>"), policyOf("text/*"), true, true, false},
+		{"script allowed", "deploy.ps1", "text/plain", script, policyOf("application/x-powershell"), true, true, true},
+	}
+
+	for _, test := range tests {
+		detected := DetectFileType(test.fileName, test.data)
+
+		if got := IsFileTypeCompatible(detected, test.declared); got != test.compatible {
+			t.Errorf("%s: declared %s compatible with %s is %t", test.name, test.declared, detected, got)
+		}
+		if got := IsExtensionCompatible(test.fileName, detected); got != test.extension {
+			t.Errorf("%s: extension compatible with %s is %t", test.name, detected, got)
+		}
+		if got := test.policy.AllowsType(PolicyFileType(test.fileName, test.declared)); got != test.allowed {
+			t.Errorf("%s: %s allowed is %t", test.name, PolicyFileType(test.fileName, test.declared), got)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"google.golang.org/api/googleapi"
+	"gorm.io/gorm"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// gmailSendAsMaxAge is how long the fetched send-as aliases are trusted before they are fetched again for a send.
+const gmailSendAsMaxAge = time.Hour
+
+// ErrGmailSettingsScope is returned when the consent of the gmail was granted without the gmail.settings.basic scope.
+var ErrGmailSettingsScope = errors.New("the gmail consent doesn't grant the " + gmailSettingsScope + " scope")
+
+// isInsufficientScope checks if Google refused the request because the token lacks a scope.
+func isInsufficientScope(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		return false
+	}
+
+	for _, item := range apiErr.Errors {
+		if item.Reason == "insufficientPermissions" {
+			return true
+		}
+	}
+
+	return strings.Contains(strings.ToLower(apiErr.Message), "insufficient authentication scopes")
+}
+
+// GetGmailSendAs gets the stored send-as aliases of the gmail.
+func GetGmailSendAs(gmailID uint) ([]models.GmailSendAs, error) {
+	sendAs := make([]models.GmailSendAs, 0)
+
+	if result := database.Pg.Where("gmail_id = ?", gmailID).Order("id").Find(&sendAs); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return sendAs, nil
+}
+
+// SyncGmailSendAs fetches the send-as aliases of the Google account and replaces the stored aliases of the gmail.
+func SyncGmailSendAs(ctx context.Context, gmail *models.Gmail) ([]models.GmailSendAs, error) {
+	gmailService, err := getSendGmailService(ctx, gmail)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := gmailService.Users.Settings.SendAs.List("me").Context(ctx).Do()
+	if isInsufficientScope(err) {
+		return nil, ErrGmailSettingsScope
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting gmail send-as aliases: %s", err.Error())
+	}
+
+	sendAs := make([]models.GmailSendAs, len(list.SendAs))
+	for i, alias := range list.SendAs {
+		sendAs[i] = models.GmailSendAs{
+			GmailID:            gmail.ID,
+			SendAsEmail:        strings.ToLower(alias.SendAsEmail),
+			DisplayName:        alias.DisplayName,
+			ReplyToAddress:     alias.ReplyToAddress,
+			IsPrimary:          alias.IsPrimary,
+			IsDefault:          alias.IsDefault,
+			TreatAsAlias:       alias.TreatAsAlias,
+			VerificationStatus: alias.VerificationStatus,
+		}
+	}
+
+	syncedAt := sql.NullTime{Valid: true, Time: time.Now()}
+
+	if err := database.Pg.Transaction(func(tx *gorm.DB) error {
+		if result := tx.Where("gmail_id = ?", gmail.ID).Delete(&models.GmailSendAs{}); result.Error != nil {
+			return result.Error
+		}
+
+		if len(sendAs) > 0 {
+			if result := tx.Omit("Gmail").Create(&sendAs); result.Error != nil {
+				return result.Error
+			}
+		}
+
+		if result := tx.Model(&models.Gmail{}).Where("id = ?", gmail.ID).Update("send_as_synced_at", syncedAt); result.Error != nil {
+			return result.Error
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	gmail.SendAsSyncedAt = syncedAt
+
+	if isInCache, err := IsGmailInCache(gmail.ID); err != nil {
+		return nil, err
+	} else if isInCache {
+		if err := SetGmailToCache(gmail); err != nil {
+			return nil, err
+		}
+	}
+
+	return sendAs, nil
+}
+
+// IsGmailSendAs checks if the address is the mailbox or a verified send-as alias of the gmail of the app mail.
+// The aliases are fetched again when they are older than gmailSendAsMaxAge.
+func IsGmailSendAs(ctx context.Context, appMail *models.AppMail, address string) (bool, error) {
+	if strings.EqualFold(address, appMail.MailName) {
+		return true, nil
+	}
+
+	gmail, err := getSendGmail(appMail)
+	if err != nil {
+		return false, err
+	}
+
+	var sendAs []models.GmailSendAs
+	if !gmail.SendAsSyncedAt.Valid || time.Since(gmail.SendAsSyncedAt.Time) > gmailSendAsMaxAge {
+		if sendAs, err = SyncGmailSendAs(ctx, gmail); err != nil {
+			return false, err
+		}
+	} else if sendAs, err = GetGmailSendAs(gmail.ID); err != nil {
+		return false, err
+	}
+
+	for i := range sendAs {
+		if strings.EqualFold(sendAs[i].SendAsEmail, address) && sendAs[i].CanSend() {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/api/googleapi"
+	"net/http"
+	"testing"
+)
+
+func TestIsInsufficientScope(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "insufficientPermissions"}}}, true},
+		{fmt.Errorf("list: %w", &googleapi.Error{Code: http.StatusForbidden, Message: "Request had insufficient authentication scopes."}), true},
+		{&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "domainPolicy"}}}, false},
+		{&googleapi.Error{Code: http.StatusUnauthorized, Message: "Request had insufficient authentication scopes."}, false},
+		{errors.New("insufficient authentication scopes"), false},
+		{nil, false},
+	}
+
+	for _, test := range tests {
+		if got := isInsufficientScope(test.err); got != test.want {
+			t.Errorf("isInsufficientScope(%v) is %t, want %t", test.err, got, test.want)
+		}
+	}
+}
@@ -8,15 +8,31 @@ import (
 	"api-mail/main/src/models"
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/valkey-io/valkey-go"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/endpoints"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 )
 
+const (
+	gmailSmtpHost  = "smtp.gmail.com"
+	gmailSmtpScope = "https://mail.google.com/"
+	gmailRevokeURL = "https://oauth2.googleapis.com/revoke"
+	// gmailSettingsScope allows listing the send-as aliases of the account.
+	gmailSettingsScope = "https://www.googleapis.com/auth/gmail.settings.basic"
+	// gmailComposeScope allows creating and sending drafts.
+	gmailComposeScope = "https://www.googleapis.com/auth/gmail.compose"
+)
+
 // IsGmailAvailable checks if the gmail exists.
 func IsGmailAvailable(app, mail string) (bool, error) {
 	var count int64
@@ -109,18 +125,24 @@ func GetGmailFromCache(id uint) (*models.Gmail, error) {
 }
 
 // CreateGmailOauthConfig creates a new oauth config.
-func CreateGmailOauthConfig(clientID, secret string) *oauth2.Config {
+// SMTP delivery needs the full mail scope, Gmail does not accept XOAUTH2 with the send scope only.
+func CreateGmailOauthConfig(clientID, secret string, smtpDelivery bool) *oauth2.Config {
 	redirectUrl := fmt.Sprintf(
 		"%sv1/oauth2/gmails/callback",
 		os.Getenv("DOMAIN_NAME"),
 	)
 
+	scopes := []string{"https://www.googleapis.com/auth/gmail.send", gmailSettingsScope, gmailComposeScope, "openid", "profile", "email"}
+	if smtpDelivery {
+		scopes = append(scopes, gmailSmtpScope)
+	}
+
 	return &oauth2.Config{
 		ClientID:     clientID,
 		ClientSecret: secret,
 		Endpoint:     endpoints.Google,
 		RedirectURL:  redirectUrl,
-		Scopes:       []string{"https://www.googleapis.com/auth/gmail.send", "openid", "profile", "email"},
+		Scopes:       scopes,
 	}
 }
 
@@ -128,9 +150,10 @@ func CreateGmailOauthConfig(clientID, secret string) *oauth2.Config {
 func CreateGmail(req *requests.CreateGmail) (*models.Gmail, error) {
 	gmailType := enums.Gmail
 	gmail := &models.Gmail{
-		ClientID: req.ClientID,
-		Secret:   req.Secret,
-		User:     req.User,
+		ClientID:     req.ClientID,
+		Secret:       req.Secret,
+		User:         req.User,
+		SmtpDelivery: req.SmtpDelivery,
 		AppMail: models.AppMail{
 			AppName:  req.App,
 			MailName: req.Mail,
@@ -224,6 +247,7 @@ func UpdateGmail(oldGmail *models.Gmail, req *requests.UpdateGmail) (*models.Gma
 	oldGmail.ClientID = req.ClientID
 	oldGmail.Secret = req.Secret
 	oldGmail.User = req.User
+	oldGmail.SmtpDelivery = req.SmtpDelivery
 
 	if req.Primary && (!oldGmail.AppMail.PrimaryType.Valid || oldGmail.AppMail.PrimaryType.String != *gmailType.ToString()) {
 		oldGmail.AppMail.PrimaryType = sql.NullString{String: *gmailType.ToString(), Valid: true}
@@ -267,6 +291,51 @@ func DeleteGmail(gmail *models.Gmail) error {
 	return nil
 }
 
+// RevokeGmailToken revokes the refresh token at Google, which also revokes its access tokens, and clears the stored token.
+// A token that Google reports as invalid is already revoked or expired.
+func RevokeGmailToken(ctx context.Context, gmail *models.Gmail) error {
+	token := gmail.RefreshToken.String
+	if token == "" {
+		token = gmail.AccessToken.String
+	}
+
+	if token != "" {
+		form := url.Values{"token": {token}}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, gmailRevokeURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("error revoking gmail token: %s", err.Error())
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK &&
+			!(resp.StatusCode == http.StatusBadRequest && strings.Contains(string(body), "invalid_token")) {
+			return fmt.Errorf("error revoking gmail token: %s, Body: %s", resp.Status, string(body))
+		}
+	}
+
+	gmail.AccessToken = sql.NullString{}
+	gmail.RefreshToken = sql.NullString{}
+	gmail.TokenType = sql.NullString{}
+	gmail.Expiry = sql.NullTime{}
+	gmail.ExpiresIn = sql.NullInt64{}
+	gmail.VerifiedMail = sql.NullString{}
+
+	if result := database.Pg.Model(gmail).
+		Select("AccessToken", "RefreshToken", "TokenType", "Expiry", "ExpiresIn", "VerifiedMail").
+		Updates(gmail); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
 // DeleteGmailFromCache deletes a existing gmail from the cache.
 func DeleteGmailFromCache(id uint) error {
 	key := gmailCacheKey(id)
@@ -288,6 +357,118 @@ func RestoreGmail(gmail *models.Gmail) error {
 	return nil
 }
 
+// GetGmailToken gets the stored OAuth2 token of the gmail.
+func GetGmailToken(gmail *models.Gmail) (*oauth2.Token, error) {
+	if !gmail.AccessToken.Valid ||
+		!gmail.RefreshToken.Valid ||
+		!gmail.TokenType.Valid ||
+		!gmail.Expiry.Valid ||
+		!gmail.ExpiresIn.Valid {
+		return nil, errors.New("gmail record not authenticated")
+	}
+
+	return &oauth2.Token{
+		AccessToken:  gmail.AccessToken.String,
+		TokenType:    gmail.TokenType.String,
+		RefreshToken: gmail.RefreshToken.String,
+		Expiry:       gmail.Expiry.Time,
+		ExpiresIn:    gmail.ExpiresIn.Int64,
+	}, nil
+}
+
+// GetGmailSmtpToken gets a valid access token of the gmail for SMTP XOAUTH2.
+func GetGmailSmtpToken(ctx context.Context, gmail *models.Gmail) (string, error) {
+	token, err := RefreshGmailToken(ctx, gmail, false)
+	if err != nil {
+		return "", err
+	}
+
+	return token.AccessToken, nil
+}
+
+// RefreshGmailToken refreshes the token of the gmail when it is expired, or always when forced.
+// A refreshed token is stored.
+func RefreshGmailToken(ctx context.Context, gmail *models.Gmail, force bool) (*oauth2.Token, error) {
+	token, err := GetGmailToken(gmail)
+	if err != nil {
+		return nil, err
+	}
+
+	if force {
+		token.Expiry = time.Unix(1, 0)
+	}
+
+	oauthConfig := CreateGmailOauthConfig(gmail.ClientID, gmail.Secret, gmail.SmtpDelivery)
+	newToken, err := oauthConfig.TokenSource(ctx, token).Token()
+	if err != nil {
+		return nil, fmt.Errorf("error refreshing gmail token: %s", err.Error())
+	}
+
+	if newToken.AccessToken != gmail.AccessToken.String {
+		if _, err := UpdateGmailToken(gmail, newToken); err != nil {
+			return nil, err
+		}
+
+		if err := SetGmailToCache(gmail); err != nil {
+			return nil, err
+		}
+	}
+
+	return newToken, nil
+}
+
+// VerifyGmailIdentity checks that the Google account that consented is the mailbox or user of the gmail.
+// It returns the address of the account, which is set as verified mail on a match and stored with the token.
+func VerifyGmailIdentity(gmail *models.Gmail, token *oauth2.Token) (string, bool, error) {
+	identity, err := getGmailIdentity(token)
+	if err != nil {
+		return "", false, err
+	}
+
+	if _, ok := MatchOauthIdentity([]string{identity}, gmail.AppMail.MailName, gmail.User); !ok {
+		return identity, false, nil
+	}
+
+	gmail.VerifiedMail = sql.NullString{Valid: true, String: identity}
+
+	return identity, true, nil
+}
+
+// getGmailIdentity reads the verified address of the Google account from the ID token.
+// The ID token is received directly from the token endpoint over TLS, so its signature is not checked (OpenID Connect Core, 3.1.3.7).
+func getGmailIdentity(token *oauth2.Token) (string, error) {
+	idToken, ok := token.Extra("id_token").(string)
+	if !ok || idToken == "" {
+		return "", errors.New("the token has no ID token")
+	}
+
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return "", errors.New("the ID token is malformed")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("the ID token is malformed: %s", err.Error())
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("the ID token is malformed: %s", err.Error())
+	}
+
+	if claims.Email == "" {
+		return "", errors.New("the ID token has no email claim")
+	} else if !claims.EmailVerified {
+		return "", fmt.Errorf("the address %s of the Google account is not verified", claims.Email)
+	}
+
+	return strings.ToLower(claims.Email), nil
+}
+
 // gmailCacheKey returns the key for the gmail cache.
 func gmailCacheKey(id uint) string {
 	return fmt.Sprintf("%s:%d", enums.Gmail, id)
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"api-mail/main/src/enums"
+	"api-mail/main/src/models"
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultImapPort       = 993
+	defaultImapSentFolder = "Sent"
+)
+
+// imapClient is a minimal IMAP4rev1 session, it only logs in and appends messages.
+type imapClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	tag    int
+}
+
+// saveSmtpSentCopy appends the sent message to the Sent folder of the smtp mailbox in the background.
+// The mail is already sent, so a failure is only logged and never fails the send.
+func saveSmtpSentCopy(smtp *models.Smtp, msg []byte) {
+	go func() {
+		folder := defaultImapSentFolder
+		if smtp.SentFolder != nil && *smtp.SentFolder != "" {
+			folder = *smtp.SentFolder
+		}
+
+		if err := appendImapMessage(smtp, folder, `\Seen`, msg); err != nil {
+			log.Printf("sent copy of smtp %d: %s", smtp.ID, err.Error())
+		}
+	}()
+}
+
+// appendImapMessage appends the message with the flags to the folder of the smtp mailbox.
+func appendImapMessage(smtp *models.Smtp, folder, flags string, msg []byte) error {
+	client, err := dialImap(smtp)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.append(folder, flags, toCRLF(msg)); err != nil {
+		return fmt.Errorf("imap error: append to %s failed: %s", folder, err.Error())
+	}
+
+	_ = client.command("LOGOUT")
+
+	return nil
+}
+
+// dialImap logs in on the IMAP server of the smtp with its credentials.
+// The IMAP server is the SMTP host unless an IMAP host is set, port 993 uses implicit TLS and other ports STARTTLS.
+// The connect timeout covers the login, the send timeout every command after it.
+func dialImap(smtp *models.Smtp) (*imapClient, error) {
+	mechanism := smtp.Auth()
+	if mechanism == enums.AuthNone {
+		return nil, errors.New("imap error: the smtp has no credentials to log in on IMAP")
+	}
+
+	password, err := smtp.DecryptPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	host := smtp.Host
+	if smtp.ImapHost != nil && *smtp.ImapHost != "" {
+		host = *smtp.ImapHost
+	}
+
+	port := defaultImapPort
+	if smtp.ImapPort != nil && *smtp.ImapPort > 0 {
+		port = *smtp.ImapPort
+	}
+
+	tlsConfig, err := newSmtpTlsConfig(smtp)
+	if err != nil {
+		return nil, fmt.Errorf("imap error: %s", err.Error())
+	}
+	if host != smtp.Host && (smtp.TlsServerName == nil || *smtp.TlsServerName == "") {
+		tlsConfig.ServerName = host
+	}
+
+	encrypted := port == defaultImapPort || smtp.Encryption() != enums.EncryptionNone
+	if !encrypted && !isLocalhost(host) {
+		return nil, errors.New("imap error: the credentials are not sent over an unencrypted connection")
+	}
+
+	connectTimeout := smtpTimeout(smtp.ConnectTimeout, defaultSmtpConnectTimeout)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	dialer := &net.Dialer{Timeout: connectTimeout}
+
+	var conn net.Conn
+	if port == defaultImapPort {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("imap error: %s", err.Error())
+	}
+
+	client := &imapClient{conn: conn, reader: bufio.NewReader(conn)}
+	if err := client.login(smtp, password, mechanism, encrypted && port != defaultImapPort, tlsConfig, connectTimeout); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("imap error: %s", err.Error())
+	}
+
+	if err := client.conn.SetDeadline(time.Now().Add(smtpTimeout(smtp.SendTimeout, defaultSmtpSendTimeout))); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("imap error: %s", err.Error())
+	}
+
+	return client, nil
+}
+
+// login reads the greeting, starts TLS and logs in within the connect timeout.
+func (c *imapClient) login(smtp *models.Smtp, password string, mechanism enums.SmtpAuth, startTls bool, tlsConfig *tls.Config, timeout time.Duration) error {
+	if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	if line, err := c.readLine(); err != nil {
+		return err
+	} else if !strings.HasPrefix(line, "* OK") {
+		return fmt.Errorf("unexpected greeting: %s", line)
+	}
+
+	if startTls {
+		if err := c.command("STARTTLS"); err != nil {
+			return err
+		}
+
+		tlsConn := tls.Client(c.conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+		c.conn = tlsConn
+		c.reader = bufio.NewReader(tlsConn)
+	}
+
+	var err error
+	if mechanism == enums.AuthXOAuth2 {
+		sasl := base64.StdEncoding.EncodeToString([]byte("user=" + smtp.Username + "\x01auth=Bearer " + password + "\x01\x01"))
+		err = c.command("AUTHENTICATE XOAUTH2 " + sasl)
+	} else {
+		err = c.command("LOGIN " + imapQuote(smtp.Username) + " " + imapQuote(password))
+	}
+	if err != nil {
+		return fmt.Errorf("login failed: %s", err.Error())
+	}
+
+	return nil
+}
+
+// Close closes the connection without a LOGOUT.
+func (c *imapClient) Close() {
+	_ = c.conn.Close()
+}
+
+// command sends a tagged command and waits for its tagged completion, untagged responses are skipped.
+func (c *imapClient) command(command string) error {
+	tag := c.nextTag()
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, command); err != nil {
+		return err
+	}
+
+	return c.waitTagged(tag)
+}
+
+// append sends the message as literal to the folder with the flags.
+func (c *imapClient) append(folder, flags string, msg []byte) error {
+	tag := c.nextTag()
+	if _, err := fmt.Fprintf(c.conn, "%s APPEND %s (%s) {%d}\r\n", tag, imapQuote(folder), flags, len(msg)); err != nil {
+		return err
+	}
+
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(line, "+") {
+			break
+		} else if strings.HasPrefix(line, tag+" ") {
+			return errors.New(strings.TrimPrefix(line, tag+" "))
+		}
+	}
+
+	if _, err := c.conn.Write(append(msg, '\r', '\n')); err != nil {
+		return err
+	}
+
+	return c.waitTagged(tag)
+}
+
+// selectFolder opens the folder for the commands that work on messages.
+func (c *imapClient) selectFolder(folder string) error {
+	return c.command("SELECT " + imapQuote(folder))
+}
+
+// searchMessageID returns the UID of the last message with the Message-ID in the selected folder, or an empty UID.
+func (c *imapClient) searchMessageID(messageID string) (string, error) {
+	tag := c.nextTag()
+	if _, err := fmt.Fprintf(c.conn, "%s UID SEARCH HEADER Message-ID %s\r\n", tag, imapQuote(messageID)); err != nil {
+		return "", err
+	}
+
+	uid := ""
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return "", err
+		}
+
+		if strings.HasPrefix(strings.ToUpper(line), "* SEARCH") {
+			if uids := strings.Fields(line[len("* SEARCH"):]); len(uids) > 0 {
+				uid = uids[len(uids)-1]
+			}
+		} else if strings.HasPrefix(line, tag+" ") {
+			if status := strings.TrimPrefix(line, tag+" "); !strings.HasPrefix(strings.ToUpper(status), "OK") {
+				return "", errors.New(status)
+			}
+
+			return uid, nil
+		}
+	}
+}
+
+// fetchMessage returns the complete message of the UID in the selected folder without setting the \Seen flag.
+func (c *imapClient) fetchMessage(uid string) ([]byte, error) {
+	tag := c.nextTag()
+	if _, err := fmt.Fprintf(c.conn, "%s UID FETCH %s BODY.PEEK[]\r\n", tag, uid); err != nil {
+		return nil, err
+	}
+
+	var msg []byte
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(line, "* ") && strings.HasSuffix(line, "}") {
+			size, err := strconv.Atoi(line[strings.LastIndex(line, "{")+1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid literal: %s", line)
+			}
+
+			msg = make([]byte, size)
+			if _, err := io.ReadFull(c.reader, msg); err != nil {
+				return nil, err
+			}
+		} else if strings.HasPrefix(line, tag+" ") {
+			if status := strings.TrimPrefix(line, tag+" "); !strings.HasPrefix(strings.ToUpper(status), "OK") {
+				return nil, errors.New(status)
+			} else if msg == nil {
+				return nil, fmt.Errorf("message %s not found", uid)
+			}
+
+			return msg, nil
+		}
+	}
+}
+
+// deleteMessage flags the UID as deleted and expunges only that UID when the server supports UIDPLUS.
+// A plain EXPUNGE would also remove the messages that another client flagged, so without UIDPLUS the message
+// stays flagged until the mail client expunges the folder.
+func (c *imapClient) deleteMessage(uid string) error {
+	if err := c.command("UID STORE " + uid + ` +FLAGS.SILENT (\Deleted)`); err != nil {
+		return err
+	}
+
+	uidPlus, err := c.hasCapability("UIDPLUS")
+	if err != nil {
+		return err
+	} else if !uidPlus {
+		return nil
+	}
+
+	return c.command("UID EXPUNGE " + uid)
+}
+
+// hasCapability checks if the server advertises the capability in its CAPABILITY response.
+func (c *imapClient) hasCapability(capability string) (bool, error) {
+	tag := c.nextTag()
+	if _, err := fmt.Fprintf(c.conn, "%s CAPABILITY\r\n", tag); err != nil {
+		return false, err
+	}
+
+	found := false
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return false, err
+		}
+
+		if strings.HasPrefix(strings.ToUpper(line), "* CAPABILITY ") {
+			for _, advertised := range strings.Fields(line[len("* CAPABILITY "):]) {
+				if strings.EqualFold(advertised, capability) {
+					found = true
+				}
+			}
+		} else if strings.HasPrefix(line, tag+" ") {
+			if status := strings.TrimPrefix(line, tag+" "); !strings.HasPrefix(strings.ToUpper(status), "OK") {
+				return false, errors.New(status)
+			}
+
+			return found, nil
+		}
+	}
+}
+
+// waitTagged reads until the completion of the tag, anything but OK is returned as error.
+func (c *imapClient) waitTagged(tag string) error {
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+
+		// A failed AUTHENTICATE sends the error as challenge, an empty response finishes it.
+		if strings.HasPrefix(line, "+") {
+			if _, err := c.conn.Write([]byte("\r\n")); err != nil {
+				return err
+			}
+			continue
+		} else if !strings.HasPrefix(line, tag+" ") {
+			continue
+		}
+
+		status := strings.TrimPrefix(line, tag+" ")
+		if !strings.HasPrefix(strings.ToUpper(status), "OK") {
+			return errors.New(status)
+		}
+
+		return nil
+	}
+}
+
+// readLine reads a response line without the CRLF.
+func (c *imapClient) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// nextTag returns the tag of the next command.
+func (c *imapClient) nextTag() string {
+	c.tag++
+
+	return "A" + strconv.Itoa(c.tag)
+}
+
+// imapQuote quotes the string for an IMAP command.
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// toCRLF converts the line endings of the message to CRLF, IMAP does not accept bare line feeds.
+func toCRLF(msg []byte) []byte {
+	return bytes.ReplaceAll(bytes.ReplaceAll(msg, []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n"))
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// serveImap answers the commands of a IMAP session with the capabilities and records them.
+func serveImap(conn net.Conn, capabilities string, commands chan<- string) {
+	defer close(commands)
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		tag, command, _ := strings.Cut(line, " ")
+		commands <- command
+
+		if command == "CAPABILITY" {
+			_ = text.PrintfLine("* CAPABILITY %s", capabilities)
+		}
+		_ = text.PrintfLine("%s OK done", tag)
+	}
+}
+
+func TestImapDeleteMessage(t *testing.T) {
+	for capabilities, want := range map[string]string{
+		"IMAP4rev1 UIDPLUS": "UID EXPUNGE 42",
+		"IMAP4rev1":         "",
+	} {
+		clientConn, serverConn := net.Pipe()
+		commands := make(chan string, 10)
+		go serveImap(serverConn, capabilities, commands)
+
+		client := &imapClient{conn: clientConn, reader: bufio.NewReader(clientConn)}
+		if err := client.deleteMessage("42"); err != nil {
+			t.Fatal(err)
+		}
+		client.Close()
+
+		expunge := ""
+		for command := range commands {
+			if strings.Contains(command, "EXPUNGE") {
+				expunge = command
+			}
+		}
+
+		if expunge != want {
+			t.Errorf("with %q the expunge is %q, want %q", capabilities, expunge, want)
+		}
+	}
+}
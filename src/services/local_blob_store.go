@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// LocalBlobStore stores the blobs as files in a directory, spread over sub directories by the first bytes of the key.
+type LocalBlobStore struct {
+	root string
+}
+
+// NewLocalBlobStore creates a local blob store in the directory, the directory is created when it does not exist.
+func NewLocalBlobStore(root string) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("blob directory %s can't be created: %s", root, err.Error())
+	}
+
+	return &LocalBlobStore{root: root}, nil
+}
+
+// Put writes the blob to a temporary file that is renamed, so a blob is never read half written.
+func (s *LocalBlobStore) Put(_ context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// Get reads the blob file.
+func (s *LocalBlobStore) Get(_ context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(path)
+}
+
+// Open opens the blob file.
+func (s *LocalBlobStore) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(path)
+}
+
+// Delete removes the blob file, a missing file is not an error.
+func (s *LocalBlobStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// path returns the file of the key, e.g. ab/cd/abcd... for key abcd...
+func (s *LocalBlobStore) path(key string) (string, error) {
+	if !isBlobKey(key) {
+		return "", fmt.Errorf("invalid blob key %s", key)
+	}
+
+	return filepath.Join(s.root, key[0:2], key[2:4], key), nil
+}
+
+// isBlobKey checks if the key is a hex SHA-256 hash, so it can't point outside of the store.
+func isBlobKey(key string) bool {
+	if len(key) != 64 {
+		return false
+	}
+
+	for _, r := range key {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
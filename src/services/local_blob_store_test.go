@@ -0,0 +1,90 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocalBlobStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := blobStore
+	SetBlobStore(store)
+	t.Cleanup(func() { SetBlobStore(previous) })
+
+	ctx := context.Background()
+	data := []byte("attachment content")
+
+	key, err := PutBlob(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	} else if key != BlobKey(data) {
+		t.Errorf("key is %s, want the hash %s", key, BlobKey(data))
+	}
+
+	path := filepath.Join(root, key[0:2], key[2:4], key)
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("blob is not stored at %s: %v", path, err)
+	}
+
+	// The same content is stored once.
+	if again, err := PutBlob(ctx, data); err != nil || again != key {
+		t.Errorf("second put is %s: %v", again, err)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp")); len(temps) > 0 {
+		t.Errorf("temporary files are left: %q", temps)
+	}
+
+	if read, err := GetBlob(ctx, key); err != nil || !bytes.Equal(read, data) {
+		t.Errorf("read %q: %v", read, err)
+	}
+
+	content, err := OpenBlob(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamed, err := io.ReadAll(content)
+	if err != nil || !bytes.Equal(streamed, data) {
+		t.Errorf("streamed %q: %v", streamed, err)
+	}
+	if err := content.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenBlob(ctx, key); err == nil {
+		t.Error("deleted blob is opened")
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("deleted blob is read: %v", err)
+	}
+
+	// A missing blob is deleted without an error.
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("deleting a missing blob failed: %v", err)
+	}
+}
+
+func TestLocalBlobStoreKey(t *testing.T) {
+	store, err := NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"", "../../etc/passwd", "ABCDEF", BlobKey(nil)[:63] + "g"} {
+		if err := store.Put(context.Background(), key, []byte("x")); err == nil {
+			t.Errorf("key %q is accepted", key)
+		}
+	}
+}
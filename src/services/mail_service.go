@@ -7,20 +7,25 @@ import (
 	"api-mail/main/src/models"
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"github.com/microsoft/kiota-abstractions-go/serialization"
 	jsonserialization "github.com/microsoft/kiota-serialization-json-go"
 	graphmodels "github.com/microsoftgraph/msgraph-sdk-go/models"
 	graphusers "github.com/microsoftgraph/msgraph-sdk-go/users"
-	"github.com/toorop/go-dkim"
-	mail "github.com/xhit/go-simple-mail/v2"
-	"golang.org/x/oauth2"
 	"google.golang.org/api/gmail/v1"
 	"google.golang.org/api/option"
+	"gorm.io/gorm"
 	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	netmail "net/mail"
+	"net/textproto"
 	"strings"
-	"time"
 )
 
 // IsMailAvailable method to check if a mail is available.
@@ -57,303 +62,210 @@ func GetAppMail(app, mailName string, preload ...bool) (models.AppMail, error) {
 	return appMail, nil
 }
 
-// SendSmtpMail sends an email using SMTP.
-func SendSmtpMail(appMail *models.AppMail, fromName, fromMail, to, subject, body, mimeType string, ccs, bccs []string, attachments []requests.SendMailAttachment) error {
-	// SMTP record.
-	var smtp *models.Smtp
-	var err error
+// MatchOauthIdentity returns the address of the authorized account that is the mail or user of the record.
+func MatchOauthIdentity(identities []string, mailName, user string) (string, bool) {
+	for _, identity := range identities {
+		if strings.EqualFold(identity, mailName) || (user != "" && strings.EqualFold(identity, user)) {
+			return identity, true
+		}
+	}
 
-	if appMail.Smtp == nil {
-		smtpID, err := GetSmtpIDByAppMailID(appMail.ID)
+	return "", false
+}
+
+// GetSendPrimaryType determines the provider that sends the mail of the app mail.
+// The requested type goes first, then the primary type and otherwise the first configured provider.
+// The determined type is set as the primary type of the app mail.
+func GetSendPrimaryType(appMail *models.AppMail, mailType *string) (enums.AppMailPrimaryType, error) {
+	primaryType := enums.ToAppMailPrimaryType(&appMail.PrimaryType.String)
+	if mailType != nil {
+		primaryType = enums.ToAppMailPrimaryType(mailType)
+	}
+
+	if primaryType == nil {
+		appMailProviders, err := GetAppMail(appMail.AppName, appMail.MailName, true)
 		if err != nil {
-			return errors.New("smtp ID not found")
+			return "", err
 		}
 
-		if isInCache, err := IsSmtpInCache(smtpID); err != nil {
-			return err
-		} else if isInCache {
-			if smtp, err = GetSmtpFromCache(smtpID); err != nil {
-				return err
-			}
-		} else {
-			if smtp, err = GetSmtp(smtpID); err != nil {
-				return err
-			}
+		var providerType enums.AppMailPrimaryType
+		switch {
+		case appMailProviders.Azure != nil:
+			providerType = enums.Azure
+		case appMailProviders.Gmail != nil:
+			providerType = enums.Gmail
+		default:
+			providerType = enums.SMTP
 		}
-	} else {
-		smtp = appMail.Smtp
+		primaryType = &providerType
 	}
 
-	if smtp != nil {
-		if err := SetSmtpToCache(smtp); err != nil {
-			return err
-		}
-	} else {
-		return errors.New("smtp not found")
-	}
+	appMail.PrimaryType = sql.NullString{String: *primaryType.ToString(), Valid: true}
 
-	// SMTP server configuration.
-	server := mail.NewSMTPClient()
-	server.Host = smtp.Host
-	server.Port = smtp.Port
-	server.Username = smtp.Username
+	return *primaryType, nil
+}
 
-	// Decrypt password.
-	password, err := smtp.DecryptPassword()
+// SendSmtpMail sends an email using SMTP.
+func SendSmtpMail(appMail *models.AppMail, sendMail *requests.SendMail) error {
+	// SMTP record.
+	smtp, err := getSendSmtp(appMail)
 	if err != nil {
 		return err
 	}
 
-	server.Password = password
-
-	// SMTP Encryption.
-	switch smtp.Port {
-	case 465:
-		server.Encryption = mail.EncryptionSSLTLS
-	case 587:
-		server.Encryption = mail.EncryptionSTARTTLS
-	default:
-		server.Encryption = mail.EncryptionNone
+	// Email.
+	message := newMimeMessage(sendMail)
+	msg, err := composeMimeMessage(appMail, message, sendMail)
+	if err != nil {
+		return fmt.Errorf("creating email error: %s", err.Error())
 	}
 
-	server.Authentication = mail.AuthLogin
-	server.KeepAlive = false
-	server.ConnectTimeout = 10 * time.Second
-	server.SendTimeout = 10 * time.Second
+	// Dkim.
+	if msg, err = SignDkimMessage(smtp, appMail.AppName, msg); err != nil {
+		return fmt.Errorf("creating email error: cannot dkim sign message: %s", err.Error())
+	}
 
-	// SMTP client.
-	client, err := server.Connect()
-	if err != nil {
-		return fmt.Errorf("smtp client error: %s", err.Error())
+	// Send email.
+	if err := sendSmtpMessage(smtp, sendMail.FromMail, message.Recipients(), msg); err != nil {
+		return fmt.Errorf("sending email error: %s", err.Error())
 	}
 
-	// Email.
-	var contentType mail.ContentType
-	switch mimeType {
-	case "text/plain":
-		contentType = mail.TextPlain
-	case "text/html":
-		contentType = mail.TextHTML
-	default:
-		contentType = mail.TextHTML
+	// Sent copy.
+	if appMail.SaveSentCopy {
+		saveSmtpSentCopy(smtp, msg)
 	}
 
-	email := mail.NewMSG()
-	email.SetFrom(fmt.Sprintf("%s <%s>", fromName, fromMail)).
-		AddTo(to).
-		SetSubject(subject).
-		SetBody(contentType, body)
+	return nil
+}
 
-	if len(ccs) > 0 {
-		email.AddCc(ccs...)
-	}
+// SendGmailMail sends an email using the Gmail API.
+func SendGmailMail(appMail *models.AppMail, sendMail *requests.SendMail) error {
+	// Create the message.
+	message := newMimeMessage(sendMail)
+	message.IncludeBcc = true
 
-	if len(bccs) > 0 {
-		email.AddBcc(bccs...)
+	msg, err := composeMimeMessage(appMail, message, sendMail)
+	if err != nil {
+		return fmt.Errorf("error creating gmail message: %s", err.Error())
 	}
 
-	if len(attachments) > 0 {
-		for _, attachment := range attachments {
-			email.Attach(&mail.File{
-				Name:     attachment.FileName,
-				MimeType: attachment.FileType,
-				Data:     attachment.FileData,
-				Inline:   false,
-			})
-		}
-	}
+	return SendGmailRawMail(appMail, message.Recipients(), msg)
+}
 
-	// Dkim.
-	if smtp.DkimPrivateKey != nil && smtp.DkimDomain != nil && smtp.DkimCanonicalizationName != nil {
-		var canonicalization string
-		switch enums.ToDkimCanonicalization(*smtp.DkimCanonicalizationName) {
-		case enums.Simple:
-			canonicalization = "simple/simple"
-		case enums.Relaxed:
-			canonicalization = "relaxed/relaxed"
-		}
+// SendAzureMail sends an email using the Microsoft Graph API.
+// A calendar invitation can't be described in the JSON format, so a mail with an event is sent in the MIME format.
+// The MIME format is also used for the SMTP delivery.
+func SendAzureMail(appMail *models.AppMail, sendMail *requests.SendMail) error {
+	// Azure record.
+	azure, err := getSendAzure(appMail)
+	if err != nil {
+		return err
+	}
 
-		options := dkim.NewSigOptions()
-		options.PrivateKey = []byte(*smtp.DkimPrivateKey)
-		options.Domain = *smtp.DkimDomain
-		options.Selector = "default"
-		options.SignatureExpireIn = 3600
-		options.Headers = []string{"from", "date", "mime-version", "received", "received"}
-		options.AddSignatureTimestamp = true
-		options.Canonicalization = canonicalization
+	if sendMail.Event != nil || azure.SmtpDelivery {
+		message := newMimeMessage(sendMail)
+		message.FromName = ""
+		message.FromMail = appMail.MailName
+		message.IncludeBcc = true
 
-		email.SetDkim(options)
-	}
+		msg, err := composeMimeMessage(appMail, message, sendMail)
+		if err != nil {
+			return fmt.Errorf("error creating mime message: %s", err.Error())
+		}
 
-	if email.Error != nil {
-		return fmt.Errorf("creating email error: %s", email.Error)
+		return SendAzureRawMail(appMail, message.Recipients(), msg)
 	}
 
-	// Send email.
-	if err := email.Send(client); err != nil {
-		return fmt.Errorf("sending email error: %s", err.Error())
+	// Azure client.
+	client, err := getSendAzureClient(context.Background(), azure)
+	if err != nil {
+		return err
 	}
 
-	return nil
-}
-
-func SendGmailMail(appMail *models.AppMail, fromName, fromMail, to, subject, body, mimeType string, ccs, bccs []string, attachments []requests.SendMailAttachment) error {
-	// Gmail record.
-	var gmailRecord *models.Gmail
-	var err error
-	ctx := context.Background()
-
-	if appMail.Gmail == nil {
-		gmailID, err := GetGmailIDByAppMailID(appMail.ID)
+	// Graph accepts about 3 MB of attachments in one request, larger attachments are uploaded to a draft that is sent.
+	// Graph always keeps a sent draft in Sent Items, so without saveSentCopy the copy is removed afterwards.
+	if isAzureUploadRequired(sendMail) {
+		draftID, err := createAzureUploadDraft(client, azure, sendMail)
 		if err != nil {
-			return errors.New("gmail ID not found")
+			return err
 		}
 
-		if isInCache, err := IsGmailInCache(gmailID); err != nil {
-			return err
-		} else if isInCache {
-			if gmailRecord, err = GetGmailFromCache(gmailID); err != nil {
-				return err
-			}
-		} else {
-			if gmailRecord, err = GetGmail(gmailID); err != nil {
+		internetMessageID := ""
+		if !appMail.SaveSentCopy {
+			if internetMessageID, err = getAzureInternetMessageID(client, azure, draftID); err != nil {
+				deleteAzureDraft(client, azure, draftID)
 				return err
 			}
 		}
-	} else {
-		gmailRecord = appMail.Gmail
-	}
 
-	if gmailRecord != nil {
-		if err := SetGmailToCache(gmailRecord); err != nil {
+		if err := postAzureDraftSend(client, azure, draftID); err != nil {
+			deleteAzureDraft(client, azure, draftID)
 			return err
 		}
-	} else {
-		return errors.New("gmail not found")
-	}
 
-	// Create OAuth2 config.
-	if !gmailRecord.AccessToken.Valid ||
-		!gmailRecord.RefreshToken.Valid ||
-		!gmailRecord.TokenType.Valid ||
-		!gmailRecord.Expiry.Valid ||
-		!gmailRecord.ExpiresIn.Valid {
-		return errors.New("gmail record not authenticated")
-	}
-
-	oauthConfig := CreateGmailOauthConfig(gmailRecord.ClientID, gmailRecord.Secret)
-	client := oauthConfig.Client(ctx, &oauth2.Token{
-		AccessToken:  gmailRecord.AccessToken.String,
-		TokenType:    gmailRecord.TokenType.String,
-		RefreshToken: gmailRecord.RefreshToken.String,
-		Expiry:       gmailRecord.Expiry.Time,
-		ExpiresIn:    gmailRecord.ExpiresIn.Int64,
-	})
+		if internetMessageID != "" {
+			deleteAzureSentCopy(client, azure, internetMessageID)
+		}
 
-	gmailService, err := gmail.NewService(ctx, option.WithHTTPClient(client))
-	if err != nil {
-		return fmt.Errorf("error getting gmail service: %s", err.Error())
+		return nil
 	}
 
-	// Create the message.
-	header := make(map[string]string)
-	header["From"] = fmt.Sprintf("%s <%s>", fromName, fromMail)
-	header["To"] = to
-	header["Cc"] = strings.Join(ccs, ",")
-	header["Bcc"] = strings.Join(bccs, ",")
-	header["Subject"] = subject
-	header["MIME-Version"] = "1.0"
-	header["Content-Type"] = "multipart/mixed; boundary=\"boundary\""
+	// Create the email.
+	requestBody := graphusers.NewItemSendMailPostRequestBody()
+	requestBody.SetMessage(newAzureMessage(sendMail))
+	saveToSentItems := appMail.SaveSentCopy
+	requestBody.SetSaveToSentItems(&saveToSentItems)
 
-	var msg string
-	for k, v := range header {
-		msg += fmt.Sprintf("%s: %s\n", k, v)
+	requestBodyJson, err := serializeAzureBody(requestBody)
+	if err != nil {
+		return err
 	}
-	msg += "\n--boundary\n"
-	msg += fmt.Sprintf("Content-Type: %s; charset=\"utf-8\"\n", mimeType)
-	msg += "Content-Transfer-Encoding: base64\n\n"
-	msg += base64.StdEncoding.EncodeToString([]byte(body)) + "\n"
 
-	if len(attachments) > 0 {
-		for _, attachment := range attachments {
-			msg += "--boundary\n"
-			msg += fmt.Sprintf("Content-Type: %s; name=%q\n", attachment.FileType, attachment.FileName)
-			msg += "Content-Transfer-Encoding: base64\n"
-			msg += fmt.Sprintf("Content-Disposition: attachment; filename=%q\n\n", attachment.FileName)
-			msg += base64.StdEncoding.EncodeToString(attachment.FileData) + "\n"
-		}
-		msg += "--boundary--"
+	// Send the mail via microsoft graph
+	resp, err := client.Post(getAzureCloud(azure).graphURL("/me/sendMail"), "application/json", bytes.NewBuffer(requestBodyJson))
+	if err != nil {
+		return fmt.Errorf("error while sending mail: %s", err.Error())
 	}
 
-	gMsg := gmail.Message{
-		Raw: base64.URLEncoding.EncodeToString([]byte(msg)),
+	// Expect a 202 or throw an error
+	if resp.StatusCode != 202 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error sending mail: %s, Body: %s", resp.Status, string(bodyBytes))
 	}
 
-	// Send the message
-	_, err = gmailService.Users.Messages.Send("me", &gMsg).Do()
-	if err != nil {
-		return fmt.Errorf("error sending gmail message: %s", err.Error())
+	if err = resp.Body.Close(); err != nil {
+		return fmt.Errorf("error closing response body: %s", err.Error())
 	}
 
 	return nil
 }
 
-func SendAzureMail(appMail *models.AppMail, to, subject, body, mimeType string, ccs, bccs []string, attachments []requests.SendMailAttachment) error {
-	// Azure record.
-	var azure *models.Azure
-	var err error
-	ctx := context.Background()
-
-	if appMail.Azure == nil {
-		azureID, err := GetAzureIDByAppMailID(appMail.ID)
-		if err != nil {
-			return errors.New("azure ID not found")
-		}
+// SendSmtpRawMail sends a raw RFC 822 message using SMTP.
+// The message is written as DATA without modifications, except for the Bcc header that is stripped.
+func SendSmtpRawMail(appMail *models.AppMail, from string, recipients []string, raw []byte) error {
+	// SMTP record.
+	smtp, err := getSendSmtp(appMail)
+	if err != nil {
+		return err
+	}
 
-		if isInCache, err := IsAzureInCache(azureID); err != nil {
-			return err
-		} else if isInCache {
-			if azure, err = GetAzureFromCache(azureID); err != nil {
-				return err
-			}
-		} else {
-			if azure, err = GetAzure(azureID); err != nil {
-				return err
-			}
-		}
-	} else {
-		azure = appMail.Azure
+	// Send email.
+	if err := sendSmtpMessage(smtp, from, recipients, stripBccHeader(raw)); err != nil {
+		return fmt.Errorf("sending email error: %s", err.Error())
 	}
 
-	if azure != nil {
-		if err := SetAzureToCache(azure); err != nil {
-			return err
-		}
-	} else {
-		return errors.New("azure not found")
+	// Sent copy, with the Bcc header like the copy of a mail client.
+	if appMail.SaveSentCopy {
+		saveSmtpSentCopy(smtp, raw)
 	}
 
-	// Create OAuth2 config.
-	if !azure.AccessToken.Valid ||
-		!azure.RefreshToken.Valid ||
-		!azure.TokenType.Valid ||
-		!azure.Expiry.Valid ||
-		!azure.ExpiresIn.Valid {
-		return errors.New("azure not authenticated")
-	}
-
-	oauthConfig := CreateAzureOauthConfig(azure.ClientID, azure.TenantID, azure.Secret)
-	client := oauthConfig.Client(ctx, &oauth2.Token{
-		AccessToken:  azure.AccessToken.String,
-		TokenType:    azure.TokenType.String,
-		RefreshToken: azure.RefreshToken.String,
-		Expiry:       azure.Expiry.Time,
-		ExpiresIn:    azure.ExpiresIn.Int64,
-	})
+	return nil
+}
 
-	// Create the email.
+// newAzureMessage creates the Graph message of the send-mail.
+func newAzureMessage(sendMail *requests.SendMail) graphmodels.Messageable {
 	var contentType graphmodels.BodyType
-	switch mimeType {
+	switch sendMail.MimeType {
 	case "text/plain":
 		contentType = graphmodels.TEXT_BODYTYPE
 	case "text/html":
@@ -362,18 +274,17 @@ func SendAzureMail(appMail *models.AppMail, to, subject, body, mimeType string,
 		contentType = graphmodels.TEXT_BODYTYPE
 	}
 
-	requestBody := graphusers.NewItemSendMailPostRequestBody()
 	message := graphmodels.NewMessage()
-	message.SetSubject(&subject)
+	message.SetSubject(&sendMail.Subject)
 	itemBody := graphmodels.NewItemBody()
 	itemBody.SetContentType(&contentType)
-	itemBody.SetContent(&body)
+	itemBody.SetContent(&sendMail.Body)
 	message.SetBody(itemBody)
 
 	// To recipients.
 	recipient := graphmodels.NewRecipient()
 	emailAddress := graphmodels.NewEmailAddress()
-	emailAddress.SetAddress(&to)
+	emailAddress.SetAddress(&sendMail.To)
 	recipient.SetEmailAddress(emailAddress)
 	toRecipients := []graphmodels.Recipientable{
 		recipient,
@@ -381,32 +292,34 @@ func SendAzureMail(appMail *models.AppMail, to, subject, body, mimeType string,
 	message.SetToRecipients(toRecipients)
 
 	// Cc recipients.
-	for _, cc := range ccs {
+	ccRecipients := make([]graphmodels.Recipientable, 0, len(sendMail.Ccs))
+	for _, cc := range sendMail.Ccs {
 		recipient := graphmodels.NewRecipient()
 		emailAddress := graphmodels.NewEmailAddress()
 		emailAddress.SetAddress(&cc)
 		recipient.SetEmailAddress(emailAddress)
-		ccRecipients := []graphmodels.Recipientable{
-			recipient,
-		}
+		ccRecipients = append(ccRecipients, recipient)
+	}
+	if len(ccRecipients) > 0 {
 		message.SetCcRecipients(ccRecipients)
 	}
 
 	// Bcc recipients.
-	for _, bcc := range bccs {
+	bccRecipients := make([]graphmodels.Recipientable, 0, len(sendMail.Bccs))
+	for _, bcc := range sendMail.Bccs {
 		recipient := graphmodels.NewRecipient()
 		emailAddress := graphmodels.NewEmailAddress()
 		emailAddress.SetAddress(&bcc)
 		recipient.SetEmailAddress(emailAddress)
-		bccRecipients := []graphmodels.Recipientable{
-			recipient,
-		}
+		bccRecipients = append(bccRecipients, recipient)
+	}
+	if len(bccRecipients) > 0 {
 		message.SetBccRecipients(bccRecipients)
 	}
 
 	// Attachments.
 	attaches := make([]graphmodels.Attachmentable, 0)
-	for _, attachment := range attachments {
+	for _, attachment := range sendMail.Attachments {
 		attach := graphmodels.NewFileAttachment()
 		attach.SetName(&attachment.FileName)
 		attach.SetContentType(&attachment.FileType)
@@ -417,22 +330,95 @@ func SendAzureMail(appMail *models.AppMail, to, subject, body, mimeType string,
 		message.SetAttachments(attaches)
 	}
 
-	requestBody.SetMessage(message)
-	saveToSentItems := false
-	requestBody.SetSaveToSentItems(&saveToSentItems)
+	return message
+}
 
+// serializeAzureBody serializes the Graph request body to JSON.
+func serializeAzureBody(body serialization.Parsable) ([]byte, error) {
 	writer := jsonserialization.NewJsonSerializationWriter()
-	if err := requestBody.Serialize(writer); err != nil {
+	if err := body.Serialize(writer); err != nil {
+		return nil, err
+	}
+	content, err := writer.GetSerializedContent()
+	if err != nil {
+		return nil, fmt.Errorf("error serializing request body: %s", err.Error())
+	}
+
+	return append([]byte("{"), append(content, '}')...), nil
+}
+
+// SendGmailRawMail sends a raw RFC 822 message using the Gmail API.
+// With SMTP delivery the message is sent to the recipients through smtp.gmail.com instead.
+func SendGmailRawMail(appMail *models.AppMail, recipients []string, raw []byte) error {
+	// Gmail record.
+	gmailRecord, err := getSendGmail(appMail)
+	if err != nil {
+		return err
+	}
+
+	if gmailRecord.SmtpDelivery {
+		accessToken := func() (string, error) {
+			return GetGmailSmtpToken(context.Background(), gmailRecord)
+		}
+
+		return sendOauthSmtpMail(gmailSmtpHost, appMail.MailName, gmailRecord.UpdatedAt, accessToken, recipients, stripBccHeader(raw))
+	}
+
+	// Gmail service.
+	gmailService, err := getSendGmailService(context.Background(), gmailRecord)
+	if err != nil {
+		return err
+	}
+
+	gMsg, media, err := gmailUploadMessage(raw)
+	if err != nil {
+		return fmt.Errorf("error sending gmail message: %s", err.Error())
+	}
+
+	// Send the message, large messages with a media upload.
+	call := gmailService.Users.Messages.Send("me", gMsg)
+	if media != nil {
+		call = call.Media(bytes.NewReader(raw), media...)
+	}
+	if _, err := call.Do(); err != nil {
+		return fmt.Errorf("error sending gmail message: %s", err.Error())
+	}
+
+	return nil
+}
+
+// SendAzureRawMail sends a raw RFC 822 message using the MIME format of Microsoft Graph sendMail.
+// With SMTP delivery the message is sent to the recipients through the Exchange Online SMTP server of the cloud instead.
+func SendAzureRawMail(appMail *models.AppMail, recipients []string, raw []byte) error {
+	// Azure record.
+	azure, err := getSendAzure(appMail)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAzureMessageSize(int64(len(raw)), azureMaxMessageSize(azure.SmtpDelivery, true)); err != nil {
 		return err
 	}
-	requestBodyJson, err := writer.GetSerializedContent()
+
+	if azure.SmtpDelivery {
+		accessToken := func() (string, error) {
+			return GetAzureSmtpToken(context.Background(), azure)
+		}
+
+		return sendOauthSmtpMail(getAzureCloud(azure).smtpHost, appMail.MailName, azure.UpdatedAt, accessToken, recipients, stripBccHeader(raw))
+	}
+
+	// Azure client.
+	client, err := getSendAzureClient(context.Background(), azure)
 	if err != nil {
-		return fmt.Errorf("error serializing request body: %s", err.Error())
+		return err
 	}
-	requestBodyJson = append([]byte("{"), append(requestBodyJson, '}')...)
+
+	// Graph expects the MIME content base64 encoded as a text/plain body.
+	requestBody := base64.StdEncoding.EncodeToString(raw)
 
 	// Send the mail via microsoft graph
-	resp, err := client.Post("https://graph.microsoft.com/v1.0/me/sendMail", "application/json", bytes.NewBuffer(requestBodyJson))
+	resp, err := client.Post(getAzureCloud(azure).graphURL("/me/sendMail"), "text/plain", strings.NewReader(requestBody))
 	if err != nil {
 		return fmt.Errorf("error while sending mail: %s", err.Error())
 	}
@@ -450,8 +436,187 @@ func SendAzureMail(appMail *models.AppMail, to, subject, body, mimeType string,
 	return nil
 }
 
+// ParseRawMail reads the headers, the body and the attachments of a raw RFC 822 message.
+// It returns the send-mail used for the history and the envelope recipients. The history keeps the text of the body and
+// the attachments in the blob store like a composed mail, the message itself is sent unchanged.
+func ParseRawMail(req *requests.SendRawMail) (*requests.SendMail, []string, error) {
+	message, err := netmail.ReadMessage(bytes.NewReader(req.Raw))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid raw message: %s", err.Error())
+	}
+
+	sendMail := &requests.SendMail{
+		App:         req.App,
+		Mail:        req.Mail,
+		Type:        req.Type,
+		Ccs:         make([]string, 0),
+		Bccs:        make([]string, 0),
+		DisableSave: req.DisableSave,
+		Mode:        req.Mode,
+	}
+
+	// From.
+	from, err := message.Header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return nil, nil, errors.New("raw message has no valid From header")
+	}
+	sendMail.FromName = from[0].Name
+	sendMail.FromMail = from[0].Address
+
+	// Subject.
+	decoder := new(mime.WordDecoder)
+	if subject, err := decoder.DecodeHeader(message.Header.Get("Subject")); err == nil {
+		sendMail.Subject = subject
+	} else {
+		sendMail.Subject = message.Header.Get("Subject")
+	}
+
+	// Recipients.
+	recipients := make([]string, 0)
+	to := make([]string, 0)
+	for _, header := range []string{"To", "Cc", "Bcc"} {
+		addresses, err := message.Header.AddressList(header)
+		if errors.Is(err, netmail.ErrHeaderNotPresent) {
+			continue
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("raw message has an invalid %s header: %s", header, err.Error())
+		}
+
+		for _, address := range addresses {
+			recipients = append(recipients, address.Address)
+
+			switch header {
+			case "To":
+				to = append(to, address.Address)
+			case "Cc":
+				sendMail.Ccs = append(sendMail.Ccs, address.Address)
+			case "Bcc":
+				sendMail.Bccs = append(sendMail.Bccs, address.Address)
+			}
+		}
+	}
+
+	if len(recipients) == 0 {
+		return nil, nil, errors.New("raw message has no recipients")
+	}
+
+	sendMail.To = strings.Join(to, ",")
+
+	if err := readRawMailContent(message, sendMail); err != nil {
+		return nil, nil, err
+	}
+
+	return sendMail, recipients, nil
+}
+
+// rawMailContent is the content of a raw message that is stored in the history instead of the message itself.
+type rawMailContent struct {
+	html        string
+	text        string
+	attachments []requests.SendMailAttachment
+}
+
+// readRawMailContent reads the body and the attachments of the message, the attachments are the parts with a file name
+// or an attachment disposition. The alternatives of the body are not attachments, and the content of a signed or
+// encrypted message is only read as far as it is not encrypted.
+func readRawMailContent(message *netmail.Message, sendMail *requests.SendMail) error {
+	content := &rawMailContent{attachments: make([]requests.SendMailAttachment, 0)}
+	if err := readRawMailPart(textproto.MIMEHeader(message.Header), message.Body, content, false); err != nil {
+		return fmt.Errorf("raw message can't be parsed: %s", err.Error())
+	}
+
+	if content.html != "" {
+		sendMail.Body = content.html
+		sendMail.MimeType = "text/html"
+		sendMail.TextBody = content.text
+	} else {
+		sendMail.Body = content.text
+		sendMail.MimeType = "text/plain"
+	}
+	sendMail.Attachments = content.attachments
+
+	return nil
+}
+
+// readRawMailPart adds the body or the attachments of the MIME part to the content.
+// The parts of an alternative are always body, an attachment of them is never sent as file.
+func readRawMailPart(header textproto.MIMEHeader, body io.Reader, content *rawMailContent, alternative bool) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	mediaType = strings.ToLower(mediaType)
+
+	switch {
+	case mediaType == "multipart/encrypted", mediaType == "application/pkcs7-mime", mediaType == "application/x-pkcs7-mime":
+		return nil
+	case strings.HasPrefix(mediaType, "multipart/"):
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			if err := readRawMailPart(part.Header, part, content, alternative || mediaType == "multipart/alternative"); err != nil {
+				return err
+			}
+
+			// The second part of a signed message is the signature.
+			if mediaType == "multipart/signed" {
+				return nil
+			}
+		}
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	fileName := dispositionParams["filename"]
+	if fileName == "" {
+		fileName = params["name"]
+	}
+	isAttachment := !alternative && (fileName != "" || strings.EqualFold(disposition, "attachment"))
+
+	// The body is only kept when it is text, and only the first HTML and plain text.
+	if !isAttachment && (mediaType != "text/html" || content.html != "") && (mediaType != "text/plain" || content.text != "") {
+		return nil
+	}
+
+	// A multipart reader decodes quoted-printable itself and removes the header.
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case isAttachment:
+		content.attachments = append(content.attachments, requests.SendMailAttachment{
+			FileName: fileName,
+			FileType: mediaType,
+			FileSize: int64(len(data)),
+			FileData: data,
+		})
+	case mediaType == "text/html":
+		content.html = string(data)
+	default:
+		content.text = string(data)
+	}
+
+	return nil
+}
+
 // CreateSendMail creates a new send-mail.
-func CreateSendMail(appMail *models.AppMail, req *requests.SendMail) error {
+// The scans are the verdicts of the attachments in the same order, nil when they were not scanned. The download links
+// and the template version the mail is rendered from are stored with the send-mail, both are optional.
+func CreateSendMail(appMail *models.AppMail, req *requests.SendMail, scans []AttachmentScan, links []models.AttachmentLink, templateVersion *models.TemplateVersion) (*models.SendMail, error) {
 	smtpType := enums.SMTP
 	primaryType := smtpType.ToString()
 
@@ -468,11 +633,16 @@ func CreateSendMail(appMail *models.AppMail, req *requests.SendMail) error {
 		Subject:     req.Subject,
 		Body:        req.Body,
 		MimeType:    req.MimeType,
+		TextBody:    sql.NullString{Valid: req.TextBody != "", String: req.TextBody},
 		Ccs:         make([]models.SendMailCc, 0),
 		Bccs:        make([]models.SendMailBcc, 0),
 		Attachments: make([]models.SendMailAttachment, 0),
 	}
 
+	if templateVersion != nil {
+		sendMail.TemplateVersionID = &templateVersion.ID
+	}
+
 	for _, cc := range req.Ccs {
 		sendMail.Ccs = append(sendMail.Ccs, models.SendMailCc{Cc: cc})
 	}
@@ -481,18 +651,281 @@ func CreateSendMail(appMail *models.AppMail, req *requests.SendMail) error {
 		sendMail.Bccs = append(sendMail.Bccs, models.SendMailBcc{Bcc: bcc})
 	}
 
-	for _, attachment := range req.Attachments {
-		sendMail.Attachments = append(sendMail.Attachments, models.SendMailAttachment{
+	keys := make([]string, len(req.Attachments))
+	for i, attachment := range req.Attachments {
+		keys[i] = BlobKey(attachment.FileData)
+
+		sendMailAttachment := models.SendMailAttachment{
 			FileName: attachment.FileName,
 			FileType: attachment.FileType,
 			FileSize: attachment.FileSize,
-			FileData: attachment.FileData,
-		})
+			FileHash: keys[i],
+		}
+
+		if i < len(scans) {
+			sendMailAttachment.ScanVerdict = sql.NullString{Valid: true, String: string(scans[i].Verdict)}
+			sendMailAttachment.ScanSignature = sql.NullString{Valid: scans[i].Signature != "", String: scans[i].Signature}
+			sendMailAttachment.ScanScanner = sql.NullString{Valid: true, String: scans[i].Scanner}
+			sendMailAttachment.ScannedAt = sql.NullTime{Valid: true, Time: scans[i].ScannedAt}
+		}
+
+		sendMail.Attachments = append(sendMail.Attachments, sendMailAttachment)
 	}
 
-	if result := database.Pg.Create(sendMail); result.Error != nil {
-		return result.Error
+	if err := database.Pg.Transaction(func(tx *gorm.DB) error {
+		if err := LockBlobs(tx, keys...); err != nil {
+			return err
+		}
+
+		for i := range req.Attachments {
+			if _, err := PutBlob(context.Background(), req.Attachments[i].FileData); err != nil {
+				return err
+			}
+		}
+
+		if result := tx.Create(sendMail); result.Error != nil {
+			return result.Error
+		}
+
+		if len(links) > 0 {
+			ids := make([]uint, len(links))
+			for i := range links {
+				ids[i] = links[i].ID
+			}
+
+			if result := tx.Model(&models.AttachmentLink{}).Where("id IN ?", ids).Update("send_mail_id", sendMail.ID); result.Error != nil {
+				return result.Error
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
-	return nil
+	for i := range links {
+		links[i].SendMailID = &sendMail.ID
+	}
+
+	return sendMail, nil
+}
+
+// newMimeMessage creates the MIME message of the send-mail.
+func newMimeMessage(sendMail *requests.SendMail) *MimeMessage {
+	return &MimeMessage{
+		FromName:    sendMail.FromName,
+		FromMail:    sendMail.FromMail,
+		To:          []string{sendMail.To},
+		Ccs:         sendMail.Ccs,
+		Bccs:        sendMail.Bccs,
+		Subject:     sendMail.Subject,
+		Body:        sendMail.Body,
+		MimeType:    sendMail.MimeType,
+		TextBody:    sendMail.TextBody,
+		Attachments: sendMail.Attachments,
+	}
+}
+
+// composeMimeMessage adds the calendar invitation to the message and applies the S/MIME or OpenPGP protection.
+// It returns the complete message.
+func composeMimeMessage(appMail *models.AppMail, message *MimeMessage, sendMail *requests.SendMail) ([]byte, error) {
+	if sendMail.Event != nil {
+		calendar, err := CreateICalendar(sendMail.Event)
+		if err != nil {
+			return nil, fmt.Errorf("creating calendar error: %s", err.Error())
+		}
+
+		message.Calendar = calendar
+		message.Method = sendMail.Event.Method
+	}
+
+	header, entity, err := message.Parts()
+	if err != nil {
+		return nil, err
+	}
+
+	if sendMail.Smime != nil && (sendMail.Smime.Sign || sendMail.Smime.Encrypt) {
+		if entity, err = ProtectSmimeEntity(appMail, entity, message.Recipients(), sendMail.Smime); err != nil {
+			return nil, err
+		}
+	} else if sendMail.Pgp != nil && (sendMail.Pgp.Sign || sendMail.Pgp.Encrypt) {
+		if entity, err = ProtectPgpEntity(appMail, entity, message.Recipients(), sendMail.Pgp); err != nil {
+			return nil, err
+		}
+	}
+
+	return append(header, entity...), nil
+}
+
+// getSendSmtp gets the smtp record of the app mail from the cache or the database.
+func getSendSmtp(appMail *models.AppMail) (*models.Smtp, error) {
+	var smtp *models.Smtp
+
+	if appMail.Smtp == nil {
+		smtpID, err := GetSmtpIDByAppMailID(appMail.ID)
+		if err != nil {
+			return nil, errors.New("smtp ID not found")
+		}
+
+		if isInCache, err := IsSmtpInCache(smtpID); err != nil {
+			return nil, err
+		} else if isInCache {
+			if smtp, err = GetSmtpFromCache(smtpID); err != nil {
+				return nil, err
+			}
+		} else {
+			if smtp, err = GetSmtp(smtpID); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		smtp = appMail.Smtp
+	}
+
+	if smtp != nil {
+		if err := SetSmtpToCache(smtp); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, errors.New("smtp not found")
+	}
+
+	return smtp, nil
+}
+
+// getSendGmail gets the gmail record of the app mail.
+func getSendGmail(appMail *models.AppMail) (*models.Gmail, error) {
+	var gmailRecord *models.Gmail
+
+	if appMail.Gmail == nil {
+		gmailID, err := GetGmailIDByAppMailID(appMail.ID)
+		if err != nil {
+			return nil, errors.New("gmail ID not found")
+		}
+
+		if isInCache, err := IsGmailInCache(gmailID); err != nil {
+			return nil, err
+		} else if isInCache {
+			if gmailRecord, err = GetGmailFromCache(gmailID); err != nil {
+				return nil, err
+			}
+		} else {
+			if gmailRecord, err = GetGmail(gmailID); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		gmailRecord = appMail.Gmail
+	}
+
+	if gmailRecord != nil {
+		if err := SetGmailToCache(gmailRecord); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, errors.New("gmail not found")
+	}
+
+	return gmailRecord, nil
+}
+
+// getSendGmailService creates an authenticated Gmail service for the gmail record.
+func getSendGmailService(ctx context.Context, gmailRecord *models.Gmail) (*gmail.Service, error) {
+	// Create OAuth2 config.
+	token, err := GetGmailToken(gmailRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	oauthConfig := CreateGmailOauthConfig(gmailRecord.ClientID, gmailRecord.Secret, gmailRecord.SmtpDelivery)
+	client := oauthConfig.Client(ctx, token)
+
+	gmailService, err := gmail.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("error getting gmail service: %s", err.Error())
+	}
+
+	return gmailService, nil
+}
+
+// getSendAzure gets the azure record of the app mail.
+func getSendAzure(appMail *models.AppMail) (*models.Azure, error) {
+	var azure *models.Azure
+
+	if appMail.Azure == nil {
+		azureID, err := GetAzureIDByAppMailID(appMail.ID)
+		if err != nil {
+			return nil, errors.New("azure ID not found")
+		}
+
+		if isInCache, err := IsAzureInCache(azureID); err != nil {
+			return nil, err
+		} else if isInCache {
+			if azure, err = GetAzureFromCache(azureID); err != nil {
+				return nil, err
+			}
+		} else {
+			if azure, err = GetAzure(azureID); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		azure = appMail.Azure
+	}
+
+	if azure != nil {
+		if err := SetAzureToCache(azure); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, errors.New("azure not found")
+	}
+
+	return azure, nil
+}
+
+// getSendAzureClient creates an authenticated HTTP client for the azure record.
+func getSendAzureClient(ctx context.Context, azure *models.Azure) (*http.Client, error) {
+	// Create OAuth2 config.
+	token, err := GetAzureToken(azure)
+	if err != nil {
+		return nil, err
+	}
+
+	oauthConfig := CreateAzureOauthConfig(azure)
+	client := oauthConfig.Client(ctx, token)
+
+	return client, nil
+}
+
+// stripBccHeader removes the Bcc header (including folded lines) from a raw message.
+func stripBccHeader(raw []byte) []byte {
+	headerEnd := bytes.Index(raw, []byte("\r\n\r\n"))
+	separator := []byte("\r\n")
+	if headerEnd == -1 {
+		headerEnd = bytes.Index(raw, []byte("\n\n"))
+		separator = []byte("\n")
+	}
+	if headerEnd == -1 {
+		return raw
+	}
+
+	lines := bytes.Split(raw[:headerEnd], separator)
+	headers := make([][]byte, 0, len(lines))
+	skipping := false
+	for _, line := range lines {
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
+			if !skipping {
+				headers = append(headers, line)
+			}
+			continue
+		}
+
+		skipping = bytes.HasPrefix(bytes.ToLower(line), []byte("bcc:"))
+		if !skipping {
+			headers = append(headers, line)
+		}
+	}
+
+	return append(bytes.Join(headers, separator), raw[headerEnd:]...)
 }
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"api-mail/main/src/dto/requests"
+	"strings"
+	"testing"
+
+	graphmodels "github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+func TestParseRawMail(t *testing.T) {
+	raw := strings.ReplaceAll(`From: sender@example.com
+To: to@example.com
+Subject: Report
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="mixed"
+
+--mixed
+Content-Type: multipart/alternative; boundary="alternative"
+
+--alternative
+Content-Type: text/plain
+
+Report
+--alternative
+Content-Type: text/calendar; method=REQUEST
+
+BEGIN:VCALENDAR
+END:VCALENDAR
+--alternative--
+--mixed
+Content-Type: text/csv; name="report.csv"
+Content-Disposition: attachment; filename="report.csv"
+Content-Transfer-Encoding: base64
+
+bmFtZSxtYWlsCg==
+--mixed
+Content-Type: application/octet-stream
+Content-Disposition: attachment; filename*=UTF-8''r%C3%A9sum%C3%A9.txt
+Content-Transfer-Encoding: quoted-printable
+
+r=C3=A9sum=C3=A9
+--mixed--
+`, "\n", "\r\n")
+
+	sendMail, _, err := ParseRawMail(&requests.SendRawMail{Raw: []byte(raw)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sendMail.Body != "Report" || sendMail.MimeType != "text/plain" {
+		t.Errorf("body is %s %q", sendMail.MimeType, sendMail.Body)
+	}
+
+	attachments := sendMail.Attachments
+	if len(attachments) != 2 {
+		t.Fatalf("read %d attachments, want 2", len(attachments))
+	}
+	if attachments[0].FileName != "report.csv" || attachments[0].FileType != "text/csv" || string(attachments[0].FileData) != "name,mail\n" {
+		t.Errorf("first attachment is %s %s %q", attachments[0].FileName, attachments[0].FileType, attachments[0].FileData)
+	}
+	if attachments[1].FileName != "résumé.txt" || string(attachments[1].FileData) != "résumé" {
+		t.Errorf("second attachment is %s %q", attachments[1].FileName, attachments[1].FileData)
+	}
+}
+
+func TestParseRawMailEncrypted(t *testing.T) {
+	raw := strings.ReplaceAll(`From: sender@example.com
+To: to@example.com
+MIME-Version: 1.0
+Content-Type: application/pkcs7-mime; smime-type=enveloped-data; name="smime.p7m"
+Content-Disposition: attachment; filename="smime.p7m"
+Content-Transfer-Encoding: base64
+
+MIAGCSqGSIb3DQEHA6CAMIACAQA=
+`, "\n", "\r\n")
+
+	sendMail, _, err := ParseRawMail(&requests.SendRawMail{Raw: []byte(raw)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sendMail.Attachments) != 0 || sendMail.Body != "" {
+		t.Errorf("read %d attachments and body %q of an encrypted message", len(sendMail.Attachments), sendMail.Body)
+	}
+}
+
+func TestParseRawMailHtml(t *testing.T) {
+	raw := strings.ReplaceAll(`From: sender@example.com
+To: to@example.com
+Subject: Hello
+MIME-Version: 1.0
+Content-Type: multipart/alternative; boundary="alternative"
+
+--alternative
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+H=C3=A9llo
+--alternative
+Content-Type: text/html; charset=utf-8
+Content-Transfer-Encoding: base64
+
+PHA+SMOpbGxvPC9wPg==
+--alternative--
+`, "\n", "\r\n")
+
+	sendMail, _, err := ParseRawMail(&requests.SendRawMail{Raw: []byte(raw)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sendMail.MimeType != "text/html" || sendMail.Body != "<p>Héllo</p>" || sendMail.TextBody != "Héllo" {
+		t.Errorf("body is %s %q with text %q", sendMail.MimeType, sendMail.Body, sendMail.TextBody)
+	}
+	if len(sendMail.Attachments) != 0 {
+		t.Errorf("read %d attachments", len(sendMail.Attachments))
+	}
+}
+
+func TestNewAzureMessageRecipients(t *testing.T) {
+	sendMail := &requests.SendMail{
+		To:   "to@example.com",
+		Ccs:  []string{"cc1@example.com", "cc2@example.com"},
+		Bccs: []string{"bcc1@example.com", "bcc2@example.com", "bcc3@example.com"},
+	}
+
+	message := newAzureMessage(sendMail)
+
+	for _, recipients := range []struct {
+		name string
+		got  []graphmodels.Recipientable
+		want []string
+	}{
+		{"cc", message.GetCcRecipients(), sendMail.Ccs},
+		{"bcc", message.GetBccRecipients(), sendMail.Bccs},
+	} {
+		addresses := make([]string, 0, len(recipients.got))
+		for _, recipient := range recipients.got {
+			addresses = append(addresses, *recipient.GetEmailAddress().GetAddress())
+		}
+
+		if strings.Join(addresses, ",") != strings.Join(recipients.want, ",") {
+			t.Errorf("%s recipients are %q, want %q", recipients.name, addresses, recipients.want)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package services
+
+import (
+	"api-mail/main/src/dto/requests"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	mail "github.com/xhit/go-simple-mail/v2"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// MimeMessage is a mail that is composed into a RFC 5322 message.
+// A calendar invitation is composed by this file, other mails are composed by go-simple-mail.
+// TextBody is the plain text alternative of an HTML body.
+type MimeMessage struct {
+	FromName    string
+	FromMail    string
+	To          []string
+	Ccs         []string
+	Bccs        []string
+	Subject     string
+	Body        string
+	MimeType    string
+	TextBody    string
+	Calendar    string
+	Method      string
+	Attachments []requests.SendMailAttachment
+	// IncludeBcc writes the Bcc header, which is needed by API providers that read the recipients from the headers.
+	IncludeBcc bool
+}
+
+// Recipients returns all envelope recipients of the message.
+func (message *MimeMessage) Recipients() []string {
+	recipients := make([]string, 0, len(message.To)+len(message.Ccs)+len(message.Bccs))
+	recipients = append(recipients, message.To...)
+	recipients = append(recipients, message.Ccs...)
+	recipients = append(recipients, message.Bccs...)
+
+	return recipients
+}
+
+// Parts returns the top-level headers and the MIME entity of the message, this are the content headers followed by the body.
+func (message *MimeMessage) Parts() ([]byte, []byte, error) {
+	if message.Calendar == "" {
+		return message.simpleMailParts()
+	}
+
+	entity, err := message.entity()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return message.header(), entity, nil
+}
+
+// Bytes returns the complete message.
+func (message *MimeMessage) Bytes() ([]byte, error) {
+	header, entity, err := message.Parts()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(header, entity...), nil
+}
+
+// simpleMailParts composes the message with go-simple-mail and splits off the content headers.
+func (message *MimeMessage) simpleMailParts() ([]byte, []byte, error) {
+	from := netmail.Address{Name: message.FromName, Address: message.FromMail}
+
+	email := mail.NewMSG()
+	email.AddBccToHeader = message.IncludeBcc
+	email.SetFrom(from.String()).
+		AddTo(message.To...).
+		SetSubject(message.Subject).
+		AddHeader("Message-ID", messageID(message.FromMail))
+
+	if len(message.Ccs) > 0 {
+		email.AddCc(message.Ccs...)
+	}
+
+	if len(message.Bccs) > 0 {
+		email.AddBcc(message.Bccs...)
+	}
+
+	// Body, with the plain text alternative of an HTML body.
+	if message.MimeType == "text/plain" {
+		email.SetBody(mail.TextPlain, message.Body)
+	} else if message.TextBody != "" {
+		email.SetBody(mail.TextPlain, message.TextBody).
+			AddAlternative(mail.TextHTML, message.Body)
+	} else {
+		email.SetBody(mail.TextHTML, message.Body)
+	}
+
+	for i := range message.Attachments {
+		email.Attach(&mail.File{
+			Name:     message.Attachments[i].FileName,
+			MimeType: message.Attachments[i].FileType,
+			Data:     message.Attachments[i].FileData,
+		})
+	}
+
+	if email.Error != nil {
+		return nil, nil, email.Error
+	}
+
+	return splitContentHeaders([]byte(email.GetMessage()))
+}
+
+// header returns the top-level headers of the calendar invitation, without the content headers.
+func (message *MimeMessage) header() []byte {
+	var buffer bytes.Buffer
+
+	from := netmail.Address{Name: message.FromName, Address: message.FromMail}
+	writeHeader(&buffer, "From", from.String())
+	writeHeader(&buffer, "To", joinAddresses(message.To))
+	if len(message.Ccs) > 0 {
+		writeHeader(&buffer, "Cc", joinAddresses(message.Ccs))
+	}
+	if message.IncludeBcc && len(message.Bccs) > 0 {
+		writeHeader(&buffer, "Bcc", joinAddresses(message.Bccs))
+	}
+	writeHeader(&buffer, "Subject", mime.QEncoding.Encode("utf-8", message.Subject))
+	writeHeader(&buffer, "Date", time.Now().Format(time.RFC1123Z))
+	writeHeader(&buffer, "Message-ID", messageID(message.FromMail))
+	writeHeader(&buffer, "MIME-Version", "1.0")
+
+	return buffer.Bytes()
+}
+
+// entity returns the MIME entity of the calendar invitation.
+func (message *MimeMessage) entity() ([]byte, error) {
+	var buffer bytes.Buffer
+
+	// Body with the calendar as alternative.
+	body, err := message.bodyEntity()
+	if err != nil {
+		return nil, err
+	}
+
+	// Mixed with the attachments.
+	writer := multipart.NewWriter(&buffer)
+	header := fmt.Sprintf("Content-Type: multipart/mixed; boundary=%q\r\n\r\n", writer.Boundary())
+
+	if err := writeEntity(writer, body); err != nil {
+		return nil, err
+	}
+
+	if err := writeBase64Part(writer, "application/ics", "invite.ics", []byte(message.Calendar)); err != nil {
+		return nil, err
+	}
+
+	for i := range message.Attachments {
+		attachment := &message.Attachments[i]
+		if err := writeBase64Part(writer, attachment.FileType, attachment.FileName, attachment.FileData); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return append([]byte(header), buffer.Bytes()...), nil
+}
+
+// bodyEntity returns the text entity with the plain text alternative of an HTML body and the calendar in a
+// multipart/alternative.
+func (message *MimeMessage) bodyEntity() ([]byte, error) {
+	mimeType := message.MimeType
+	if mimeType != "text/plain" {
+		mimeType = "text/html"
+	}
+
+	text, err := textEntity(mimeType, message.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	hasPlainText := mimeType == "text/html" && message.TextBody != ""
+
+	var buffer bytes.Buffer
+	alternative := multipart.NewWriter(&buffer)
+	header := fmt.Sprintf("Content-Type: multipart/alternative; boundary=%q\r\n\r\n", alternative.Boundary())
+
+	// The alternatives are ordered from the plainest to the richest.
+	if hasPlainText {
+		plainText, err := textEntity("text/plain", message.TextBody)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeEntity(alternative, plainText); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeEntity(alternative, text); err != nil {
+		return nil, err
+	}
+
+	calendarHeader := make(textproto.MIMEHeader)
+	calendarHeader.Set("Content-Type", fmt.Sprintf("text/calendar; charset=\"utf-8\"; method=%s", message.Method))
+	calendarHeader.Set("Content-Transfer-Encoding", "base64")
+	part, err := alternative.CreatePart(calendarHeader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(wrapBase64([]byte(message.Calendar))); err != nil {
+		return nil, err
+	}
+
+	if err := alternative.Close(); err != nil {
+		return nil, err
+	}
+
+	return append([]byte(header), buffer.Bytes()...), nil
+}
+
+// textEntity returns the quoted-printable text entity of the MIME type.
+func textEntity(mimeType, body string) ([]byte, error) {
+	var text bytes.Buffer
+	text.WriteString(fmt.Sprintf("Content-Type: %s; charset=\"utf-8\"\r\n", mimeType))
+	text.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	writer := quotedprintable.NewWriter(&text)
+	if _, err := writer.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return text.Bytes(), nil
+}
+
+// writeHeader writes a single header line.
+func writeHeader(buffer *bytes.Buffer, key, value string) {
+	buffer.WriteString(key)
+	buffer.WriteString(": ")
+	buffer.WriteString(value)
+	buffer.WriteString("\r\n")
+}
+
+// writeEntity writes an entity, with its own content headers, as part of a multipart.
+func writeEntity(writer *multipart.Writer, entity []byte) error {
+	headerEnd := bytes.Index(entity, []byte("\r\n\r\n"))
+	if headerEnd == -1 {
+		return fmt.Errorf("invalid mime entity")
+	}
+
+	header := make(textproto.MIMEHeader)
+	for _, line := range strings.Split(string(entity[:headerEnd]), "\r\n") {
+		if key, value, found := strings.Cut(line, ": "); found {
+			header.Add(key, value)
+		}
+	}
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = part.Write(entity[headerEnd+4:])
+
+	return err
+}
+
+// writeBase64Part writes a base64 encoded attachment as part of a multipart.
+// The file name is a RFC 2231 parameter, because an encoded-word is not allowed in a quoted string.
+func writeBase64Part(writer *multipart.Writer, mimeType, fileName string, data []byte) error {
+	contentType := mime.FormatMediaType(mimeType, map[string]string{"name": fileName})
+	if contentType == "" {
+		contentType = mime.FormatMediaType("application/octet-stream", map[string]string{"name": fileName})
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "base64")
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = part.Write(wrapBase64(data))
+
+	return err
+}
+
+// splitContentHeaders splits the message into the top-level headers and the MIME entity with the content headers.
+// The content headers are unfolded, so the entity can be written as part of a multipart.
+func splitContentHeaders(msg []byte) ([]byte, []byte, error) {
+	headerEnd := bytes.Index(msg, []byte("\r\n\r\n"))
+	if headerEnd == -1 {
+		return nil, nil, fmt.Errorf("invalid mime message")
+	}
+
+	var header, entity bytes.Buffer
+	fields := make([]string, 0)
+	for _, line := range strings.Split(string(msg[:headerEnd]), "\r\n") {
+		if len(fields) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			fields[len(fields)-1] += "\r\n" + line
+		} else {
+			fields = append(fields, line)
+		}
+	}
+
+	for _, field := range fields {
+		if strings.HasPrefix(strings.ToLower(field), "content-") {
+			entity.WriteString(unfoldHeader(field) + "\r\n")
+		} else {
+			header.WriteString(field + "\r\n")
+		}
+	}
+
+	entity.WriteString("\r\n")
+	entity.Write(msg[headerEnd+4:])
+
+	return header.Bytes(), entity.Bytes(), nil
+}
+
+// unfoldHeader joins the folded lines of a header field.
+func unfoldHeader(field string) string {
+	lines := strings.Split(strings.ReplaceAll(field, "\r\n", "\n"), "\n")
+	for i := 1; i < len(lines); i++ {
+		lines[i] = strings.TrimLeft(lines[i], " \t")
+	}
+
+	return strings.Join(lines, " ")
+}
+
+// wrapBase64 encodes the data as base64 with lines of 76 characters.
+func wrapBase64(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	var buffer bytes.Buffer
+	for len(encoded) > 76 {
+		buffer.WriteString(encoded[:76])
+		buffer.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	buffer.WriteString(encoded)
+	buffer.WriteString("\r\n")
+
+	return buffer.Bytes()
+}
+
+// joinAddresses joins mail addresses for an address list header.
+func joinAddresses(addresses []string) string {
+	formatted := make([]string, len(addresses))
+	for i := range addresses {
+		if address, err := netmail.ParseAddress(addresses[i]); err == nil {
+			formatted[i] = address.String()
+		} else {
+			formatted[i] = addresses[i]
+		}
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+// recipientAddress returns the lowercase address of a recipient, which may contain a display name.
+func recipientAddress(recipient string) string {
+	if address, err := netmail.ParseAddress(recipient); err == nil {
+		return strings.ToLower(address.Address)
+	}
+
+	return strings.ToLower(recipient)
+}
+
+// messageID generates a unique Message-ID for the domain of the sender.
+func messageID(fromMail string) string {
+	domain := "localhost"
+	if _, host, found := strings.Cut(fromMail, "@"); found && host != "" {
+		domain = host
+	}
+
+	random := make([]byte, 16)
+	_, _ = rand.Read(random)
+
+	return fmt.Sprintf("<%s.%d@%s>", hex.EncodeToString(random), time.Now().UnixNano(), domain)
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"api-mail/main/src/dto/requests"
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"strings"
+	"testing"
+)
+
+func TestMimeMessageWithoutCalendar(t *testing.T) {
+	message := &MimeMessage{
+		FromName: "Sender",
+		FromMail: "sender@example.com",
+		To:       []string{"to@example.com"},
+		Bccs:     []string{"bcc@example.com"},
+		Subject:  "Hello",
+		Body:     "<p>Hello</p>",
+		MimeType: "text/html",
+		TextBody: "Hello",
+		Attachments: []requests.SendMailAttachment{
+			{FileName: "report.pdf", FileType: "application/pdf", FileData: []byte("%PDF-1.4")},
+		},
+	}
+
+	header, entity, err := message.Parts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(bytes.ToLower(header), []byte("content-type")) {
+		t.Errorf("header contains content headers:\n%s", header)
+	}
+	if !strings.HasPrefix(string(entity), "Content-") {
+		t.Errorf("entity doesn't start with content headers:\n%s", entity)
+	}
+
+	parsed, err := netmail.ReadMessage(bytes.NewReader(append(header, entity...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header.Get("Message-Id") == "" {
+		t.Error("message has no Message-ID")
+	}
+	if parsed.Header.Get("Bcc") != "" {
+		t.Error("message has a Bcc header without IncludeBcc")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("content type is %q: %v", mediaType, err)
+	}
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	alternative, err := reader.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(alternative.Header.Get("Content-Type")); mediaType != "multipart/alternative" {
+		t.Errorf("first part is %q, want multipart/alternative", mediaType)
+	}
+
+	attachment, err := reader.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attachment.FileName() != "report.pdf" {
+		t.Errorf("file name is %q, want report.pdf", attachment.FileName())
+	}
+}
+
+func TestMimeMessageWithCalendar(t *testing.T) {
+	message := &MimeMessage{
+		FromMail: "sender@example.com",
+		To:       []string{"to@example.com"},
+		Subject:  "Meeting",
+		Body:     "Meeting",
+		MimeType: "text/plain",
+		Calendar: "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n",
+		Method:   "REQUEST",
+		Attachments: []requests.SendMailAttachment{
+			{FileName: "agenda é.txt", FileType: "text/plain", FileData: []byte("agenda")},
+		},
+	}
+
+	msg, err := message.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := netmail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	names := make([]string, 0)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		if part.FileName() != "" {
+			names = append(names, part.FileName())
+		}
+	}
+
+	if len(names) != 2 || names[0] != "invite.ics" || names[1] != "agenda é.txt" {
+		t.Errorf("file names are %q", names)
+	}
+	if bytes.Contains(msg, []byte(`filename="=?`)) {
+		t.Error("file name is an encoded-word in a quoted string")
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"api-mail/main/src/configs"
+	"api-mail/main/src/dto/requests"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"strings"
+	"time"
+)
+
+const (
+	multipartMailPart       = "mail"
+	multipartAttachmentPart = "attachments"
+	multipartFilePart       = "file"
+	multipartExpiresAtPart  = "expiresAt"
+)
+
+// ErrAttachmentSize is returned when a streamed attachment exceeds the attachment limits.
+var ErrAttachmentSize = errors.New("attachment size limit exceeded")
+
+// ParseMultipartMail reads a send-mail from a multipart/form-data stream.
+// The "mail" part holds the send-mail as JSON and every "attachments" part is a file that is added to its attachments.
+// The limits are checked while a file is read, and the type of a file is detected from its content instead of the
+// declared Content-Type of the part.
+func ParseMultipartMail(body io.Reader, boundary string, limits configs.AttachmentLimits) (*requests.SendMail, error) {
+	var sendMail *requests.SendMail
+	attachments := make([]requests.SendMailAttachment, 0)
+	total := int64(0)
+
+	reader := multipart.NewReader(body, boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("multipart body can't be read: %s", err.Error())
+		}
+
+		switch part.FormName() {
+		case multipartMailPart:
+			if sendMail != nil {
+				return nil, errors.New("the mail part is sent more than once")
+			}
+
+			sendMail = &requests.SendMail{}
+			if err := json.NewDecoder(io.LimitReader(part, int64(configs.BodyLimit()))).Decode(sendMail); err != nil {
+				return nil, fmt.Errorf("mail part can't be parsed: %s", err.Error())
+			}
+		case multipartAttachmentPart:
+			if part.FileName() == "" {
+				return nil, errors.New("an attachments part has no filename")
+			}
+
+			limit := min(limits.MaxFileSize, limits.MaxTotalSize-total)
+			data, err := io.ReadAll(io.LimitReader(part, limit+1))
+			if err != nil {
+				return nil, fmt.Errorf("attachment %s can't be read: %s", part.FileName(), err.Error())
+			} else if int64(len(data)) > limits.MaxFileSize {
+				return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrAttachmentSize, part.FileName(), limits.MaxFileSize)
+			} else if int64(len(data)) > limit {
+				return nil, fmt.Errorf("%w: the attachments are larger than %d bytes", ErrAttachmentSize, limits.MaxTotalSize)
+			}
+			total += int64(len(data))
+
+			attachments = append(attachments, requests.SendMailAttachment{
+				FileName: part.FileName(),
+				FileType: DetectFileType(part.FileName(), data),
+				FileSize: int64(len(data)),
+				FileData: data,
+			})
+		default:
+			return nil, fmt.Errorf("unknown part %s", part.FormName())
+		}
+
+		_ = part.Close()
+	}
+
+	if sendMail == nil {
+		return nil, errors.New("the mail part is missing")
+	}
+
+	// Attachments in the JSON of the mail part count towards the total as well.
+	for i := range sendMail.Attachments {
+		if total += int64(len(sendMail.Attachments[i].FileData)); total > limits.MaxTotalSize {
+			return nil, fmt.Errorf("%w: the attachments are larger than %d bytes", ErrAttachmentSize, limits.MaxTotalSize)
+		}
+	}
+	sendMail.Attachments = append(sendMail.Attachments, attachments...)
+
+	return sendMail, nil
+}
+
+// ParseMultipartAttachment reads an upload of the attachment library from a multipart/form-data stream.
+// The "file" part is the file, which is refused when it is larger than maxSize, and the optional "expiresAt" part
+// is the RFC 3339 time after which the attachment can't be sent anymore.
+func ParseMultipartAttachment(body io.Reader, boundary string, maxSize int64) (*requests.CreateAttachment, error) {
+	var attachment *requests.CreateAttachment
+	var expiresAt *time.Time
+
+	reader := multipart.NewReader(body, boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("multipart body can't be read: %s", err.Error())
+		}
+
+		switch part.FormName() {
+		case multipartFilePart:
+			if attachment != nil {
+				return nil, errors.New("the file part is sent more than once")
+			} else if part.FileName() == "" {
+				return nil, errors.New("the file part has no filename")
+			}
+
+			data, err := io.ReadAll(io.LimitReader(part, maxSize+1))
+			if err != nil {
+				return nil, fmt.Errorf("file %s can't be read: %s", part.FileName(), err.Error())
+			} else if int64(len(data)) > maxSize {
+				return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrAttachmentSize, part.FileName(), maxSize)
+			}
+
+			attachment = &requests.CreateAttachment{
+				FileName: part.FileName(),
+				FileType: DetectFileType(part.FileName(), data),
+				FileData: data,
+			}
+		case multipartExpiresAtPart:
+			value, err := io.ReadAll(io.LimitReader(part, 64))
+			if err != nil {
+				return nil, fmt.Errorf("expiresAt part can't be read: %s", err.Error())
+			}
+
+			expiry, err := time.Parse(time.RFC3339, strings.TrimSpace(string(value)))
+			if err != nil {
+				return nil, fmt.Errorf("expiresAt is not an RFC 3339 time: %s", err.Error())
+			}
+			expiresAt = &expiry
+		default:
+			return nil, fmt.Errorf("unknown part %s", part.FormName())
+		}
+
+		_ = part.Close()
+	}
+
+	if attachment == nil {
+		return nil, errors.New("the file part is missing")
+	}
+	attachment.ExpiresAt = expiresAt
+
+	return attachment, nil
+}
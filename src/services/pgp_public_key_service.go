@@ -0,0 +1,182 @@
+package services
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/models"
+	"errors"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"strings"
+	"time"
+)
+
+// IsPgpPublicKeyAvailable checks if the OpenPGP public key of the recipient exists.
+func IsPgpPublicKeyAvailable(app, mail string) (bool, error) {
+	var count int64
+	if result := database.Pg.Model(&models.PgpPublicKey{}).
+		Where("app_name = ? AND LOWER(mail) = LOWER(?)", app, mail).
+		Count(&count); result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+// GetPgpPublicKey gets the OpenPGP public key of a recipient.
+func GetPgpPublicKey(id uint, unscoped ...bool) (*models.PgpPublicKey, error) {
+	publicKey := &models.PgpPublicKey{}
+	query := database.Pg
+
+	if len(unscoped) > 0 && unscoped[0] {
+		query = query.Unscoped()
+	}
+
+	if result := query.Find(publicKey, "id = ?", id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return publicKey, nil
+}
+
+// GetMissingPgpPublicKeys gets the recipients of the app without an OpenPGP public key.
+func GetMissingPgpPublicKeys(app string, recipients []string) ([]string, error) {
+	publicKeys, err := getPgpPublicKeysByMail(app, recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make([]string, 0)
+	for _, recipient := range recipients {
+		if _, ok := publicKeys[recipientAddress(recipient)]; !ok {
+			missing = append(missing, recipient)
+		}
+	}
+
+	return missing, nil
+}
+
+// GetPgpRecipientEntities gets the OpenPGP keys of the recipients.
+// A recipient without a key is an error, the mail is never sent unencrypted instead.
+func GetPgpRecipientEntities(app string, recipients []string) ([]*openpgp.Entity, error) {
+	publicKeys, err := getPgpPublicKeysByMail(app, recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgpRecipientEntities(recipients, publicKeys)
+}
+
+// pgpRecipientEntities reads the keys of the recipients from the stored public keys by address.
+func pgpRecipientEntities(recipients []string, publicKeys map[string]models.PgpPublicKey) ([]*openpgp.Entity, error) {
+	entities := make([]*openpgp.Entity, 0, len(recipients))
+	for _, recipient := range recipients {
+		publicKey, ok := publicKeys[recipientAddress(recipient)]
+		if !ok {
+			return nil, fmt.Errorf("recipient %s has no OpenPGP public key", recipient)
+		}
+
+		entity, err := ReadPgpEntity(publicKey.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		entities = append(entities, entity)
+	}
+
+	return entities, nil
+}
+
+// ValidatePgpPublicKey checks that the public key can be read and is able to encrypt.
+func ValidatePgpPublicKey(publicKey string) error {
+	entity, err := ReadPgpEntity(publicKey)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := entity.EncryptionKey(time.Now()); !ok {
+		return errors.New("the OpenPGP key has no valid encryption key")
+	}
+
+	return nil
+}
+
+// CreatePgpPublicKey creates a new OpenPGP public key of a recipient.
+func CreatePgpPublicKey(req *requests.CreatePgpPublicKey) (*models.PgpPublicKey, error) {
+	entity, err := ReadPgpEntity(req.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey := &models.PgpPublicKey{
+		AppName:     req.App,
+		Mail:        strings.ToLower(req.Mail),
+		PublicKey:   req.PublicKey,
+		Fingerprint: pgpFingerprint(entity),
+		UserID:      pgpUserID(entity),
+	}
+
+	if result := database.Pg.Create(publicKey); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return publicKey, nil
+}
+
+// UpdatePgpPublicKey updates a existing OpenPGP public key of a recipient.
+func UpdatePgpPublicKey(oldPublicKey *models.PgpPublicKey, req *requests.UpdatePgpPublicKey) (*models.PgpPublicKey, error) {
+	entity, err := ReadPgpEntity(req.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPublicKey.PublicKey = req.PublicKey
+	oldPublicKey.Fingerprint = pgpFingerprint(entity)
+	oldPublicKey.UserID = pgpUserID(entity)
+
+	if result := database.Pg.Save(oldPublicKey); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return oldPublicKey, nil
+}
+
+// DeletePgpPublicKey deletes a existing OpenPGP public key of a recipient.
+func DeletePgpPublicKey(publicKey *models.PgpPublicKey) error {
+	if result := database.Pg.Delete(publicKey); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// RestorePgpPublicKey restores a deleted OpenPGP public key of a recipient.
+func RestorePgpPublicKey(publicKey *models.PgpPublicKey) error {
+	if result := database.Pg.Model(&publicKey).Unscoped().Update("deleted_at", nil); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// getPgpPublicKeysByMail gets the OpenPGP public keys of the recipients, keyed by the lowercase mail.
+func getPgpPublicKeysByMail(app string, recipients []string) (map[string]models.PgpPublicKey, error) {
+	mails := make([]string, len(recipients))
+	for i := range recipients {
+		mails[i] = recipientAddress(recipients[i])
+	}
+
+	publicKeys := make([]models.PgpPublicKey, 0)
+	if result := database.Pg.
+		Where("app_name = ? AND LOWER(mail) IN ?", app, mails).
+		Order("updated_at").
+		Find(&publicKeys); result.Error != nil {
+		return nil, result.Error
+	}
+
+	byMail := make(map[string]models.PgpPublicKey, len(publicKeys))
+	for i := range publicKeys {
+		byMail[strings.ToLower(publicKeys[i].Mail)] = publicKeys[i]
+	}
+
+	return byMail, nil
+}
@@ -0,0 +1,399 @@
+package services
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/models"
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// pgpConfig is the configuration of the OpenPGP signatures and encryption.
+var pgpConfig = &packet.Config{
+	DefaultHash:   crypto.SHA256,
+	DefaultCipher: packet.CipherAES256,
+}
+
+// IsPgpKeyAvailable checks if the OpenPGP key of the app mail exists.
+func IsPgpKeyAvailable(app, mail string) (bool, error) {
+	var count int64
+	if result := database.Pg.Model(&models.PgpKey{}).
+		Joins("JOIN app_mails ON app_mails.id = pgp_keys.app_mail_id").
+		Where("app_mails.app_name = ? AND app_mails.mail_name = ?", app, mail).
+		Count(&count); result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+// GetPgpKey gets the OpenPGP key.
+func GetPgpKey(id uint, unscoped ...bool) (*models.PgpKey, error) {
+	pgpKey := &models.PgpKey{}
+	query := database.Pg
+
+	if len(unscoped) > 0 && unscoped[0] {
+		query = query.Unscoped()
+	}
+
+	if result := query.Preload("AppMail").Find(pgpKey, "id = ?", id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return pgpKey, nil
+}
+
+// GetPgpKeyByAppMailID gets the OpenPGP key of the app mail.
+func GetPgpKeyByAppMailID(appMailID uint) (*models.PgpKey, error) {
+	pgpKey := &models.PgpKey{}
+
+	if result := database.Pg.Find(pgpKey, "app_mail_id = ?", appMailID); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return pgpKey, nil
+}
+
+// CreatePgpKey creates a new OpenPGP key.
+func CreatePgpKey(req *requests.CreatePgpKey) (*models.PgpKey, error) {
+	entity, err := ReadPgpEntity(req.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	appMail, err := GetAppMail(req.App, req.Mail)
+	if err != nil {
+		return nil, err
+	} else if appMail.ID == 0 {
+		return nil, errors.New("app mail does not exist")
+	}
+
+	pgpKey := &models.PgpKey{
+		AppMailID:   appMail.ID,
+		PrivateKey:  req.PrivateKey,
+		Passphrase:  req.Passphrase,
+		Fingerprint: pgpFingerprint(entity),
+		UserID:      pgpUserID(entity),
+		AppMail:     appMail,
+	}
+
+	if err := pgpKey.EncryptPrivateKey(); err != nil {
+		return nil, err
+	}
+
+	if result := database.Pg.Omit("AppMail").Create(pgpKey); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return pgpKey, nil
+}
+
+// UpdatePgpKey updates a existing OpenPGP key.
+func UpdatePgpKey(oldPgpKey *models.PgpKey, req *requests.UpdatePgpKey) (*models.PgpKey, error) {
+	entity, err := ReadPgpEntity(req.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPgpKey.PrivateKey = req.PrivateKey
+	oldPgpKey.Passphrase = req.Passphrase
+	oldPgpKey.Fingerprint = pgpFingerprint(entity)
+	oldPgpKey.UserID = pgpUserID(entity)
+
+	if err := oldPgpKey.EncryptPrivateKey(); err != nil {
+		return nil, err
+	}
+
+	if result := database.Pg.Omit("AppMail").Save(oldPgpKey); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return oldPgpKey, nil
+}
+
+// DeletePgpKey deletes a existing OpenPGP key.
+func DeletePgpKey(pgpKey *models.PgpKey) error {
+	if result := database.Pg.Delete(pgpKey); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// RestorePgpKey restores a deleted OpenPGP key.
+func RestorePgpKey(pgpKey *models.PgpKey) error {
+	if result := database.Pg.Model(&pgpKey).Unscoped().Update("deleted_at", nil); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// ValidatePgpKey checks that the private key can be unlocked with the passphrase and is able to sign.
+func ValidatePgpKey(privateKey, passphrase string) error {
+	_, err := unlockPgpEntity(privateKey, passphrase)
+
+	return err
+}
+
+// ReadPgpEntity reads the first key of an armored OpenPGP key ring.
+func ReadPgpEntity(armored string) (*openpgp.Entity, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entities) == 0 {
+		return nil, errors.New("no OpenPGP key found")
+	}
+
+	return entities[0], nil
+}
+
+// ProtectPgpEntity signs and/or encrypts the MIME entity with OpenPGP/MIME (RFC 3156).
+// The mail is encrypted for the recipients and for the sender, so it stays readable in the sent items.
+func ProtectPgpEntity(appMail *models.AppMail, entity []byte, recipients []string, options *requests.SendMailPgp) ([]byte, error) {
+	pgpKey, err := GetPgpKeyByAppMailID(appMail.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var signer *openpgp.Entity
+	if pgpKey.ID != 0 {
+		privateKey, passphrase, err := pgpKey.DecryptPrivateKey()
+		if err != nil {
+			return nil, err
+		}
+
+		if signer, err = unlockPgpEntity(privateKey, passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Sign && signer == nil {
+		return nil, errors.New("the mail has no OpenPGP key to sign with")
+	}
+
+	if !options.Encrypt {
+		if entity, err = signPgpEntity(entity, signer); err != nil {
+			return nil, fmt.Errorf("signing OpenPGP error: %s", err.Error())
+		}
+
+		return entity, nil
+	}
+
+	to, err := GetPgpRecipientEntities(appMail.AppName, recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	if signer != nil {
+		to = append(to, signer)
+	}
+
+	// A signed and encrypted mail is signed inside the encrypted data (RFC 3156, section 6.2).
+	var encryptSigner *openpgp.Entity
+	if options.Sign {
+		encryptSigner = signer
+	}
+
+	if entity, err = encryptPgpEntity(entity, to, encryptSigner); err != nil {
+		return nil, fmt.Errorf("encrypting OpenPGP error: %s", err.Error())
+	}
+
+	return entity, nil
+}
+
+// signPgpEntity wraps the entity in a multipart/signed entity with a detached signature.
+func signPgpEntity(entity []byte, signer *openpgp.Entity) ([]byte, error) {
+	var signature bytes.Buffer
+	if err := openpgp.DetachSign(&signature, signer, bytes.NewReader(entity), pgpConfig); err != nil {
+		return nil, err
+	}
+
+	// The micalg parameter must name the hash that was really used by the key.
+	signaturePacket, err := packet.Read(bytes.NewReader(signature.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	micalg := "pgp-sha256"
+	if typed, ok := signaturePacket.(*packet.Signature); ok {
+		micalg = pgpMicalg(typed.Hash)
+	}
+
+	armored, err := armorPgp("PGP SIGNATURE", signature.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+	header := fmt.Sprintf(
+		"Content-Type: multipart/signed; protocol=\"application/pgp-signature\"; micalg=%s; boundary=%q\r\n\r\n",
+		micalg,
+		writer.Boundary(),
+	)
+
+	// The signed entity must be written byte for byte, so it is not written as a part.
+	buffer.WriteString("--" + writer.Boundary() + "\r\n")
+	buffer.Write(entity)
+	buffer.WriteString("\r\n")
+
+	signatureHeader := make(textproto.MIMEHeader)
+	signatureHeader.Set("Content-Type", "application/pgp-signature; name=\"signature.asc\"")
+	signatureHeader.Set("Content-Description", "OpenPGP digital signature")
+	signatureHeader.Set("Content-Disposition", "attachment; filename=\"signature.asc\"")
+	part, err := writer.CreatePart(signatureHeader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(armored); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return append([]byte(header), buffer.Bytes()...), nil
+}
+
+// encryptPgpEntity replaces the entity with a multipart/encrypted entity, signed inside when a signer is given.
+func encryptPgpEntity(entity []byte, to []*openpgp.Entity, signer *openpgp.Entity) ([]byte, error) {
+	var encrypted bytes.Buffer
+	plaintext, err := openpgp.Encrypt(&encrypted, to, signer, &openpgp.FileHints{IsBinary: true}, pgpConfig)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := plaintext.Write(entity); err != nil {
+		return nil, err
+	}
+	if err := plaintext.Close(); err != nil {
+		return nil, err
+	}
+
+	armored, err := armorPgp("PGP MESSAGE", encrypted.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+	header := fmt.Sprintf(
+		"Content-Type: multipart/encrypted; protocol=\"application/pgp-encrypted\"; boundary=%q\r\n\r\n",
+		writer.Boundary(),
+	)
+
+	versionHeader := make(textproto.MIMEHeader)
+	versionHeader.Set("Content-Type", "application/pgp-encrypted")
+	versionHeader.Set("Content-Description", "PGP/MIME version identification")
+	part, err := writer.CreatePart(versionHeader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write([]byte("Version: 1\r\n")); err != nil {
+		return nil, err
+	}
+
+	messageHeader := make(textproto.MIMEHeader)
+	messageHeader.Set("Content-Type", "application/octet-stream; name=\"encrypted.asc\"")
+	messageHeader.Set("Content-Description", "OpenPGP encrypted message")
+	messageHeader.Set("Content-Disposition", "inline; filename=\"encrypted.asc\"")
+	if part, err = writer.CreatePart(messageHeader); err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(armored); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return append([]byte(header), buffer.Bytes()...), nil
+}
+
+// unlockPgpEntity reads the private key and decrypts it with the passphrase.
+func unlockPgpEntity(privateKey, passphrase string) (*openpgp.Entity, error) {
+	entity, err := ReadPgpEntity(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if entity.PrivateKey == nil {
+		return nil, errors.New("the OpenPGP key is not a private key")
+	}
+
+	if entity.PrivateKey.Encrypted {
+		if passphrase == "" {
+			return nil, errors.New("the OpenPGP private key is protected by a passphrase")
+		}
+
+		if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := entity.SigningKey(time.Now()); !ok {
+		return nil, errors.New("the OpenPGP key has no valid signing key")
+	}
+
+	return entity, nil
+}
+
+// armorPgp armors the OpenPGP data with CRLF line endings.
+func armorPgp(blockType string, data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer, err := armor.Encode(&buffer, blockType, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	armored := strings.ReplaceAll(buffer.String(), "\r\n", "\n")
+
+	return []byte(strings.ReplaceAll(armored, "\n", "\r\n") + "\r\n"), nil
+}
+
+// pgpMicalg returns the micalg parameter of the hash.
+func pgpMicalg(hash crypto.Hash) string {
+	switch hash {
+	case crypto.SHA1:
+		return "pgp-sha1"
+	case crypto.SHA224:
+		return "pgp-sha224"
+	case crypto.SHA384:
+		return "pgp-sha384"
+	case crypto.SHA512:
+		return "pgp-sha512"
+	default:
+		return "pgp-sha256"
+	}
+}
+
+// pgpFingerprint returns the uppercase hexadecimal fingerprint of the primary key.
+func pgpFingerprint(entity *openpgp.Entity) string {
+	return strings.ToUpper(fmt.Sprintf("%x", entity.PrimaryKey.Fingerprint))
+}
+
+// pgpUserID returns the primary user ID of the key.
+func pgpUserID(entity *openpgp.Entity) string {
+	if identity := entity.PrimaryIdentity(); identity != nil {
+		return identity.Name
+	}
+
+	return ""
+}
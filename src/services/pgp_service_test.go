@@ -0,0 +1,117 @@
+package services
+
+import (
+	"api-mail/main/src/models"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// newPgpEntity creates an OpenPGP key of the address.
+func newPgpEntity(t *testing.T, address string) *openpgp.Entity {
+	entity, err := openpgp.NewEntity("Test", "", address, pgpConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return entity
+}
+
+// armorPgpPublicKey returns the armored public key of the entity, as it is stored for a recipient.
+func armorPgpPublicKey(t *testing.T, entity *openpgp.Entity) string {
+	var buffer bytes.Buffer
+	writer, err := armor.Encode(&buffer, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(writer); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.String()
+}
+
+func TestSignPgpEntity(t *testing.T) {
+	signer := newPgpEntity(t, "sender@example.com")
+
+	entity, err := signPgpEntity([]byte(testEntity), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, signaturePart := splitSignedEntity(t, entity)
+	if string(signed) != testEntity {
+		t.Errorf("signed content is %q", signed)
+	}
+
+	_, signature, _ := bytes.Cut(signaturePart, []byte("\r\n\r\n"))
+	keyring := openpgp.EntityList{signer}
+
+	if _, err := openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(signed), bytes.NewReader(signature), pgpConfig); err != nil {
+		t.Errorf("signature is not valid: %v", err)
+	}
+
+	changed := strings.NewReader(strings.Replace(testEntity, "Hello", "Hallo", 1))
+	if _, err := openpgp.CheckArmoredDetachedSignature(keyring, changed, bytes.NewReader(signature), pgpConfig); err == nil {
+		t.Error("signature of changed content is valid")
+	}
+}
+
+func TestEncryptPgpEntity(t *testing.T) {
+	recipient := newPgpEntity(t, "to@example.com")
+	signer := newPgpEntity(t, "sender@example.com")
+
+	entity, err := encryptPgpEntity([]byte(testEntity), []*openpgp.Entity{recipient, signer}, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := bytes.Index(entity, []byte("-----BEGIN PGP MESSAGE-----"))
+	if start < 0 {
+		t.Fatalf("entity has no encrypted message:\n%s", entity)
+	}
+
+	for _, key := range []*openpgp.Entity{recipient, signer} {
+		block, err := armor.Decode(bytes.NewReader(entity[start:]))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		message, err := openpgp.ReadMessage(block.Body, openpgp.EntityList{key, signer}, nil, pgpConfig)
+		if err != nil {
+			t.Fatalf("%s can't decrypt: %v", pgpUserID(key), err)
+		}
+
+		decrypted, err := io.ReadAll(message.UnverifiedBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(decrypted) != testEntity {
+			t.Errorf("decrypted entity is %q", decrypted)
+		}
+		if !message.IsSigned || message.SignatureError != nil {
+			t.Errorf("signed %t, signature error %v", message.IsSigned, message.SignatureError)
+		}
+	}
+}
+
+func TestPgpRecipientEntities(t *testing.T) {
+	publicKeys := map[string]models.PgpPublicKey{
+		"to@example.com": {PublicKey: armorPgpPublicKey(t, newPgpEntity(t, "to@example.com"))},
+	}
+
+	if entities, err := pgpRecipientEntities([]string{"To <TO@example.com>"}, publicKeys); err != nil || len(entities) != 1 {
+		t.Errorf("read %d keys: %v", len(entities), err)
+	}
+
+	if _, err := pgpRecipientEntities([]string{"to@example.com", "cc@example.com"}, publicKeys); err == nil || !strings.Contains(err.Error(), "cc@example.com") {
+		t.Errorf("error of a recipient without key is %v", err)
+	}
+}
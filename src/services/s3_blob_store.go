@@ -0,0 +1,83 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"io"
+	"net/http"
+)
+
+// S3BlobStore stores the blobs as objects in a bucket of S3 or an S3-compatible storage such as MinIO.
+type S3BlobStore struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3BlobStore creates an S3 blob store for the bucket at the endpoint, such as s3.amazonaws.com or localhost:9000.
+func NewS3BlobStore(endpoint, region, bucket, accessKey, secretKey string, useSsl bool) (*S3BlobStore, error) {
+	if endpoint == "" || bucket == "" {
+		return nil, errors.New("the s3 blob store needs an endpoint and a bucket")
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSsl,
+		Region: region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 client error: %s", err.Error())
+	}
+
+	return &S3BlobStore{client: client, bucket: bucket}, nil
+}
+
+// Put uploads the object unless an object with the key already exists.
+func (s *S3BlobStore) Put(ctx context.Context, key string, data []byte) error {
+	if _, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{}); err == nil {
+		return nil
+	} else if minio.ToErrorResponse(err).StatusCode != http.StatusNotFound {
+		return err
+	}
+
+	_, err := s.client.PutObject(ctx, s.bucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+
+	return err
+}
+
+// Get downloads the object.
+func (s *S3BlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	return io.ReadAll(object)
+}
+
+// Open opens the object, it is downloaded while it is read.
+// The object is checked first, because GetObject only reports a missing object on the first read.
+func (s *S3BlobStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := object.Stat(); err != nil {
+		_ = object.Close()
+		return nil, err
+	}
+
+	return object, nil
+}
+
+// Delete removes the object.
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
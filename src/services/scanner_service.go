@@ -0,0 +1,88 @@
+package services
+
+import (
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/enums"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// AttachmentScanner scans the attachments of the send pipeline for malware.
+type AttachmentScanner interface {
+	// Name identifies the scanner in the recorded verdicts.
+	Name() string
+	// Scan reads the content and returns the verdict, an error when the content could not be scanned.
+	Scan(ctx context.Context, content io.Reader) (AttachmentScan, error)
+}
+
+// AttachmentScan is the verdict of the scan of one attachment.
+type AttachmentScan struct {
+	Verdict   enums.ScanVerdict
+	Signature string
+	Scanner   string
+	ScannedAt time.Time
+}
+
+// attachmentScanner is the scanner of the send pipeline, nil disables scanning.
+var attachmentScanner AttachmentScanner
+
+// SetupAttachmentScanner sets the clamd scanner of CLAMD_ADDRESS, scanning is disabled when it is empty.
+func SetupAttachmentScanner() error {
+	address := os.Getenv("CLAMD_ADDRESS")
+	if address == "" {
+		return nil
+	}
+
+	scanner, err := NewClamdScanner(address, os.Getenv("CLAMD_TIMEOUT"))
+	if err != nil {
+		return err
+	}
+
+	SetAttachmentScanner(scanner)
+
+	return nil
+}
+
+// SetAttachmentScanner replaces the scanner of the send pipeline, nil disables scanning.
+func SetAttachmentScanner(scanner AttachmentScanner) {
+	attachmentScanner = scanner
+}
+
+// ScanAttachments scans every attachment and returns the verdicts in the order of the attachments.
+// Without a scanner nothing is scanned and the verdicts are nil.
+func ScanAttachments(ctx context.Context, attachments []requests.SendMailAttachment) ([]AttachmentScan, error) {
+	if attachmentScanner == nil {
+		return nil, nil
+	}
+
+	scans := make([]AttachmentScan, len(attachments))
+	for i := range attachments {
+		scan, err := attachmentScanner.Scan(ctx, bytes.NewReader(attachments[i].FileData))
+		if err != nil {
+			return nil, fmt.Errorf("attachment %s can't be scanned: %s", attachments[i].FileName, err.Error())
+		}
+
+		scans[i] = scan
+	}
+
+	return scans, nil
+}
+
+// ScanRawMail scans the complete message, the scanner finds the attachments in the MIME parts.
+// Without a scanner the message is not scanned and the verdict is nil.
+func ScanRawMail(ctx context.Context, raw []byte) (*AttachmentScan, error) {
+	if attachmentScanner == nil {
+		return nil, nil
+	}
+
+	scan, err := attachmentScanner.Scan(ctx, bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("raw message can't be scanned: %s", err.Error())
+	}
+
+	return &scan, nil
+}
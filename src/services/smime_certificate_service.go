@@ -0,0 +1,165 @@
+package services
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/models"
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// IsSmimeCertificateAvailable checks if the S/MIME certificate of the recipient exists.
+func IsSmimeCertificateAvailable(app, mail string) (bool, error) {
+	var count int64
+	if result := database.Pg.Model(&models.SmimeCertificate{}).
+		Where("app_name = ? AND LOWER(mail) = LOWER(?)", app, mail).
+		Count(&count); result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+// GetSmimeCertificate gets the S/MIME certificate of a recipient.
+func GetSmimeCertificate(id uint, unscoped ...bool) (*models.SmimeCertificate, error) {
+	certificate := &models.SmimeCertificate{}
+	query := database.Pg
+
+	if len(unscoped) > 0 && unscoped[0] {
+		query = query.Unscoped()
+	}
+
+	if result := query.Find(certificate, "id = ?", id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return certificate, nil
+}
+
+// GetMissingSmimeCertificates gets the recipients of the app without a S/MIME certificate.
+func GetMissingSmimeCertificates(app string, recipients []string) ([]string, error) {
+	certificates, err := getSmimeCertificatesByMail(app, recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make([]string, 0)
+	for _, recipient := range recipients {
+		if _, ok := certificates[recipientAddress(recipient)]; !ok {
+			missing = append(missing, recipient)
+		}
+	}
+
+	return missing, nil
+}
+
+// GetSmimeRecipientCertificates gets the parsed S/MIME certificates of the recipients.
+func GetSmimeRecipientCertificates(app string, recipients []string) ([]*x509.Certificate, error) {
+	certificates, err := getSmimeCertificatesByMail(app, recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	return smimeRecipientCertificates(recipients, certificates)
+}
+
+// smimeRecipientCertificates parses the certificates of the recipients from the stored certificates by address.
+func smimeRecipientCertificates(recipients []string, certificates map[string]models.SmimeCertificate) ([]*x509.Certificate, error) {
+	parsed := make([]*x509.Certificate, 0, len(recipients))
+	for _, recipient := range recipients {
+		certificate, ok := certificates[recipientAddress(recipient)]
+		if !ok {
+			return nil, fmt.Errorf("recipient %s has no S/MIME certificate", recipient)
+		}
+
+		chain, err := ParseSmimeCertificates(certificate.Certificate)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed = append(parsed, chain[0])
+	}
+
+	return parsed, nil
+}
+
+// CreateSmimeCertificate creates a new S/MIME certificate of a recipient.
+func CreateSmimeCertificate(req *requests.CreateSmimeCertificate) (*models.SmimeCertificate, error) {
+	certificates, err := ParseSmimeCertificates(req.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate := &models.SmimeCertificate{
+		AppName:     req.App,
+		Mail:        strings.ToLower(req.Mail),
+		Certificate: req.Certificate,
+		Subject:     certificates[0].Subject.String(),
+		ExpiresAt:   certificates[0].NotAfter,
+	}
+
+	if result := database.Pg.Create(certificate); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return certificate, nil
+}
+
+// UpdateSmimeCertificate updates a existing S/MIME certificate of a recipient.
+func UpdateSmimeCertificate(oldCertificate *models.SmimeCertificate, req *requests.UpdateSmimeCertificate) (*models.SmimeCertificate, error) {
+	certificates, err := ParseSmimeCertificates(req.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	oldCertificate.Certificate = req.Certificate
+	oldCertificate.Subject = certificates[0].Subject.String()
+	oldCertificate.ExpiresAt = certificates[0].NotAfter
+
+	if result := database.Pg.Save(oldCertificate); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return oldCertificate, nil
+}
+
+// DeleteSmimeCertificate deletes a existing S/MIME certificate of a recipient.
+func DeleteSmimeCertificate(certificate *models.SmimeCertificate) error {
+	if result := database.Pg.Delete(certificate); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// RestoreSmimeCertificate restores a deleted S/MIME certificate of a recipient.
+func RestoreSmimeCertificate(certificate *models.SmimeCertificate) error {
+	if result := database.Pg.Model(&certificate).Unscoped().Update("deleted_at", nil); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// getSmimeCertificatesByMail gets the S/MIME certificates of the recipients, keyed by the lowercase mail.
+func getSmimeCertificatesByMail(app string, recipients []string) (map[string]models.SmimeCertificate, error) {
+	mails := make([]string, len(recipients))
+	for i := range recipients {
+		mails[i] = recipientAddress(recipients[i])
+	}
+
+	certificates := make([]models.SmimeCertificate, 0)
+	if result := database.Pg.
+		Where("app_name = ? AND LOWER(mail) IN ?", app, mails).
+		Order("updated_at").
+		Find(&certificates); result.Error != nil {
+		return nil, result.Error
+	}
+
+	byMail := make(map[string]models.SmimeCertificate, len(certificates))
+	for i := range certificates {
+		byMail[strings.ToLower(certificates[i].Mail)] = certificates[i]
+	}
+
+	return byMail, nil
+}
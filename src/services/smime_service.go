@@ -0,0 +1,334 @@
+package services
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/models"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/smallstep/pkcs7"
+	"mime/multipart"
+	"strings"
+)
+
+func init() {
+	// The package defaults to DES, which mail clients reject.
+	pkcs7.ContentEncryptionAlgorithm = pkcs7.EncryptionAlgorithmAES256CBC
+}
+
+// IsSmimeAvailable checks if the S/MIME record of the app mail exists.
+func IsSmimeAvailable(app, mail string) (bool, error) {
+	var count int64
+	if result := database.Pg.Model(&models.Smime{}).
+		Joins("JOIN app_mails ON app_mails.id = smimes.app_mail_id").
+		Where("app_mails.app_name = ? AND app_mails.mail_name = ?", app, mail).
+		Count(&count); result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+// GetSmime gets the S/MIME record.
+func GetSmime(id uint, unscoped ...bool) (*models.Smime, error) {
+	smime := &models.Smime{}
+	query := database.Pg
+
+	if len(unscoped) > 0 && unscoped[0] {
+		query = query.Unscoped()
+	}
+
+	if result := query.Preload("AppMail").Find(smime, "id = ?", id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return smime, nil
+}
+
+// GetSmimeByAppMailID gets the S/MIME record of the app mail.
+func GetSmimeByAppMailID(appMailID uint) (*models.Smime, error) {
+	smime := &models.Smime{}
+
+	if result := database.Pg.Find(smime, "app_mail_id = ?", appMailID); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return smime, nil
+}
+
+// CreateSmime creates a new S/MIME record.
+func CreateSmime(req *requests.CreateSmime) (*models.Smime, error) {
+	certificates, err := ParseSmimeCertificates(req.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	appMail, err := GetAppMail(req.App, req.Mail)
+	if err != nil {
+		return nil, err
+	} else if appMail.ID == 0 {
+		return nil, errors.New("app mail does not exist")
+	}
+
+	smime := &models.Smime{
+		AppMailID:   appMail.ID,
+		Certificate: req.Certificate,
+		PrivateKey:  req.PrivateKey,
+		Subject:     certificates[0].Subject.String(),
+		ExpiresAt:   certificates[0].NotAfter,
+		AppMail:     appMail,
+	}
+
+	if err := smime.EncryptPrivateKey(); err != nil {
+		return nil, err
+	}
+
+	if result := database.Pg.Omit("AppMail").Create(smime); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return smime, nil
+}
+
+// UpdateSmime updates a existing S/MIME record.
+// The private key is kept when it is not given.
+func UpdateSmime(oldSmime *models.Smime, req *requests.UpdateSmime) (*models.Smime, error) {
+	certificates, err := ParseSmimeCertificates(req.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	oldSmime.Certificate = req.Certificate
+	oldSmime.Subject = certificates[0].Subject.String()
+	oldSmime.ExpiresAt = certificates[0].NotAfter
+
+	if req.PrivateKey != "" {
+		oldSmime.PrivateKey = req.PrivateKey
+		if err := oldSmime.EncryptPrivateKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	if result := database.Pg.Omit("AppMail").Save(oldSmime); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return oldSmime, nil
+}
+
+// DeleteSmime deletes a existing S/MIME record.
+func DeleteSmime(smime *models.Smime) error {
+	if result := database.Pg.Delete(smime); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// RestoreSmime restores a deleted S/MIME record.
+func RestoreSmime(smime *models.Smime) error {
+	if result := database.Pg.Model(&smime).Unscoped().Update("deleted_at", nil); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// ValidateSmime checks that the certificate and the private key can be parsed and belong together.
+func ValidateSmime(certificate, privateKey string) error {
+	certificates, err := ParseSmimeCertificates(certificate)
+	if err != nil {
+		return err
+	}
+
+	_, err = parseSmimePrivateKey(privateKey, certificates[0])
+
+	return err
+}
+
+// ParseSmimeCertificates parses the PEM certificate, followed by its optional chain.
+func ParseSmimeCertificates(certificate string) ([]*x509.Certificate, error) {
+	certificates := make([]*x509.Certificate, 0)
+	rest := []byte(certificate)
+
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		parsed, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certificates = append(certificates, parsed)
+	}
+
+	if len(certificates) == 0 {
+		return nil, errors.New("no PEM certificate found")
+	}
+
+	return certificates, nil
+}
+
+// ProtectSmimeEntity signs and/or encrypts the MIME entity with S/MIME.
+// Besides the certificates of the recipients the entity is encrypted for the certificate of the mail itself,
+// otherwise the sender can't open the copy in its own mailbox.
+func ProtectSmimeEntity(appMail *models.AppMail, entity []byte, recipients []string, options *requests.SendMailSmime) ([]byte, error) {
+	smime, err := GetSmimeByAppMailID(appMail.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var certificates []*x509.Certificate
+	if smime.ID != 0 {
+		if certificates, err = ParseSmimeCertificates(smime.Certificate); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Sign {
+		if smime.ID == 0 {
+			return nil, errors.New("the mail has no S/MIME certificate to sign with")
+		}
+
+		privateKey, err := smime.DecryptPrivateKey()
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := parseSmimePrivateKey(privateKey, certificates[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if entity, err = signSmimeEntity(entity, certificates, key); err != nil {
+			return nil, fmt.Errorf("signing S/MIME error: %s", err.Error())
+		}
+	}
+
+	if options.Encrypt {
+		recipientCertificates, err := GetSmimeRecipientCertificates(appMail.AppName, recipients)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(certificates) > 0 {
+			recipientCertificates = append(recipientCertificates, certificates[0])
+		}
+
+		if entity, err = encryptSmimeEntity(entity, recipientCertificates); err != nil {
+			return nil, fmt.Errorf("encrypting S/MIME error: %s", err.Error())
+		}
+	}
+
+	return entity, nil
+}
+
+// signSmimeEntity wraps the entity in a multipart/signed entity with a detached signature.
+func signSmimeEntity(entity []byte, certificates []*x509.Certificate, key crypto.PrivateKey) ([]byte, error) {
+	signedData, err := pkcs7.NewSignedData(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	signedData.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	if err := signedData.AddSignerChain(certificates[0], key, certificates[1:], pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, err
+	}
+	signedData.Detach()
+
+	signature, err := signedData.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+	header := fmt.Sprintf(
+		"Content-Type: multipart/signed; protocol=\"application/pkcs7-signature\"; micalg=sha-256; boundary=%q\r\n\r\n",
+		writer.Boundary(),
+	)
+
+	// The signed entity must be written byte for byte, so it is not written as a part.
+	buffer.WriteString("--" + writer.Boundary() + "\r\n")
+	buffer.Write(entity)
+	buffer.WriteString("\r\n")
+
+	if err := writeBase64Part(writer, "application/pkcs7-signature", "smime.p7s", signature); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return append([]byte(header), buffer.Bytes()...), nil
+}
+
+// encryptSmimeEntity replaces the entity with an application/pkcs7-mime enveloped-data entity.
+func encryptSmimeEntity(entity []byte, certificates []*x509.Certificate) ([]byte, error) {
+	encrypted, err := pkcs7.Encrypt(entity, certificates)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("Content-Type: application/pkcs7-mime; smime-type=enveloped-data; name=\"smime.p7m\"\r\n")
+	buffer.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buffer.WriteString("Content-Disposition: attachment; filename=\"smime.p7m\"\r\n\r\n")
+	buffer.Write(wrapBase64(encrypted))
+
+	return buffer.Bytes(), nil
+}
+
+// parseSmimePrivateKey parses the PEM private key and checks that it belongs to the certificate.
+func parseSmimePrivateKey(privateKey string, certificate *x509.Certificate) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, errors.New("no PEM private key found")
+	}
+
+	var key crypto.PrivateKey
+	var err error
+	switch strings.TrimSpace(block.Type) {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var publicKey interface{ Equal(crypto.PublicKey) bool }
+	switch typed := key.(type) {
+	case *rsa.PrivateKey:
+		publicKey = &typed.PublicKey
+	case *ecdsa.PrivateKey:
+		publicKey = &typed.PublicKey
+	case ed25519.PrivateKey:
+		publicKey = typed.Public().(ed25519.PublicKey)
+	default:
+		return nil, errors.New("unsupported private key type")
+	}
+
+	if !publicKey.Equal(certificate.PublicKey) {
+		return nil, errors.New("the private key does not belong to the certificate")
+	}
+
+	return key, nil
+}
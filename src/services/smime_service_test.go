@@ -0,0 +1,164 @@
+package services
+
+import (
+	"api-mail/main/src/models"
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"mime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+)
+
+// testEntity is the MIME entity that is signed and encrypted.
+const testEntity = "Content-Type: text/plain; charset=utf-8\r\n\r\nHello\r\n"
+
+// newSmimeCertificate creates a self-signed S/MIME certificate of the address and its key.
+func newSmimeCertificate(t *testing.T, address string) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(time.Now().UnixNano()),
+		Subject:        pkix.Name{CommonName: address},
+		EmailAddresses: []string{address},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return certificate, key
+}
+
+// splitSignedEntity returns the signed content and the signature part of a multipart/signed entity.
+func splitSignedEntity(t *testing.T, entity []byte) ([]byte, []byte) {
+	header, body, ok := bytes.Cut(entity, []byte("\r\n\r\n"))
+	if !ok {
+		t.Fatalf("entity has no header:\n%s", entity)
+	}
+
+	_, params, err := mime.ParseMediaType(strings.TrimPrefix(string(header), "Content-Type: "))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delimiter := []byte("--" + params["boundary"])
+	parts := bytes.Split(body, delimiter)
+	if len(parts) < 4 {
+		t.Fatalf("entity has %d parts:\n%s", len(parts)-2, entity)
+	}
+
+	// The signed content starts after the CRLF of the delimiter and ends before the CRLF of the next delimiter.
+	signed := bytes.TrimSuffix(bytes.TrimPrefix(parts[1], []byte("\r\n")), []byte("\r\n"))
+
+	return signed, parts[2]
+}
+
+func TestSignSmimeEntity(t *testing.T) {
+	certificate, key := newSmimeCertificate(t, "sender@example.com")
+
+	entity, err := signSmimeEntity([]byte(testEntity), []*x509.Certificate{certificate}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, signaturePart := splitSignedEntity(t, entity)
+	if string(signed) != testEntity {
+		t.Errorf("signed content is %q", signed)
+	}
+
+	_, encoded, _ := bytes.Cut(signaturePart, []byte("\r\n\r\n"))
+	signature, err := base64.StdEncoding.DecodeString(strings.NewReplacer("\r", "", "\n", "", "-", "").Replace(string(encoded)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p7, err := pkcs7.Parse(signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p7.Content = signed
+
+	if err := p7.Verify(); err != nil {
+		t.Errorf("signature is not valid: %v", err)
+	}
+
+	p7.Content = []byte(strings.Replace(testEntity, "Hello", "Hallo", 1))
+	if err := p7.Verify(); err == nil {
+		t.Error("signature of changed content is valid")
+	}
+}
+
+func TestEncryptSmimeEntity(t *testing.T) {
+	recipient, recipientKey := newSmimeCertificate(t, "to@example.com")
+	sender, senderKey := newSmimeCertificate(t, "sender@example.com")
+
+	entity, err := encryptSmimeEntity([]byte(testEntity), []*x509.Certificate{recipient, sender})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, encoded, _ := bytes.Cut(entity, []byte("\r\n\r\n"))
+	if !bytes.Contains(header, []byte("smime-type=enveloped-data")) {
+		t.Errorf("header is %s", header)
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(strings.NewReplacer("\r", "", "\n", "").Replace(string(encoded)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p7, err := pkcs7.Parse(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, certificate := range []struct {
+		certificate *x509.Certificate
+		key         *rsa.PrivateKey
+	}{{recipient, recipientKey}, {sender, senderKey}} {
+		decrypted, err := p7.Decrypt(certificate.certificate, certificate.key)
+		if err != nil {
+			t.Fatalf("%s can't decrypt: %v", certificate.certificate.Subject.CommonName, err)
+		}
+		if string(decrypted) != testEntity {
+			t.Errorf("decrypted entity is %q", decrypted)
+		}
+	}
+}
+
+func TestSmimeRecipientCertificates(t *testing.T) {
+	certificate, _ := newSmimeCertificate(t, "to@example.com")
+	certificates := map[string]models.SmimeCertificate{
+		"to@example.com": {Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw}))},
+	}
+
+	if parsed, err := smimeRecipientCertificates([]string{"To <TO@example.com>"}, certificates); err != nil || len(parsed) != 1 {
+		t.Errorf("parsed %d certificates: %v", len(parsed), err)
+	}
+
+	if _, err := smimeRecipientCertificates([]string{"to@example.com", "cc@example.com"}, certificates); err == nil || !strings.Contains(err.Error(), "cc@example.com") {
+		t.Errorf("error of a recipient without certificate is %v", err)
+	}
+}
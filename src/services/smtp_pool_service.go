@@ -0,0 +1,251 @@
+package services
+
+import (
+	"api-mail/main/src/models"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSmtpMaxConnections = 4
+	defaultSmtpIdleTimeout    = 60 * time.Second
+	smtpPoolReapInterval      = 15 * time.Second
+)
+
+// smtpPool keeps the authenticated connections of a smtp open between mails.
+type smtpPool struct {
+	mu          sync.Mutex
+	smtp        models.Smtp
+	dial        func(smtp *models.Smtp) (*smtpClient, error)
+	idle        []*smtpClient
+	slots       chan struct{}
+	keepAlive   bool
+	idleTimeout time.Duration
+	closed      bool
+}
+
+var (
+	smtpPools         = make(map[string]*smtpPool)
+	smtpPoolsMu       sync.Mutex
+	smtpPoolReaperRun sync.Once
+)
+
+// InvalidateSmtpPool closes the idle connections of the smtp, connections in use are closed when they are released.
+func InvalidateSmtpPool(id uint) {
+	key := smtpPoolKey(id)
+
+	smtpPoolsMu.Lock()
+	pool, ok := smtpPools[key]
+	delete(smtpPools, key)
+	smtpPoolsMu.Unlock()
+
+	if ok {
+		pool.close()
+	}
+}
+
+// sendSmtpMessage sends the message through a pooled connection of the smtp.
+// When the server dropped a reused connection before the mail was accepted, it is sent again on another connection.
+func sendSmtpMessage(smtp *models.Smtp, from string, recipients []string, msg []byte) error {
+	return sendPooledSmtpMessage(getSmtpPool(smtpPoolKey(smtp.ID), smtp, newSmtpClient), from, recipients, msg)
+}
+
+// sendPooledSmtpMessage sends the message through a connection of the pool.
+func sendPooledSmtpMessage(pool *smtpPool, from string, recipients []string, msg []byte) error {
+	for {
+		client, err := pool.acquire()
+		if err != nil {
+			return err
+		}
+
+		if err := client.Send(from, recipients, msg); err != nil {
+			pool.release(client, false)
+
+			var replyErr *textproto.Error
+			if client.reused && !client.accepted && !errors.As(err, &replyErr) {
+				continue
+			}
+
+			return err
+		}
+
+		pool.release(client, true)
+
+		return nil
+	}
+}
+
+// getSmtpPool gets the pool of the key, a pool of an older version of the smtp is replaced.
+// The version is compared because the smtp can be updated by another instance of the service.
+// New connections of the pool are opened with dial.
+func getSmtpPool(key string, smtp *models.Smtp, dial func(smtp *models.Smtp) (*smtpClient, error)) *smtpPool {
+	smtpPoolReaperRun.Do(func() {
+		go reapSmtpPools()
+	})
+
+	smtpPoolsMu.Lock()
+	defer smtpPoolsMu.Unlock()
+
+	if pool, ok := smtpPools[key]; ok {
+		if pool.smtp.UpdatedAt.Equal(smtp.UpdatedAt) {
+			return pool
+		}
+
+		go pool.close()
+	}
+
+	maxConnections := defaultSmtpMaxConnections
+	if smtp.MaxConnections != nil && *smtp.MaxConnections > 0 {
+		maxConnections = *smtp.MaxConnections
+	}
+
+	pool := &smtpPool{
+		smtp:        *smtp,
+		dial:        dial,
+		slots:       make(chan struct{}, maxConnections),
+		keepAlive:   smtp.KeepAlive == nil || *smtp.KeepAlive,
+		idleTimeout: smtpTimeout(smtp.IdleTimeout, defaultSmtpIdleTimeout),
+	}
+	smtpPools[key] = pool
+
+	return pool
+}
+
+// acquire takes a free slot and returns a healthy idle connection, or a new connection.
+// It waits for a slot at most the connect timeout when all connections are in use.
+func (pool *smtpPool) acquire() (*smtpClient, error) {
+	select {
+	case pool.slots <- struct{}{}:
+	case <-time.After(smtpTimeout(pool.smtp.ConnectTimeout, defaultSmtpConnectTimeout)):
+		return nil, errors.New("smtp client error: all connections of the smtp are in use")
+	}
+
+	for {
+		client := pool.popIdle()
+		if client == nil {
+			break
+		}
+
+		if err := client.Reset(); err != nil {
+			client.Close()
+			continue
+		}
+
+		client.reused = true
+		client.accepted = false
+
+		return client, nil
+	}
+
+	client, err := pool.dial(&pool.smtp)
+	if err != nil {
+		<-pool.slots
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// release returns the connection to the pool, or closes it when it is broken, keep-alive is off or the pool is closed.
+func (pool *smtpPool) release(client *smtpClient, healthy bool) {
+	defer func() { <-pool.slots }()
+
+	pool.mu.Lock()
+	if healthy && pool.keepAlive && !pool.closed {
+		client.lastUsed = time.Now()
+		pool.idle = append(pool.idle, client)
+		pool.mu.Unlock()
+		return
+	}
+	pool.mu.Unlock()
+
+	if healthy {
+		_ = client.Quit()
+	} else {
+		client.Close()
+	}
+}
+
+// popIdle takes the most recently used idle connection, connections past the idle timeout are closed.
+func (pool *smtpPool) popIdle() *smtpClient {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for len(pool.idle) > 0 {
+		client := pool.idle[len(pool.idle)-1]
+		pool.idle = pool.idle[:len(pool.idle)-1]
+
+		if time.Since(client.lastUsed) < pool.idleTimeout {
+			return client
+		}
+
+		go client.Quit()
+	}
+
+	return nil
+}
+
+// reap closes the idle connections past the idle timeout.
+func (pool *smtpPool) reap() {
+	pool.mu.Lock()
+	idle := pool.idle[:0]
+	expired := make([]*smtpClient, 0)
+	for _, client := range pool.idle {
+		if time.Since(client.lastUsed) < pool.idleTimeout {
+			idle = append(idle, client)
+		} else {
+			expired = append(expired, client)
+		}
+	}
+	pool.idle = idle
+	pool.mu.Unlock()
+
+	for _, client := range expired {
+		_ = client.Quit()
+	}
+}
+
+// close closes all idle connections and makes the pool close the connections in use on release.
+func (pool *smtpPool) close() {
+	pool.mu.Lock()
+	pool.closed = true
+	idle := pool.idle
+	pool.idle = nil
+	pool.mu.Unlock()
+
+	for _, client := range idle {
+		_ = client.Quit()
+	}
+}
+
+// reapSmtpPools closes the expired idle connections of all pools.
+func reapSmtpPools() {
+	ticker := time.NewTicker(smtpPoolReapInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		smtpPoolsMu.Lock()
+		pools := make([]*smtpPool, 0, len(smtpPools))
+		for _, pool := range smtpPools {
+			pools = append(pools, pool)
+		}
+		smtpPoolsMu.Unlock()
+
+		for _, pool := range pools {
+			pool.reap()
+		}
+	}
+}
+
+// smtpPoolKey returns the pool key of the smtp.
+func smtpPoolKey(id uint) string {
+	return fmt.Sprintf("smtp:%d", id)
+}
+
+// oauthSmtpPoolKey returns the pool key of the mailbox of a Gmail or Azure at the SMTP server.
+func oauthSmtpPoolKey(host, mailbox string) string {
+	return fmt.Sprintf("oauth:%s:%s", host, mailbox)
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"api-mail/main/src/models"
+	"net"
+	netsmtp "net/smtp"
+	"testing"
+	"time"
+)
+
+func TestSmtpPoolReusesConnection(t *testing.T) {
+	smtp := newOauthSmtp("mail.example.com", "mailbox@example.com")
+	smtp.UpdatedAt = time.Now()
+
+	dials := 0
+	key := oauthSmtpPoolKey(smtp.Host, smtp.Username)
+	t.Cleanup(func() {
+		smtpPoolsMu.Lock()
+		pool := smtpPools[key]
+		delete(smtpPools, key)
+		smtpPoolsMu.Unlock()
+		pool.close()
+	})
+
+	pool := getSmtpPool(key, smtp, func(smtp *models.Smtp) (*smtpClient, error) {
+		dials++
+
+		clientConn, serverConn := net.Pipe()
+		go serveSmtp(serverConn, []string{"AUTH XOAUTH2"}, make(chan string, 100))
+
+		client, err := netsmtp.NewClient(clientConn, smtp.Host)
+		if err != nil {
+			return nil, err
+		}
+
+		return &smtpClient{client: client, conn: clientConn, sendTimeout: time.Second}, nil
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := sendPooledSmtpMessage(pool, "mailbox@example.com", []string{"to@example.com"}, []byte("Subject: Test\r\n\r\nTest\r\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if dials != 1 {
+		t.Errorf("dialed %d connections, want 1", dials)
+	}
+
+	if getSmtpPool(key, smtp, nil) != pool {
+		t.Error("the pool of the same version is replaced")
+	}
+}
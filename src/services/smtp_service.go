@@ -103,9 +103,25 @@ func CreateSmtp(req *requests.CreateSmtp) (*models.Smtp, error) {
 		Password:                 req.Password,
 		Host:                     req.Host,
 		Port:                     req.Port,
+		SmtpEncryptionName:       req.Encryption,
+		SmtpAuthName:             req.Auth,
+		TlsServerName:            req.TlsServerName,
+		CaBundle:                 req.CaBundle,
+		ConnectTimeout:           req.ConnectTimeout,
+		SendTimeout:              req.SendTimeout,
+		HeloName:                 req.HeloName,
+		KeepAlive:                req.KeepAlive,
+		MaxConnections:           req.MaxConnections,
+		IdleTimeout:              req.IdleTimeout,
+		ImapHost:                 req.ImapHost,
+		ImapPort:                 req.ImapPort,
+		SentFolder:               req.SentFolder,
+		DraftsFolder:             req.DraftsFolder,
 		DkimPrivateKey:           req.DkimPrivateKey,
 		DkimDomain:               req.DkimDomain,
 		DkimCanonicalizationName: req.DkimCanonicalization,
+		DkimSelector:             req.DkimSelector,
+		DkimHeaders:              joinDkimHeaders(req.DkimHeaders),
 		AppMail: models.AppMail{
 			AppName:  req.App,
 			MailName: req.Mail,
@@ -176,9 +192,25 @@ func UpdateSmtp(oldSmtp *models.Smtp, req *requests.UpdateSmtp) (*models.Smtp, e
 	oldSmtp.Username = req.Username
 	oldSmtp.Host = req.Host
 	oldSmtp.Port = req.Port
+	oldSmtp.SmtpEncryptionName = req.Encryption
+	oldSmtp.SmtpAuthName = req.Auth
+	oldSmtp.TlsServerName = req.TlsServerName
+	oldSmtp.CaBundle = req.CaBundle
+	oldSmtp.ConnectTimeout = req.ConnectTimeout
+	oldSmtp.SendTimeout = req.SendTimeout
+	oldSmtp.HeloName = req.HeloName
+	oldSmtp.KeepAlive = req.KeepAlive
+	oldSmtp.MaxConnections = req.MaxConnections
+	oldSmtp.IdleTimeout = req.IdleTimeout
+	oldSmtp.ImapHost = req.ImapHost
+	oldSmtp.ImapPort = req.ImapPort
+	oldSmtp.SentFolder = req.SentFolder
+	oldSmtp.DraftsFolder = req.DraftsFolder
 	oldSmtp.DkimPrivateKey = req.DkimPrivateKey
 	oldSmtp.DkimDomain = req.DkimDomain
 	oldSmtp.DkimCanonicalizationName = req.DkimCanonicalization
+	oldSmtp.DkimSelector = req.DkimSelector
+	oldSmtp.DkimHeaders = joinDkimHeaders(req.DkimHeaders)
 
 	if req.Password != "" {
 		oldSmtp.Password = req.Password
@@ -209,6 +241,8 @@ func UpdateSmtp(oldSmtp *models.Smtp, req *requests.UpdateSmtp) (*models.Smtp, e
 		}
 	}
 
+	InvalidateSmtpPool(oldSmtp.ID)
+
 	return oldSmtp, nil
 }
 
@@ -226,6 +260,8 @@ func DeleteSmtp(smtp *models.Smtp) error {
 		}
 	}
 
+	InvalidateSmtpPool(smtp.ID)
+
 	return nil
 }
 
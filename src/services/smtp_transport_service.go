@@ -0,0 +1,371 @@
+package services
+
+import (
+	"api-mail/main/src/enums"
+	"api-mail/main/src/models"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	netsmtp "net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSmtpConnectTimeout = 10 * time.Second
+	defaultSmtpSendTimeout    = 10 * time.Second
+	defaultSmtpHeloName       = "localhost"
+)
+
+// ErrSmtpAuthNotAdvertised is returned when a mechanism is configured but the server does not offer AUTH.
+var ErrSmtpAuthNotAdvertised = errors.New("the server does not advertise AUTH, set the auth None to send without authentication")
+
+// smtpClient is a connected and authenticated SMTP session.
+type smtpClient struct {
+	client      *netsmtp.Client
+	conn        net.Conn
+	sendTimeout time.Duration
+	lastUsed    time.Time
+	reused      bool
+	accepted    bool
+}
+
+// IsValidCaBundle checks if the CA bundle contains at least one PEM certificate.
+func IsValidCaBundle(caBundle *string) bool {
+	if caBundle == nil || *caBundle == "" {
+		return true
+	}
+
+	return x509.NewCertPool().AppendCertsFromPEM([]byte(*caBundle))
+}
+
+// newSmtpClient connects and authenticates to the SMTP server of the smtp record.
+func newSmtpClient(smtp *models.Smtp) (*smtpClient, error) {
+	password := ""
+	if smtp.Auth() != enums.AuthNone {
+		var err error
+		if password, err = smtp.DecryptPassword(); err != nil {
+			return nil, err
+		}
+	}
+
+	return dialSmtp(smtp, password)
+}
+
+// sendOauthSmtpMail sends the message over STARTTLS and XOAUTH2 through a pooled connection of a Gmail or Azure mailbox.
+// The access token is only requested to open a new connection, and the pool is replaced when the version changes.
+func sendOauthSmtpMail(host, mailbox string, version time.Time, accessToken func() (string, error), recipients []string, msg []byte) error {
+	smtp := newOauthSmtp(host, mailbox)
+	smtp.UpdatedAt = version
+
+	pool := getSmtpPool(oauthSmtpPoolKey(host, mailbox), smtp, func(smtp *models.Smtp) (*smtpClient, error) {
+		token, err := accessToken()
+		if err != nil {
+			return nil, err
+		}
+
+		return dialSmtp(smtp, token)
+	})
+
+	if err := sendPooledSmtpMessage(pool, mailbox, recipients, msg); err != nil {
+		return fmt.Errorf("sending email error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// newOauthSmtp returns the STARTTLS and XOAUTH2 settings of the SMTP server of a Gmail or Azure mailbox.
+func newOauthSmtp(host, mailbox string) *models.Smtp {
+	encryption := string(enums.EncryptionSTARTTLS)
+	auth := string(enums.AuthXOAuth2)
+
+	return &models.Smtp{
+		Host:               host,
+		Port:               587,
+		Username:           mailbox,
+		SmtpEncryptionName: &encryption,
+		SmtpAuthName:       &auth,
+	}
+}
+
+// dialSmtp connects to the SMTP server and authenticates with the password or access token.
+// STARTTLS is required when it is the encryption mode, the server is never used unencrypted instead.
+func dialSmtp(smtp *models.Smtp, password string) (*smtpClient, error) {
+	tlsConfig, err := newSmtpTlsConfig(smtp)
+	if err != nil {
+		return nil, fmt.Errorf("smtp client error: %s", err.Error())
+	}
+
+	connectTimeout := smtpTimeout(smtp.ConnectTimeout, defaultSmtpConnectTimeout)
+	address := net.JoinHostPort(smtp.Host, strconv.Itoa(smtp.Port))
+	dialer := &net.Dialer{Timeout: connectTimeout}
+	encryption := smtp.Encryption()
+
+	var conn net.Conn
+	if encryption == enums.EncryptionSSLTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("smtp client error: %s", err.Error())
+	}
+
+	// The greeting, EHLO, STARTTLS and AUTH must all finish within the connect timeout.
+	if err := conn.SetDeadline(time.Now().Add(connectTimeout)); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("smtp client error: %s", err.Error())
+	}
+
+	client, err := netsmtp.NewClient(conn, smtp.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("smtp client error: %s", err.Error())
+	}
+
+	session := &smtpClient{
+		client:      client,
+		conn:        conn,
+		sendTimeout: smtpTimeout(smtp.SendTimeout, defaultSmtpSendTimeout),
+	}
+
+	if err := session.handshake(smtp, password, encryption, tlsConfig); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("smtp client error: %s", err.Error())
+	}
+
+	return session, nil
+}
+
+// handshake greets the server, starts TLS and authenticates.
+func (s *smtpClient) handshake(smtp *models.Smtp, password string, encryption enums.SmtpEncryption, tlsConfig *tls.Config) error {
+	heloName := defaultSmtpHeloName
+	if smtp.HeloName != nil && *smtp.HeloName != "" {
+		heloName = *smtp.HeloName
+	}
+
+	if err := s.client.Hello(heloName); err != nil {
+		return err
+	}
+
+	if encryption == enums.EncryptionSTARTTLS {
+		if ok, _ := s.client.Extension("STARTTLS"); !ok {
+			return errors.New("the server does not support STARTTLS")
+		}
+
+		if err := s.client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	mechanism := smtp.Auth()
+	if mechanism == enums.AuthNone {
+		return nil
+	}
+
+	// Without AUTH the credentials would be silently dropped, the auth None sends without authentication.
+	ok, mechanisms := s.client.Extension("AUTH")
+	if !ok {
+		return ErrSmtpAuthNotAdvertised
+	}
+
+	if err := s.client.Auth(newSmtpAuth(mechanism, smtp.Username, password, smtp.Host, encryption == enums.EncryptionNone)); err != nil {
+		if !hasSmtpMechanism(mechanisms, mechanism) {
+			return fmt.Errorf("%s authentication failed, the server advertises %s: %w", mechanism, mechanisms, err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Send sends the message to the recipients within the send timeout.
+func (s *smtpClient) Send(from string, recipients []string, msg []byte) error {
+	if err := s.conn.SetDeadline(time.Now().Add(s.sendTimeout)); err != nil {
+		return err
+	}
+
+	if err := s.client.Mail(from); err != nil {
+		return err
+	}
+	s.accepted = true
+
+	for _, recipient := range recipients {
+		if err := s.client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	writer, err := s.client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(msg); err != nil {
+		_ = writer.Close()
+		return err
+	}
+
+	return writer.Close()
+}
+
+// Reset checks that the idle session is still alive with a RSET, which also clears an unfinished transaction.
+func (s *smtpClient) Reset() error {
+	if err := s.conn.SetDeadline(time.Now().Add(s.sendTimeout)); err != nil {
+		return err
+	}
+
+	return s.client.Reset()
+}
+
+// Quit ends the session, the connection is closed when the server does not answer.
+func (s *smtpClient) Quit() error {
+	if err := s.client.Quit(); err != nil {
+		s.Close()
+		return err
+	}
+
+	return nil
+}
+
+// Close closes the connection without a QUIT.
+func (s *smtpClient) Close() {
+	_ = s.client.Close()
+}
+
+// newSmtpTlsConfig creates the TLS config of the smtp with its server name and extra CA certificates.
+func newSmtpTlsConfig(smtp *models.Smtp) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: smtp.Host,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if smtp.TlsServerName != nil && *smtp.TlsServerName != "" {
+		tlsConfig.ServerName = *smtp.TlsServerName
+	}
+
+	if smtp.CaBundle != nil && *smtp.CaBundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM([]byte(*smtp.CaBundle)) {
+			return nil, errors.New("the CA bundle contains no PEM certificates")
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// smtpTimeout converts the timeout in seconds, or returns the fallback when it is not set.
+func smtpTimeout(seconds *int, fallback time.Duration) time.Duration {
+	if seconds == nil || *seconds <= 0 {
+		return fallback
+	}
+
+	return time.Duration(*seconds) * time.Second
+}
+
+// newSmtpAuth creates the SASL client of the mechanism, XOAUTH2 uses the password as the access token.
+// With plaintext PLAIN and LOGIN are also sent without TLS, for a smtp with the encryption None like before.
+// A bearer token is never sent without TLS.
+func newSmtpAuth(mechanism enums.SmtpAuth, username, password, host string, plaintext bool) netsmtp.Auth {
+	var auth netsmtp.Auth
+	switch mechanism {
+	case enums.AuthPlain:
+		auth = netsmtp.PlainAuth("", username, password, host)
+	case enums.AuthCramMD5:
+		return netsmtp.CRAMMD5Auth(username, password)
+	case enums.AuthXOAuth2:
+		return &xoauth2Auth{username: username, token: password}
+	default:
+		auth = &loginAuth{username: username, password: password}
+	}
+
+	if plaintext {
+		return &plaintextAuth{Auth: auth}
+	}
+
+	return auth
+}
+
+// hasSmtpMechanism checks if the mechanism is in the AUTH line of the server.
+func hasSmtpMechanism(mechanisms string, mechanism enums.SmtpAuth) bool {
+	return strings.Contains(" "+strings.ToUpper(mechanisms)+" ", " "+string(mechanism)+" ")
+}
+
+// plaintextAuth starts the mechanism as if the connection uses TLS, so the credentials are sent unencrypted.
+type plaintextAuth struct {
+	netsmtp.Auth
+}
+
+func (a *plaintextAuth) Start(server *netsmtp.ServerInfo) (string, []byte, error) {
+	info := *server
+	info.TLS = true
+
+	return a.Auth.Start(&info)
+}
+
+// loginAuth implements the LOGIN mechanism, which net/smtp does not provide.
+type loginAuth struct {
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *netsmtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch challenge := strings.ToLower(strings.TrimSpace(string(fromServer))); {
+	case strings.HasPrefix(challenge, "user"):
+		return []byte(a.username), nil
+	case strings.HasPrefix(challenge, "pass"):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN challenge: %s", fromServer)
+	}
+}
+
+// xoauth2Auth implements the XOAUTH2 mechanism of Google and Microsoft.
+type xoauth2Auth struct {
+	username string
+	token    string
+}
+
+func (a *xoauth2Auth) Start(server *netsmtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+
+	return "XOAUTH2", []byte("user=" + a.username + "\x01auth=Bearer " + a.token + "\x01\x01"), nil
+}
+
+func (a *xoauth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		// The server sends a JSON error as challenge, an empty response finishes the exchange with the error.
+		return []byte{}, nil
+	}
+
+	return nil, nil
+}
+
+// isLocalhost checks if the host is the local machine, where credentials may be sent without TLS.
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"api-mail/main/src/enums"
+	"api-mail/main/src/models"
+	"encoding/base64"
+	"errors"
+	"net"
+	netsmtp "net/smtp"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// serveSmtp runs a SMTP server on the connection that advertises the extensions and records the commands.
+func serveSmtp(conn net.Conn, extensions []string, commands chan<- string) {
+	defer close(commands)
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	_ = text.PrintfLine("220 mail.example.com ESMTP")
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		commands <- line
+
+		switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+		case "EHLO":
+			lines := append([]string{"mail.example.com"}, extensions...)
+			for i, extension := range lines {
+				separator := "-"
+				if i == len(lines)-1 {
+					separator = " "
+				}
+				_ = text.PrintfLine("250%s%s", separator, extension)
+			}
+		case "AUTH":
+			_ = text.PrintfLine("235 2.7.0 Authentication successful")
+		case "MAIL", "RCPT", "RSET":
+			_ = text.PrintfLine("250 2.0.0 OK")
+		case "DATA":
+			_ = text.PrintfLine("354 Start mail input")
+			if _, err := text.ReadDotBytes(); err != nil {
+				return
+			}
+			_ = text.PrintfLine("250 2.0.0 Queued")
+		case "QUIT":
+			_ = text.PrintfLine("221 2.0.0 Bye")
+			return
+		default:
+			_ = text.PrintfLine("502 5.5.2 Command not implemented")
+		}
+	}
+}
+
+// handshakeSmtp runs the handshake of the smtp against a server with the extensions and returns the commands it received.
+func handshakeSmtp(t *testing.T, smtp *models.Smtp, extensions []string) ([]string, error) {
+	clientConn, serverConn := net.Pipe()
+	commands := make(chan string, 10)
+	go serveSmtp(serverConn, extensions, commands)
+
+	client, err := netsmtp.NewClient(clientConn, smtp.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := &smtpClient{client: client, conn: clientConn}
+	err = session.handshake(smtp, "secret", smtp.Encryption(), nil)
+	_ = session.Quit()
+
+	received := make([]string, 0)
+	for command := range commands {
+		received = append(received, command)
+	}
+
+	return received, err
+}
+
+func TestSmtpHandshakePlaintextAuth(t *testing.T) {
+	auth := string(enums.AuthPlain)
+	smtp := &models.Smtp{Host: "mail.example.com", Port: 25, Username: "user", SmtpAuthName: &auth}
+
+	commands, err := handshakeSmtp(t, smtp, []string{"AUTH PLAIN LOGIN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "AUTH PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00user\x00secret"))
+	if len(commands) < 2 || commands[1] != want {
+		t.Errorf("commands are %q, want %q", commands, want)
+	}
+}
+
+func TestSmtpHandshakeWithoutAuthExtension(t *testing.T) {
+	smtp := &models.Smtp{Host: "mail.example.com", Port: 2525, Username: "user"}
+
+	if _, err := handshakeSmtp(t, smtp, []string{"8BITMIME"}); !errors.Is(err, ErrSmtpAuthNotAdvertised) {
+		t.Errorf("handshake without an AUTH extension returned %v", err)
+	}
+
+	auth := string(enums.AuthNone)
+	smtp.SmtpAuthName = &auth
+
+	commands, err := handshakeSmtp(t, smtp, []string{"8BITMIME"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, command := range commands {
+		if strings.HasPrefix(command, "AUTH") {
+			t.Errorf("authenticated with the auth None: %q", commands)
+		}
+	}
+}
+
+func TestNewSmtpAuthPlaintext(t *testing.T) {
+	server := &netsmtp.ServerInfo{Name: "mail.example.com", TLS: false}
+
+	for _, mechanism := range []enums.SmtpAuth{enums.AuthPlain, enums.AuthLogin, enums.AuthXOAuth2} {
+		if _, _, err := newSmtpAuth(mechanism, "user", "secret", "mail.example.com", false).Start(server); err == nil {
+			t.Errorf("%s is started on an unencrypted connection", mechanism)
+		}
+	}
+
+	for _, mechanism := range []enums.SmtpAuth{enums.AuthPlain, enums.AuthLogin} {
+		if _, _, err := newSmtpAuth(mechanism, "user", "secret", "mail.example.com", true).Start(server); err != nil {
+			t.Errorf("%s is not started with the encryption None: %v", mechanism, err)
+		}
+	}
+
+	if _, _, err := newSmtpAuth(enums.AuthXOAuth2, "user", "token", "mail.example.com", true).Start(server); err == nil {
+		t.Error("XOAUTH2 is started with the encryption None")
+	}
+}
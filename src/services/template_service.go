@@ -0,0 +1,266 @@
+package services
+
+import (
+	"api-mail/main/src/database"
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/models"
+	"bytes"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	htmltemplate "html/template"
+	"io"
+	texttemplate "text/template"
+)
+
+// GetTemplate gets the template of the app.
+func GetTemplate(appName string, id uint) (*models.Template, error) {
+	template := &models.Template{}
+
+	if result := database.Pg.Find(template, "app_name = ? AND id = ?", appName, id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return template, nil
+}
+
+// IsTemplateAvailable checks if the app has another template than the template of the ID with the name.
+func IsTemplateAvailable(appName, name string, id uint) (bool, error) {
+	if result := database.Pg.Limit(1).Find(&models.Template{}, "app_name = ? AND name = ? AND id <> ?", appName, name, id); result.Error != nil {
+		return false, result.Error
+	} else if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// CreateTemplate creates the template with the content of the request as first and active version.
+func CreateTemplate(appName string, req *requests.CreateTemplate) (*models.Template, *models.TemplateVersion, error) {
+	template := &models.Template{
+		AppName:     appName,
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	version := &models.TemplateVersion{
+		Version: 1,
+		Subject: req.Subject,
+		Html:    req.Html,
+		Text:    req.Text,
+	}
+
+	if err := database.Pg.Transaction(func(tx *gorm.DB) error {
+		if result := tx.Omit("App").Create(template); result.Error != nil {
+			return result.Error
+		}
+
+		version.TemplateID = template.ID
+		if result := tx.Omit("Template").Create(version); result.Error != nil {
+			return result.Error
+		}
+
+		template.ActiveVersionID = &version.ID
+		if result := tx.Model(template).Update("active_version_id", version.ID); result.Error != nil {
+			return result.Error
+		}
+
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return template, version, nil
+}
+
+// UpdateTemplate updates the name and description of the template, the content is changed with a new version.
+func UpdateTemplate(template *models.Template, req *requests.UpdateTemplate) (*models.Template, error) {
+	template.Name = req.Name
+	template.Description = req.Description
+
+	if result := database.Pg.Model(template).Select("Name", "Description").Updates(template); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return template, nil
+}
+
+// DeleteTemplate deletes the template with its versions, the mails sent with a version keep their rendered content.
+func DeleteTemplate(template *models.Template) error {
+	if result := database.Pg.Delete(template); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// GetTemplateVersions gets the versions of the template, the newest first.
+func GetTemplateVersions(templateID uint) ([]models.TemplateVersion, error) {
+	versions := make([]models.TemplateVersion, 0)
+
+	if result := database.Pg.Where("template_id = ?", templateID).Order("version DESC").Find(&versions); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return versions, nil
+}
+
+// GetTemplateVersion gets the version of the template by its number.
+func GetTemplateVersion(templateID uint, version int) (*models.TemplateVersion, error) {
+	templateVersion := &models.TemplateVersion{}
+
+	if result := database.Pg.Find(templateVersion, "template_id = ? AND version = ?", templateID, version); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return templateVersion, nil
+}
+
+// GetActiveTemplateVersion gets the version of the template that is sent, the ID is 0 when it has none.
+func GetActiveTemplateVersion(template *models.Template) (*models.TemplateVersion, error) {
+	templateVersion := &models.TemplateVersion{}
+	if template.ActiveVersionID == nil {
+		return templateVersion, nil
+	}
+
+	if result := database.Pg.Find(templateVersion, "id = ?", *template.ActiveVersionID); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return templateVersion, nil
+}
+
+// CreateTemplateVersion adds the content of the request as next version of the template, and activates it when asked.
+// The template is locked, so concurrent requests get consecutive version numbers.
+func CreateTemplateVersion(template *models.Template, req *requests.CreateTemplateVersion) (*models.TemplateVersion, error) {
+	version := &models.TemplateVersion{
+		TemplateID: template.ID,
+		Subject:    req.Subject,
+		Html:       req.Html,
+		Text:       req.Text,
+	}
+
+	if err := database.Pg.Transaction(func(tx *gorm.DB) error {
+		if result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&models.Template{}, "id = ?", template.ID); result.Error != nil {
+			return result.Error
+		}
+
+		if result := tx.Model(&models.TemplateVersion{}).
+			Where("template_id = ?", template.ID).
+			Select("COALESCE(MAX(version), 0) + 1").
+			Scan(&version.Version); result.Error != nil {
+			return result.Error
+		}
+
+		if result := tx.Omit("Template").Create(version); result.Error != nil {
+			return result.Error
+		}
+
+		if req.Activate {
+			if result := tx.Model(template).Update("active_version_id", version.ID); result.Error != nil {
+				return result.Error
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if req.Activate {
+		template.ActiveVersionID = &version.ID
+	}
+
+	return version, nil
+}
+
+// ActivateTemplateVersion makes the version the version of the template that is sent.
+func ActivateTemplateVersion(template *models.Template, version *models.TemplateVersion) error {
+	if result := database.Pg.Model(template).Update("active_version_id", version.ID); result.Error != nil {
+		return result.Error
+	}
+
+	template.ActiveVersionID = &version.ID
+
+	return nil
+}
+
+// ParseTemplateVersion checks that the subject, HTML and text parse, the version needs HTML or text.
+func ParseTemplateVersion(subject string, html, text *string) error {
+	if (html == nil || *html == "") && (text == nil || *text == "") {
+		return errors.New("the template needs html or text")
+	}
+
+	if _, err := texttemplate.New("subject").Parse(subject); err != nil {
+		return err
+	}
+	if html != nil {
+		if _, err := htmltemplate.New("html").Parse(*html); err != nil {
+			return err
+		}
+	}
+	if text != nil {
+		if _, err := texttemplate.New("text").Parse(*text); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RenderSendMailTemplate renders the version with the data of the send-mail into its subject and body.
+// The HTML is the body with the text as plain text alternative, a version with only text is sent as plain text.
+// A variable that is missing in the data is an error.
+func RenderSendMailTemplate(version *models.TemplateVersion, sendMail *requests.SendMail) error {
+	subjectTemplate, err := texttemplate.New("subject").Option("missingkey=error").Parse(version.Subject)
+	if err != nil {
+		return err
+	}
+	subject, err := executeTemplate(subjectTemplate, sendMail.Data)
+	if err != nil {
+		return err
+	}
+
+	var html, text string
+	if version.Html != nil && *version.Html != "" {
+		htmlTemplate, err := htmltemplate.New("html").Option("missingkey=error").Parse(*version.Html)
+		if err != nil {
+			return err
+		}
+		if html, err = executeTemplate(htmlTemplate, sendMail.Data); err != nil {
+			return err
+		}
+	}
+	if version.Text != nil && *version.Text != "" {
+		textTemplate, err := texttemplate.New("text").Option("missingkey=error").Parse(*version.Text)
+		if err != nil {
+			return err
+		}
+		if text, err = executeTemplate(textTemplate, sendMail.Data); err != nil {
+			return err
+		}
+	}
+
+	sendMail.Subject = subject
+	if html != "" {
+		sendMail.Body = html
+		sendMail.TextBody = text
+		sendMail.MimeType = "text/html"
+	} else {
+		sendMail.Body = text
+		sendMail.MimeType = "text/plain"
+	}
+
+	return nil
+}
+
+// executeTemplate executes the text or HTML template with the data.
+func executeTemplate(template interface {
+	Execute(wr io.Writer, data any) error
+}, data map[string]any) (string, error) {
+	var buffer bytes.Buffer
+	if err := template.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"api-mail/main/src/dto/requests"
+	"api-mail/main/src/models"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	graphmodels "github.com/microsoftgraph/msgraph-sdk-go/models"
+	graphusers "github.com/microsoftgraph/msgraph-sdk-go/users"
+)
+
+const (
+	// azureMaxInlineSize is the attachment size Graph accepts inline in one request.
+	azureMaxInlineSize = 3 * 1024 * 1024
+	// azureMaxAttachmentSize is the largest attachment of an upload session.
+	azureMaxAttachmentSize = 150 * 1024 * 1024
+	// azureUploadChunkSize is the size of an upload session chunk, Graph requires a multiple of 320 KiB.
+	azureUploadChunkSize = 12 * 320 * 1024
+	// azureUploadTimeout limits the upload of one chunk.
+	azureUploadTimeout = 2 * time.Minute
+	// azureSentCopyAttempts is how often Sent Items is searched for the copy of a sent draft, Graph sends asynchronously.
+	azureSentCopyAttempts = 5
+	// azureSentCopyInterval is the wait before each search of Sent Items.
+	azureSentCopyInterval = 3 * time.Second
+	// azureMaxMimeSize is the MIME message Graph accepts base64 encoded in one request of 4 MB.
+	azureMaxMimeSize = 3 * 1024 * 1024
+	// azureSmtpMaxMessageSize is the default message size limit of Exchange Online for the SMTP delivery.
+	azureSmtpMaxMessageSize = 35 * 1024 * 1024
+
+	// gmailMaxRawSize is the message size that is still sent base64 in the Raw field of the JSON request.
+	gmailMaxRawSize = 3 * 1024 * 1024
+	// gmailMaxMessageSize is the largest message the Gmail media upload accepts.
+	gmailMaxMessageSize = 35 * 1024 * 1024
+	// gmailUploadChunkSize is the chunk size of the resumable upload, smaller messages are uploaded in one request.
+	gmailUploadChunkSize = 8 * 1024 * 1024
+)
+
+// ErrMessageSize is returned when the message is larger than the delivery path of the provider accepts.
+var ErrMessageSize = errors.New("message size limit exceeded")
+
+// CheckAzureMessage checks before anything is saved that the delivery path of the azure of the app mail accepts the mail.
+// The MIME format and the SMTP delivery have no upload session.
+func CheckAzureMessage(appMail *models.AppMail, sendMail *requests.SendMail, draft bool) error {
+	azure, err := getSendAzure(appMail)
+	if err != nil {
+		return err
+	}
+
+	smtpDelivery := azure.SmtpDelivery && !draft
+
+	return checkAzureMessageSize(estimateMessageSize(sendMail), azureMaxMessageSize(smtpDelivery, sendMail.Event != nil))
+}
+
+// CheckAzureRawMessage checks before anything is saved that the delivery path of the azure of the app mail accepts the raw message.
+func CheckAzureRawMessage(appMail *models.AppMail, raw []byte, draft bool) error {
+	azure, err := getSendAzure(appMail)
+	if err != nil {
+		return err
+	}
+
+	return checkAzureMessageSize(int64(len(raw)), azureMaxMessageSize(azure.SmtpDelivery && !draft, true))
+}
+
+// azureMaxMessageSize returns the largest message of the delivery path: the SMTP delivery, one Graph request in the
+// MIME format, or the JSON format with upload sessions.
+func azureMaxMessageSize(smtpDelivery, mime bool) int64 {
+	switch {
+	case smtpDelivery:
+		return azureSmtpMaxMessageSize
+	case mime:
+		return azureMaxMimeSize
+	default:
+		return azureMaxAttachmentSize
+	}
+}
+
+// checkAzureMessageSize returns ErrMessageSize when the size is larger than the limit.
+func checkAzureMessageSize(size, limit int64) error {
+	if size > limit {
+		return fmt.Errorf("%w: the message is %s, outlook accepts %s on this delivery path", ErrMessageSize, formatFileSize(size), formatFileSize(limit))
+	}
+
+	return nil
+}
+
+// isAzureUploadRequired checks if the attachments are too large to send the message in one Graph request.
+func isAzureUploadRequired(sendMail *requests.SendMail) bool {
+	size := 0
+	for i := range sendMail.Attachments {
+		size += len(sendMail.Attachments[i].FileData)
+	}
+
+	return size > azureMaxInlineSize
+}
+
+// createAzureUploadDraft creates the message as draft and adds the attachments one by one.
+// Attachments of 3 MB and larger are uploaded in chunks with an upload session, the draft is removed when an attachment fails.
+func createAzureUploadDraft(client *http.Client, azure *models.Azure, sendMail *requests.SendMail) (string, error) {
+	for i := range sendMail.Attachments {
+		if len(sendMail.Attachments[i].FileData) > azureMaxAttachmentSize {
+			return "", fmt.Errorf("attachment %s is larger than the 150 MB graph accepts", sendMail.Attachments[i].FileName)
+		}
+	}
+
+	message := *sendMail
+	message.Attachments = nil
+
+	body, err := serializeAzureBody(newAzureMessage(&message))
+	if err != nil {
+		return "", err
+	}
+
+	draftID, err := postAzureDraft(client, azure, "application/json", body)
+	if err != nil {
+		return "", err
+	}
+
+	for i := range sendMail.Attachments {
+		if len(sendMail.Attachments[i].FileData) < azureMaxInlineSize {
+			err = addAzureAttachment(client, azure, draftID, &sendMail.Attachments[i])
+		} else {
+			err = uploadAzureAttachment(client, azure, draftID, &sendMail.Attachments[i])
+		}
+
+		if err != nil {
+			deleteAzureDraft(client, azure, draftID)
+			return "", err
+		}
+	}
+
+	return draftID, nil
+}
+
+// addAzureAttachment adds a small attachment to the draft with Graph /me/messages/{id}/attachments.
+func addAzureAttachment(client *http.Client, azure *models.Azure, draftID string, attachment *requests.SendMailAttachment) error {
+	attach := graphmodels.NewFileAttachment()
+	attach.SetName(&attachment.FileName)
+	attach.SetContentType(&attachment.FileType)
+	attach.SetContentBytes(attachment.FileData)
+
+	body, err := serializeAzureBody(attach)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(getAzureCloud(azure).graphURL("/me/messages/"+url.PathEscape(draftID)+"/attachments"), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error while adding attachment %s: %s", attachment.FileName, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error adding attachment %s: %s, Body: %s", attachment.FileName, resp.Status, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// uploadAzureAttachment creates an upload session for the attachment of the draft and uploads it in chunks.
+// The upload URL is pre-authenticated, so the chunks are sent without the token of the client.
+func uploadAzureAttachment(client *http.Client, azure *models.Azure, draftID string, attachment *requests.SendMailAttachment) error {
+	size := int64(len(attachment.FileData))
+	attachmentType := graphmodels.FILE_ATTACHMENTTYPE
+
+	item := graphmodels.NewAttachmentItem()
+	item.SetAttachmentType(&attachmentType)
+	item.SetName(&attachment.FileName)
+	item.SetContentType(&attachment.FileType)
+	item.SetSize(&size)
+
+	requestBody := graphusers.NewItemMessagesItemAttachmentsCreateUploadSessionPostRequestBody()
+	requestBody.SetAttachmentItem(item)
+
+	body, err := serializeAzureBody(requestBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(getAzureCloud(azure).graphURL("/me/messages/"+url.PathEscape(draftID)+"/attachments/createUploadSession"), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error while creating upload session for %s: %s", attachment.FileName, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error creating upload session for %s: %s, Body: %s", attachment.FileName, resp.Status, string(bodyBytes))
+	}
+
+	var session struct {
+		UploadURL string `json:"uploadUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return fmt.Errorf("error reading upload session: %s", err.Error())
+	} else if session.UploadURL == "" {
+		return errors.New("error reading upload session: the response has no uploadUrl")
+	}
+
+	uploadClient := &http.Client{Timeout: azureUploadTimeout}
+	for start := int64(0); start < size; start += azureUploadChunkSize {
+		end := min(start+azureUploadChunkSize, size)
+
+		if err := putAzureUploadChunk(uploadClient, session.UploadURL, attachment.FileData[start:end], start, size); err != nil {
+			return fmt.Errorf("error uploading attachment %s: %s", attachment.FileName, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// putAzureUploadChunk uploads the chunk that starts at the offset to the upload session.
+func putAzureUploadChunk(client *http.Client, uploadURL string, chunk []byte, offset, size int64) error {
+	req, err := http.NewRequest(http.MethodPut, uploadURL, bytes.NewReader(chunk))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(chunk))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(len(chunk))-1, size))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Graph answers 200 with the next expected range and 201 after the last chunk.
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s, Body: %s", resp.Status, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// deleteAzureDraft removes a draft that could not be completed, a failure leaves the draft behind.
+func deleteAzureDraft(client *http.Client, azure *models.Azure, draftID string) {
+	req, err := http.NewRequest(http.MethodDelete, getAzureCloud(azure).graphURL("/me/messages/"+url.PathEscape(draftID)), nil)
+	if err != nil {
+		return
+	}
+
+	if resp, err := client.Do(req); err == nil {
+		_ = resp.Body.Close()
+	}
+}
+
+// gmailUploadMessage returns the Gmail message and media options of the raw message.
+// A small message is sent base64 in the Raw field, a larger one as message/rfc822 media upload that is resumable in chunks.
+// The media options are nil for the Raw field.
+func gmailUploadMessage(raw []byte) (*gmail.Message, []googleapi.MediaOption, error) {
+	if len(raw) > gmailMaxMessageSize {
+		return nil, nil, errors.New("the message is larger than the 35 MB gmail accepts")
+	}
+
+	if len(raw) <= gmailMaxRawSize {
+		return &gmail.Message{Raw: base64.URLEncoding.EncodeToString(raw)}, nil, nil
+	}
+
+	return &gmail.Message{}, []googleapi.MediaOption{
+		googleapi.ContentType("message/rfc822"),
+		googleapi.ChunkSize(gmailUploadChunkSize),
+	}, nil
+}
+
+// getAzureInternetMessageID returns the Message-ID that Graph assigned to the draft.
+func getAzureInternetMessageID(client *http.Client, azure *models.Azure, draftID string) (string, error) {
+	resp, err := client.Get(getAzureCloud(azure).graphURL("/me/messages/" + url.PathEscape(draftID) + "?$select=internetMessageId"))
+	if err != nil {
+		return "", fmt.Errorf("error while reading draft: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("error reading draft: %s, Body: %s", resp.Status, string(bodyBytes))
+	}
+
+	var message struct {
+		InternetMessageID string `json:"internetMessageId"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+		return "", fmt.Errorf("error reading draft: %s", err.Error())
+	} else if message.InternetMessageID == "" {
+		return "", errors.New("error reading draft: the response has no internetMessageId")
+	}
+
+	return message.InternetMessageID, nil
+}
+
+// deleteAzureSentCopy removes the copy that Graph keeps in Sent Items of a sent draft in the background.
+// The mail is already sent, so a failure is only logged and the copy stays in Sent Items.
+func deleteAzureSentCopy(client *http.Client, azure *models.Azure, internetMessageID string) {
+	go func() {
+		searchURL := azureSentCopySearchURL(azure, internetMessageID)
+
+		for attempt := 0; attempt < azureSentCopyAttempts; attempt++ {
+			time.Sleep(azureSentCopyInterval)
+
+			messageID, err := findAzureMessage(client, searchURL)
+			if err != nil {
+				log.Printf("sent copy of azure %d: %s", azure.ID, err.Error())
+				return
+			} else if messageID == "" {
+				continue
+			}
+
+			if err := permanentDeleteAzureMessage(client, azure, messageID); err != nil {
+				log.Printf("sent copy of azure %d: %s", azure.ID, err.Error())
+			}
+			return
+		}
+
+		log.Printf("sent copy of azure %d: %s not found in Sent Items", azure.ID, internetMessageID)
+	}()
+}
+
+// azureSentCopySearchURL returns the Graph search of the message with the Message-ID in Sent Items.
+func azureSentCopySearchURL(azure *models.Azure, internetMessageID string) string {
+	filter := "internetMessageId eq '" + strings.ReplaceAll(internetMessageID, "'", "''") + "'"
+
+	return getAzureCloud(azure).graphURL("/me/mailFolders/sentitems/messages?$select=id&$filter=" + url.QueryEscape(filter))
+}
+
+// findAzureMessage returns the ID of the first message of the search, or an empty ID.
+func findAzureMessage(client *http.Client, searchURL string) (string, error) {
+	resp, err := client.Get(searchURL)
+	if err != nil {
+		return "", fmt.Errorf("error while searching sent items: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("error searching sent items: %s, Body: %s", resp.Status, string(bodyBytes))
+	}
+
+	var messages struct {
+		Value []struct {
+			ID string `json:"id"`
+		} `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&messages); err != nil {
+		return "", fmt.Errorf("error reading sent items: %s", err.Error())
+	} else if len(messages.Value) == 0 {
+		return "", nil
+	}
+
+	return messages.Value[0].ID, nil
+}
+
+// permanentDeleteAzureMessage deletes the message without moving it to Deleted Items.
+func permanentDeleteAzureMessage(client *http.Client, azure *models.Azure, messageID string) error {
+	resp, err := client.Post(getAzureCloud(azure).graphURL("/me/messages/"+url.PathEscape(messageID)+"/permanentDelete"), "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("error while deleting message: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error deleting message: %s, Body: %s", resp.Status, string(bodyBytes))
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"api-mail/main/src/models"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckAzureMessageSize(t *testing.T) {
+	tests := []struct {
+		smtpDelivery bool
+		mime         bool
+		size         int64
+		want         bool
+	}{
+		{false, true, azureMaxMimeSize, false},
+		{false, true, azureMaxMimeSize + 1, true},
+		{true, true, azureMaxMimeSize + 1, false},
+		{true, false, azureSmtpMaxMessageSize + 1, true},
+		{false, false, 100 * 1024 * 1024, false},
+		{false, false, azureMaxAttachmentSize + 1, true},
+	}
+
+	for _, test := range tests {
+		err := checkAzureMessageSize(test.size, azureMaxMessageSize(test.smtpDelivery, test.mime))
+		if got := errors.Is(err, ErrMessageSize); got != test.want {
+			t.Errorf("smtpDelivery %t, mime %t, size %d: error is %v", test.smtpDelivery, test.mime, test.size, err)
+		}
+	}
+}
+
+func TestDeleteAzureSentCopyRequests(t *testing.T) {
+	deleted := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1.0/me/mailFolders/sentitems/messages":
+			if filter := r.URL.Query().Get("$filter"); filter == "internetMessageId eq '<sent@example.com>'" {
+				_, _ = w.Write([]byte(`{"value":[{"id":"AAMk"}]}`))
+			} else {
+				_, _ = w.Write([]byte(`{"value":[]}`))
+			}
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/permanentDelete"):
+			deleted = strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1.0/me/messages/"), "/permanentDelete")
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	azure := &models.Azure{GraphURL: &server.URL}
+
+	messageID, err := findAzureMessage(server.Client(), azureSentCopySearchURL(azure, "<sent@example.com>"))
+	if err != nil || messageID != "AAMk" {
+		t.Fatalf("found message %q: %v", messageID, err)
+	}
+
+	if messageID, err := findAzureMessage(server.Client(), azureSentCopySearchURL(azure, "<other@example.com>")); err != nil || messageID != "" {
+		t.Errorf("found message %q of another Message-ID: %v", messageID, err)
+	}
+
+	if err := permanentDeleteAzureMessage(server.Client(), azure, messageID); err != nil || deleted != "AAMk" {
+		t.Errorf("deleted %q: %v", deleted, err)
+	}
+}